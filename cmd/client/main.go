@@ -3,6 +3,8 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -10,15 +12,19 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"OldSchool/internal/transport/protocol"
 )
 
 type Response struct {
+	ID      uint64          `json:"id,omitempty"`
 	Status  bool            `json:"status,omitempty"`
 	Message string          `json:"message,omitempty"`
 	Data    json.RawMessage `json:"data,omitempty"`
 }
 
 type Request struct {
+	ID     uint64      `json:"id,omitempty"`
 	Method string      `json:"method,omitempty"`
 	Data   interface{} `json:"data,omitempty"`
 }
@@ -32,12 +38,33 @@ type hasID struct {
 func main() {
 	addr := flag.String("addr", "127.0.0.1:8080", "server address host:port")
 	pause := flag.Duration("pause", 0, "pause between requests (e.g. 200ms)")
+	framing := flag.String("framing", protocol.FramingLines, "message framing: lines or length-prefixed")
+	codecName := flag.String("codec", protocol.CodecJSON, "payload codec: json, msgpack or cbor")
+	useTLS := flag.Bool("tls", false, "connect with TLS (implied by -ca, -cert and -key)")
+	caFile := flag.String("ca", "", "PEM CA bundle used to verify the server")
+	certFile := flag.String("cert", "", "PEM client certificate for mutual TLS")
+	keyFile := flag.String("key", "", "PEM private key matching -cert")
+	loginID := flag.Uint("login-id", 1, "person id to log in as")
+	password := flag.String("password", os.Getenv("OLDSCHOOL_PASSWORD"), "password for -login-id (default $OLDSCHOOL_PASSWORD)")
 	flag.Parse()
 
 	fmt.Println("== OldSchool Scenario Client ==")
 	fmt.Println("Connecting to:", *addr)
 
-	conn, err := net.Dial("tcp", *addr)
+	var (
+		conn net.Conn
+		err  error
+	)
+	if *useTLS || *caFile != "" || *certFile != "" || *keyFile != "" {
+		tlsCfg, err := clientTLSConfig(*caFile, *certFile, *keyFile)
+		if err != nil {
+			fmt.Println("TLS config error:", err)
+			os.Exit(1)
+		}
+		conn, err = tls.Dial("tcp", *addr, tlsCfg)
+	} else {
+		conn, err = net.Dial("tcp", *addr)
+	}
 	if err != nil {
 		fmt.Println("Dial error:", err)
 		os.Exit(1)
@@ -47,39 +74,77 @@ func main() {
 	r := bufio.NewReader(conn)
 	w := bufio.NewWriter(conn)
 
+	codec, ok := protocol.LookupCodec(*codecName)
+	if !ok {
+		fmt.Println("Unknown codec:", *codecName)
+		os.Exit(1)
+	}
+	framed := *framing != protocol.FramingLines || *codecName != protocol.CodecJSON
+	if framed {
+		if err := negotiate(r, w, *framing, *codecName); err != nil {
+			fmt.Println("Negotiation error:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Negotiated %s framing with %s codec\n", *framing, *codecName)
+	}
+
 	// ---- helpers ----
+	var nextID uint64
 	send := func(method string, payload any) (Response, error) {
 		if *pause > 0 {
 			time.Sleep(*pause)
 		}
 
-		req := Request{Method: method, Data: payload}
+		nextID++
+		req := Request{ID: nextID, Method: method, Data: payload}
 		b, err := json.Marshal(req)
 		if err != nil {
 			return Response{}, err
 		}
 
-		// newline-delimited JSON
-		if _, err := w.Write(b); err != nil {
-			return Response{}, err
-		}
-		if err := w.WriteByte('\n'); err != nil {
-			return Response{}, err
-		}
-		if err := w.Flush(); err != nil {
-			return Response{}, err
-		}
-
-		line, err := r.ReadBytes('\n')
-		if err != nil {
-			return Response{}, err
+		var line []byte
+		if framed {
+			out, err := codec.FromJSON(b)
+			if err != nil {
+				return Response{}, err
+			}
+			if err := protocol.WriteFrame(w, out); err != nil {
+				return Response{}, err
+			}
+
+			in, err := protocol.ReadFrame(r)
+			if err != nil {
+				return Response{}, err
+			}
+			if line, err = codec.ToJSON(in); err != nil {
+				return Response{}, err
+			}
+		} else {
+			// newline-delimited JSON
+			if _, err := w.Write(b); err != nil {
+				return Response{}, err
+			}
+			if err := w.WriteByte('\n'); err != nil {
+				return Response{}, err
+			}
+			if err := w.Flush(); err != nil {
+				return Response{}, err
+			}
+
+			line, err = r.ReadBytes('\n')
+			if err != nil {
+				return Response{}, err
+			}
+			line = bytes.TrimSpace(line)
 		}
-		line = bytes.TrimSpace(line)
 
 		var resp Response
 		if err := json.Unmarshal(line, &resp); err != nil {
 			return Response{}, fmt.Errorf("invalid response JSON: %w | raw=%s", err, string(line))
 		}
+		if resp.ID != req.ID {
+			return Response{}, fmt.Errorf("response id %d does not match request id %d", resp.ID, req.ID)
+		}
 
 		// Pretty print I/O
 		fmt.Println("\n--- REQUEST ---")
@@ -136,8 +201,13 @@ func main() {
 	// SCENARIO STARTS HERE
 	// =========================
 
+	// 0) Log in; the session stays bound to this connection
+	resp, err := send("/auth/login", map[string]any{"id": *loginID, "password": *password})
+	mustNoErr("Login", err)
+	mustOK("Login", resp)
+
 	// 1) Create schools
-	resp, err := send("/school/create", map[string]any{"name": "S1"})
+	resp, err = send("/school/create", map[string]any{"name": "S1"})
 	mustNoErr("Create school S1", err)
 	mustOK("Create school S1", resp)
 	s1ID = extractID(resp)
@@ -174,7 +244,7 @@ func main() {
 	student2ID = extractID(resp)
 
 	// 2b) Invalid role (should fail)
-	resp, err = send("/person/create", map[string]any{"name": "BadRole", "role": "admin"})
+	resp, err = send("/person/create", map[string]any{"name": "BadRole", "role": "janitor"})
 	mustNoErr("Create invalid role", err)
 	mustFail("Create invalid role", resp, "invalid")
 
@@ -300,6 +370,60 @@ func main() {
 	fmt.Println("  schools, people, classes, enrollments")
 }
 
+func clientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// negotiate switches the connection to the requested framing and codec. It
+// has to be the first message sent.
+func negotiate(r *bufio.Reader, w *bufio.Writer, framing, codec string) error {
+	b, err := json.Marshal(Request{
+		Method: protocol.NegotiateMethod,
+		Data:   protocol.Negotiation{Framing: framing, Codec: codec},
+	})
+	if err != nil {
+		return err
+	}
+	if err := protocol.WriteLine(w, b); err != nil {
+		return err
+	}
+
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return err
+	}
+	if !resp.Status {
+		return fmt.Errorf("server refused: %s", resp.Message)
+	}
+	return nil
+}
+
 func mustNoErr(step string, err error) {
 	if err != nil {
 		fmt.Printf("❌ %s error: %v\n", step, err)
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"OldSchool/internal/repository"
+	"OldSchool/internal/service"
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+// runExport is the export subcommand. It streams the export to a file, or to
+// stdout, as it reads the database.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", service.DefaultExportFormat, "csv, ndjson or xlsx")
+	tables := fs.String("tables", "", "comma-separated tables to export: schools, classes, people, enrollments (default all; csv takes one)")
+	columns := fs.String("columns", "", "comma-separated columns to keep, as column or table.column")
+	schoolID := fs.Uint("school", 0, "export only this school and its people")
+	termID := fs.Uint("term", 0, "export only classes and enrollments of this term")
+	role := fs.String("role", "", "export only people holding this role")
+	out := fs.String("o", "-", "file to write, - for stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: oldschool export [flags]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	// stdout may carry the export, so SQL logging goes to stderr.
+	logger.Default = logger.New(log.New(os.Stderr, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold: 200 * time.Millisecond,
+		LogLevel:      logger.Warn,
+		Colorful:      true,
+	})
+	db, err := repository.InitDB(dbPath)
+	if err != nil {
+		return err
+	}
+	services := service.NewServices(repository.NewRepos(db))
+
+	f := os.Stdout
+	if *out != "-" {
+		if f, err = os.Create(*out); err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+	w := bufio.NewWriter(f)
+
+	err = services.Export.Export(w, *format, service.ExportOptions{
+		Tables:   splitFlag(*tables),
+		Columns:  splitFlag(*columns),
+		SchoolID: *schoolID,
+		TermID:   *termID,
+		Role:     *role,
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func splitFlag(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
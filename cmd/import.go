@@ -0,0 +1,56 @@
+package main
+
+import (
+	"OldSchool/internal/repository"
+	"OldSchool/internal/service"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// runImport is the import subcommand: oldschool import [-dry-run] file.csv.
+// It reads the file straight into the database, so it has none of the
+// request size limits of the import method.
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "check every row and report errors without importing anything")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: oldschool import [-dry-run] file.csv")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	db, err := repository.InitDB(dbPath)
+	if err != nil {
+		return err
+	}
+	services := service.NewServices(repository.NewRepos(db))
+
+	report, err := services.Import.Import(f, *dryRun)
+	if err != nil && !errors.Is(err, service.ErrImportFailed) {
+		return err
+	}
+	for _, re := range report.Errors {
+		log.Printf("%s:%d: %s", fs.Arg(0), re.Line, re.Message)
+	}
+	log.Printf("%d rows, created %v, %d waitlisted, %d errors", report.Rows, report.Created, report.Waitlisted, len(report.Errors))
+	switch {
+	case err != nil:
+		return err
+	case *dryRun:
+		log.Print("dry run, nothing was imported")
+	}
+	return nil
+}
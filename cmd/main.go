@@ -1,39 +1,95 @@
+// Command OldSchool runs the school server and its import and export tools.
+//
+// Build with -tags sqlite_fts5 to compile SQLite with FTS5, which backs the
+// search method with a ranked full-text index. Without the tag search falls
+// back to LIKE scans. Run the tests with and without the tag:
+//
+//	go test ./... && go test -tags sqlite_fts5 ./...
 package main
 
 import (
 	"OldSchool/internal/repository"
 	"OldSchool/internal/service"
+	"OldSchool/internal/transport/rest"
 	"OldSchool/internal/transport/router"
 	"OldSchool/internal/transport/server"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 )
 
+const dbPath = "./oldSchool.db"
+
 func main() {
-	db, err := repository.InitDB("./oldSchool.db")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			if err := runImport(os.Args[2:]); err != nil {
+				log.Fatalf("import failed %v", err)
+			}
+			return
+		case "export":
+			if err := runExport(os.Args[2:]); err != nil {
+				log.Fatalf("export failed %v", err)
+			}
+			return
+		}
+	}
+
+	maxInFlight := flag.Int("max-inflight", server.DefaultMaxInFlight, "max concurrent requests per connection")
+	httpPort := flag.String("http-port", "8081", "port for the HTTP/JSON gateway, empty to disable")
+	wsPort := flag.String("ws-port", "8082", "port for the WebSocket endpoint, empty to disable")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate for TLS on the TCP, WebSocket and HTTP listeners")
+	tlsKey := flag.String("tls-key", "", "PEM private key matching -tls-cert")
+	tlsClientCA := flag.String("tls-client-ca", "", "PEM CA bundle; when set, clients must present a certificate it signed")
+	adminName := flag.String("admin-name", "admin", "name of the admin account created on first run (password from $OLDSCHOOL_ADMIN_PASSWORD)")
+	wsOrigins := flag.String("ws-origins", "", "comma-separated extra origins allowed to open a WebSocket")
+	flag.Parse()
+
+	db, err := repository.InitDB(dbPath)
 	if err != nil {
 		log.Fatal("Cannot open the Sqlite Database")
 	}
 
 	// Repos
-	schoolRepo := repository.NewSchoolRepository(db)
-	personRepo := repository.NewPersonRepositrory(db)
-	classRepo := repository.NewClassRepository(db)
-	enrollmentRepo := repository.NewEnrollmentRepository(db)
-	unitOfWorkRepo := repository.NewUnitOfWork(db)
+	repos := repository.NewRepos(db)
 
 	// Services
-	schoolService := service.NewSchoolService(schoolRepo, classRepo)
-	personService := service.NewPersonService(personRepo, classRepo, enrollmentRepo)
-	classService := service.NewClassService(classRepo, personRepo, unitOfWorkRepo, enrollmentRepo)
+	services := service.NewServices(repos)
+
+	// Nobody can log in on an empty database, so the first run creates an
+	// admin account from the environment.
+	if pw := os.Getenv("OLDSCHOOL_ADMIN_PASSWORD"); pw != "" {
+		admin, err := services.Auth.Bootstrap(*adminName, pw)
+		if err != nil {
+			log.Fatalf("cannot create admin account %v", err)
+		}
+		if admin != nil {
+			log.Printf("created admin account %q with id %d", admin.Name, admin.ID)
+		}
+	}
 
 	// router
-	router := router.NewRouter(schoolService, personService, classService)
+	router := router.NewRouter(services)
 
 	// server
-	server := server.New(router)
+	cfg := server.Config{MaxInFlight: *maxInFlight}
+	if *wsOrigins != "" {
+		cfg.AllowedOrigins = strings.Split(*wsOrigins, ",")
+	}
+	if *tlsCert != "" || *tlsKey != "" {
+		cfg.TLS, err = server.LoadTLSConfig(*tlsCert, *tlsKey, *tlsClientCA)
+		if err != nil {
+			log.Fatalf("cannot load TLS config %v", err)
+		}
+	} else if *tlsClientCA != "" {
+		log.Fatal("-tls-client-ca needs -tls-cert and -tls-key")
+	}
+	wsServer := server.NewWebSocket(router, cfg)
+	server := server.New(router, cfg)
 
 	port := "8080"
 
@@ -43,10 +99,31 @@ func main() {
 
 	log.Printf("server listening on: %s", port)
 
+	gateway := rest.New(router, cfg)
+	if *httpPort != "" {
+		if err := gateway.Start(*httpPort); err != nil {
+			log.Fatalf("http gateway start failed %v", err)
+		}
+		log.Printf("http gateway listening on: %s", *httpPort)
+	}
+
+	if *wsPort != "" {
+		if err := wsServer.Start(*wsPort); err != nil {
+			log.Fatalf("websocket server start failed %v", err)
+		}
+		log.Printf("websocket listening on: %s/ws", *wsPort)
+	}
+
 	signC := make(chan os.Signal, 1)
 	signal.Notify(signC, os.Interrupt, syscall.SIGTERM)
 	<-signC
 
+	if *httpPort != "" {
+		_ = gateway.Stop()
+	}
+	if *wsPort != "" {
+		_ = wsServer.Stop()
+	}
 	_ = server.Stop()
 	log.Println("server stopped")
 
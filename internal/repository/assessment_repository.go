@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type AssessmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAssessmentRepository(db *gorm.DB) *AssessmentRepository {
+	return &AssessmentRepository{db: db}
+}
+
+func (ar *AssessmentRepository) Create(classID uint, name string, weight, maxPoints float64) (*models.Assessment, error) {
+	a := &models.Assessment{
+		ClassID:   classID,
+		Name:      name,
+		Weight:    weight,
+		MaxPoints: maxPoints,
+	}
+
+	if err := ar.db.Create(a).Error; err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (ar *AssessmentRepository) GetByID(id uint) (*models.Assessment, error) {
+	var a models.Assessment
+
+	err := ar.db.First(&a, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (ar *AssessmentRepository) ListByClassIDs(classIDs []uint) ([]models.Assessment, error) {
+	var as []models.Assessment
+	if len(classIDs) == 0 {
+		return as, nil
+	}
+
+	err := ar.db.Where("class_id IN ?", classIDs).Order("class_id, id").Find(&as).Error
+	return as, err
+}
+
+func (ar *AssessmentRepository) DeleteByClassID(classID uint) error {
+	return ar.db.Where("class_id = ?", classID).Delete(&models.Assessment{}).Error
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AssignmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAssignmentRepository(db *gorm.DB) *AssignmentRepository {
+	return &AssignmentRepository{db: db}
+}
+
+func (ar *AssignmentRepository) Create(classID uint, title, description string, dueAt time.Time) (*models.Assignment, error) {
+	a := &models.Assignment{
+		ClassID:     classID,
+		Title:       title,
+		Description: description,
+		DueAt:       dueAt,
+	}
+
+	if err := ar.db.Create(a).Error; err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (ar *AssignmentRepository) GetByID(id uint) (*models.Assignment, error) {
+	var a models.Assignment
+
+	err := ar.db.First(&a, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (ar *AssignmentRepository) ListByClassID(classID uint) ([]models.Assignment, error) {
+	var as []models.Assignment
+	err := ar.db.Where("class_id = ?", classID).Order("due_at, id").Find(&as).Error
+	return as, err
+}
+
+func (ar *AssignmentRepository) DeleteByClassID(classID uint) error {
+	return ar.db.Where("class_id = ?", classID).Delete(&models.Assignment{}).Error
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AttendanceRepository struct {
+	db *gorm.DB
+}
+
+func NewAttendanceRepository(db *gorm.DB) *AttendanceRepository {
+	return &AttendanceRepository{db: db}
+}
+
+// AttendanceSummary counts one student's attendance records in one class by
+// status.
+type AttendanceSummary struct {
+	ClassID   uint
+	StudentID uint
+	Present   int64
+	Absent    int64
+	Late      int64
+	Excused   int64
+}
+
+// Record stores the student's status for the session, replacing any earlier
+// record.
+func (ar *AttendanceRepository) Record(classID, studentID uint, date, status string) error {
+	a := &models.Attendance{
+		ClassID:   classID,
+		StudentID: studentID,
+		Date:      date,
+		Status:    status,
+	}
+
+	return ar.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "class_id"}, {Name: "date"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "updated_at"}),
+	}).Create(a).Error
+}
+
+func (ar *AttendanceRepository) ListByClassAndDate(classID uint, date string) ([]models.Attendance, error) {
+	var as []models.Attendance
+	err := ar.db.Where("class_id = ? AND date = ?", classID, date).Order("student_id").Find(&as).Error
+	return as, err
+}
+
+func (ar *AttendanceRepository) summaries() *gorm.DB {
+	return ar.db.Model(&models.Attendance{}).
+		Select(`attendances.class_id, attendances.student_id,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS present,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS absent,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS late,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS excused`,
+			models.AttendancePresent, models.AttendanceAbsent, models.AttendanceLate, models.AttendanceExcused).
+		Group("attendances.class_id, attendances.student_id")
+}
+
+// Summarize counts the student's records in the class.
+func (ar *AttendanceRepository) Summarize(classID, studentID uint) (AttendanceSummary, error) {
+	s := AttendanceSummary{ClassID: classID, StudentID: studentID}
+	var rows []AttendanceSummary
+	err := ar.summaries().Where("attendances.class_id = ? AND attendances.student_id = ?", classID, studentID).Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return s, err
+	}
+	return rows[0], nil
+}
+
+// SummarizeByClassIDs counts the records of every student still enrolled in
+// the given classes.
+func (ar *AttendanceRepository) SummarizeByClassIDs(classIDs []uint) ([]AttendanceSummary, error) {
+	var rows []AttendanceSummary
+	if len(classIDs) == 0 {
+		return rows, nil
+	}
+
+	err := ar.summaries().
+		Joins("JOIN enrollments ON enrollments.class_id = attendances.class_id AND enrollments.student_id = attendances.student_id").
+		Where("attendances.class_id IN ?", classIDs).
+		Order("attendances.class_id, attendances.student_id").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (ar *AttendanceRepository) DeleteByClassID(classID uint) error {
+	return ar.db.Where("class_id = ?", classID).Delete(&models.Attendance{}).Error
+}
+
+func (ar *AttendanceRepository) DeleteByStudentID(studentID uint) error {
+	return ar.db.Where("student_id = ?", studentID).Delete(&models.Attendance{}).Error
+}
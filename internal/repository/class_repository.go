@@ -3,6 +3,7 @@ package repository
 import (
 	"OldSchool/internal/repository/models"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -15,11 +16,12 @@ func NewClassRepository(db *gorm.DB) *ClassRepository {
 	return &ClassRepository{db: db}
 }
 
-func (c *ClassRepository) Create(name string, schoolID uint, teacherID uint) (*models.Class, error) {
+func (c *ClassRepository) Create(name string, schoolID uint, teacherID uint, termID uint) (*models.Class, error) {
 	cr := &models.Class{
 		Name:      name,
 		SchoolID:  schoolID,
 		TeacherID: teacherID,
+		TermID:    termID,
 	}
 
 	if err := c.db.Create(cr).Error; err != nil {
@@ -68,3 +70,78 @@ func (cr *ClassRepository) ListIDsByTeacherID(teacherID uint) ([]uint, error) {
 	}
 	return ids, nil
 }
+
+func (cr *ClassRepository) UpdateName(classID uint, name string) error {
+	return cr.db.Model(&models.Class{}).Where("id = ?", classID).Update("name", name).Error
+}
+
+func (cr *ClassRepository) ListIDsBySchoolID(schoolID uint) ([]uint, error) {
+	var ids []uint
+	err := cr.db.Model(&models.Class{}).Where("school_id = ?", schoolID).Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (cr *ClassRepository) Delete(classID uint) error {
+	return cr.db.Delete(&models.Class{}, classID).Error
+}
+
+// SetCapacity limits the class to capacity students; nil removes the limit.
+func (cr *ClassRepository) SetCapacity(classID uint, capacity *uint) error {
+	return cr.db.Model(&models.Class{}).Where("id = ?", classID).Update("capacity", capacity).Error
+}
+
+// PageBySchoolAndTermID is ListBySchoolAndTermID a page at a time, filtered
+// by name and teacher.
+func (cr *ClassRepository) PageBySchoolAndTermID(schoolID, termID uint, opts ListOptions) (Page[models.Class], error) {
+	q := cr.db.Model(&models.Class{}).Where("classes.school_id = ? AND classes.term_id = ?", schoolID, termID)
+	q = nameContains(q, "classes.name", opts.NameContains)
+	if opts.TeacherID != 0 {
+		q = q.Where("classes.teacher_id = ?", opts.TeacherID)
+	}
+	return paginate(q, "classes", opts, func(c *models.Class) cursor {
+		return cursor{ID: c.ID, Name: c.Name, CreatedAt: c.CreatedAt}
+	}, "Teacher")
+}
+
+func (cr *ClassRepository) ListBySchoolAndTermID(schoolID, termID uint) ([]models.Class, error) {
+	var classes []models.Class
+	err := cr.db.Where("school_id = ? AND term_id = ?", schoolID, termID).Preload("Teacher").Order("id ASC").Find(&classes).Error
+	if err != nil {
+		return nil, err
+	}
+	return classes, nil
+}
+
+// FilterIDsByTermID keeps the classes among ids that belong to the term.
+func (cr *ClassRepository) FilterIDsByTermID(ids []uint, termID uint) ([]uint, error) {
+	filtered := []uint{}
+	if len(ids) == 0 {
+		return filtered, nil
+	}
+	err := cr.db.Model(&models.Class{}).Where("id IN ? AND term_id = ?", ids, termID).Order("id ASC").Pluck("id", &filtered).Error
+	if err != nil {
+		return nil, err
+	}
+	return filtered, nil
+}
+
+// FilterIDsByOpenTerm keeps the classes among ids whose term is open at the
+// given time.
+func (cr *ClassRepository) FilterIDsByOpenTerm(ids []uint, at time.Time) ([]uint, error) {
+	filtered := []uint{}
+	if len(ids) == 0 {
+		return filtered, nil
+	}
+	at = at.UTC()
+	err := cr.db.Model(&models.Class{}).
+		Joins("JOIN terms ON terms.id = classes.term_id").
+		Where("classes.id IN ? AND terms.starts_at <= ? AND terms.ends_at > ?", ids, at, at).
+		Order("classes.id ASC").Pluck("classes.id", &filtered).Error
+	if err != nil {
+		return nil, err
+	}
+	return filtered, nil
+}
@@ -9,7 +9,12 @@ import (
 )
 
 func InitDB(dbPath string) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	// Requests from one connection run concurrently, so writers wait on a
+	// busy database instead of failing, and transactions take the write
+	// lock up front to avoid deadlocking on a lock upgrade.
+	dsn := dbPath + "?_busy_timeout=5000&_txlock=immediate"
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 
@@ -25,14 +30,84 @@ func InitDB(dbPath string) (*gorm.DB, error) {
 	err = db.AutoMigrate(
 		&models.School{},
 		&models.Person{},
+		&models.PersonRole{},
 		&models.Class{},
 		&models.Enrollment{},
+		&models.Session{},
+		&models.Transfer{},
+		&models.Waitlist{},
+		&models.Term{},
+		&models.Room{},
+		&models.Slot{},
+		&models.Assessment{},
+		&models.Score{},
+		&models.Attendance{},
+		&models.Assignment{},
+		&models.Submission{},
+		&models.ReportComment{},
+		&models.Guardianship{},
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if err := migratePersonRole(db); err != nil {
+		return nil, err
+	}
+
+	if err := migrateClassTerms(db); err != nil {
+		return nil, err
+	}
+
+	if err := migrateSearch(db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 
 }
+
+// migratePersonRole moves the old single people.role column into the
+// person_roles table. Databases created after the move have no such column
+// and are left alone.
+func migratePersonRole(db *gorm.DB) error {
+	if !db.Migrator().HasColumn("people", "role") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT OR IGNORE INTO person_roles (person_id, role)
+			SELECT id, role FROM people WHERE role <> ''`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec("ALTER TABLE people DROP COLUMN role").Error
+	})
+}
+
+// migrateClassTerms puts classes created before terms existed into their
+// school's default term.
+func migrateClassTerms(db *gorm.DB) error {
+	var schoolIDs []uint
+	err := db.Model(&models.Class{}).Where("term_id IS NULL OR term_id = 0").Distinct().Pluck("school_id", &schoolIDs).Error
+	if err != nil || len(schoolIDs) == 0 {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		terms := NewTermRepository(tx)
+		for _, schoolID := range schoolIDs {
+			t, err := terms.GetOrCreateDefault(schoolID)
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&models.Class{}).
+				Where("school_id = ? AND (term_id IS NULL OR term_id = 0)", schoolID).
+				Update("term_id", t.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -33,13 +33,29 @@ func (er *EnrollmentRepository) Exists(classID uint, studentID uint) (bool, erro
 func (er *EnrollmentRepository) ListStudentsByClassID(classID uint) ([]models.Person, error) {
 	var students []models.Person
 
-	err := er.db.Table("people").Joins("JOIN enrollments ON enrollments.student_id = people.id").Where("enrollments.class_id = ?", classID).Order("people.id ASC").Scan(&students).Error
+	err := er.db.Preload("Roles").Joins("JOIN enrollments ON enrollments.student_id = people.id").Where("enrollments.class_id = ?", classID).Order("people.id ASC").Find(&students).Error
 	if err != nil {
 		return nil, err
 	}
 	return students, nil
 }
 
+// PageStudentsByClassID is ListStudentsByClassID a page at a time, filtered
+// by name, role and the school the student is bound to.
+func (er *EnrollmentRepository) PageStudentsByClassID(classID uint, opts ListOptions) (Page[models.Person], error) {
+	q := er.db.Model(&models.Person{}).Joins("JOIN enrollments ON enrollments.student_id = people.id").Where("enrollments.class_id = ?", classID)
+	q = nameContains(q, "people.name", opts.NameContains)
+	if opts.Role != "" {
+		q = q.Where("EXISTS (SELECT 1 FROM person_roles WHERE person_roles.person_id = people.id AND person_roles.role = ?)", opts.Role)
+	}
+	if opts.SchoolID != 0 {
+		q = q.Where("people.student_school_id = ?", opts.SchoolID)
+	}
+	return paginate(q, "people", opts, func(p *models.Person) cursor {
+		return cursor{ID: p.ID, Name: p.Name, CreatedAt: p.CreatedAt}
+	}, "Roles")
+}
+
 func (er *EnrollmentRepository) Add(classID, studentID uint) (*models.Enrollment, error) {
 	e := &models.Enrollment{
 		ClassID:   classID,
@@ -64,3 +80,21 @@ func (er *EnrollmentRepository) ListClassIDsByStudentID(studentID uint) ([]uint,
 
 	return classIDs, nil
 }
+
+func (er *EnrollmentRepository) CountByClassID(classID uint) (int64, error) {
+	var n int64
+	err := er.db.Model(&models.Enrollment{}).Where("class_id = ?", classID).Count(&n).Error
+	return n, err
+}
+
+func (er *EnrollmentRepository) DeleteByClassID(classID uint) error {
+	return er.db.Where("class_id = ?", classID).Delete(&models.Enrollment{}).Error
+}
+
+func (er *EnrollmentRepository) DeleteByStudentID(studentID uint) error {
+	return er.db.Where("student_id = ?", studentID).Delete(&models.Enrollment{}).Error
+}
+
+func (er *EnrollmentRepository) Delete(classID, studentID uint) error {
+	return er.db.Where("class_id = ? AND student_id = ?", classID, studentID).Delete(&models.Enrollment{}).Error
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is how many rows an export reads at a time.
+const exportBatchSize = 500
+
+// ExportRepository reads whole tables in id order, a batch at a time, so an
+// export never holds more than one batch in memory.
+type ExportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+// EachSchool calls fn for every school, or just schoolID when it is not 0.
+func (er *ExportRepository) EachSchool(schoolID uint, fn func(*models.School) error) error {
+	q := er.db.Model(&models.School{})
+	if schoolID != 0 {
+		q = q.Where("id = ?", schoolID)
+	}
+	return eachInBatches(q, fn)
+}
+
+// EachClass calls fn for every class with its teacher. Non-zero schoolID and
+// termID narrow the classes down to that school and term.
+func (er *ExportRepository) EachClass(schoolID, termID uint, fn func(*models.Class) error) error {
+	q := er.db.Model(&models.Class{}).Preload("Teacher")
+	if schoolID != 0 {
+		q = q.Where("school_id = ?", schoolID)
+	}
+	if termID != 0 {
+		q = q.Where("term_id = ?", termID)
+	}
+	return eachInBatches(q, fn)
+}
+
+// EachPerson calls fn for every person with their roles. A schoolID other
+// than 0 keeps the school's students and the teachers of its classes, and a
+// role keeps only those holding it.
+func (er *ExportRepository) EachPerson(schoolID uint, role string, fn func(*models.Person) error) error {
+	q := er.db.Model(&models.Person{}).Preload("Roles")
+	if schoolID != 0 {
+		q = q.Where("(student_school_id = ? OR id IN (SELECT teacher_id FROM classes WHERE school_id = ?))", schoolID, schoolID)
+	}
+	if role != "" {
+		q = q.Where("EXISTS (SELECT 1 FROM person_roles WHERE person_roles.person_id = people.id AND person_roles.role = ?)", role)
+	}
+	return eachInBatches(q, fn)
+}
+
+// EachEnrollment calls fn for every enrollment with its class and student,
+// ordered by class and then student. Non-zero schoolID and termID narrow
+// them down to classes of that school and term.
+func (er *ExportRepository) EachEnrollment(schoolID, termID uint, fn func(*models.Enrollment) error) error {
+	q := er.db.Model(&models.Enrollment{}).
+		Joins("JOIN classes ON classes.id = enrollments.class_id").
+		Preload("Class").Preload("Student")
+	if schoolID != 0 {
+		q = q.Where("classes.school_id = ?", schoolID)
+	}
+	if termID != 0 {
+		q = q.Where("classes.term_id = ?", termID)
+	}
+
+	// Enrollments have no single id to batch on, so page by (class, student).
+	var lastClass, lastStudent uint
+	for {
+		var batch []models.Enrollment
+		err := q.Session(&gorm.Session{}).
+			Where("(enrollments.class_id > ? OR (enrollments.class_id = ? AND enrollments.student_id > ?))", lastClass, lastClass, lastStudent).
+			Order("enrollments.class_id, enrollments.student_id").
+			Limit(exportBatchSize).
+			Find(&batch).Error
+		if err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if len(batch) < exportBatchSize {
+			return nil
+		}
+		last := batch[len(batch)-1]
+		lastClass, lastStudent = last.ClassID, last.StudentID
+	}
+}
+
+// eachInBatches runs q in primary key order and calls fn for every row.
+func eachInBatches[T any](q *gorm.DB, fn func(*T) error) error {
+	var batch []T
+	return q.FindInBatches(&batch, exportBatchSize, func(_ *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
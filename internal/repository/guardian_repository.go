@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GuardianRepository struct {
+	db *gorm.DB
+}
+
+func NewGuardianRepository(db *gorm.DB) *GuardianRepository {
+	return &GuardianRepository{db: db}
+}
+
+// Link stores the guardianship, replacing the details of an existing link
+// between the same two people.
+func (gr *GuardianRepository) Link(g *models.Guardianship) error {
+	return gr.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "guardian_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"relationship", "preferred_contact", "emergency_contact", "receives_reports", "updated_at"}),
+	}).Create(g).Error
+}
+
+func (gr *GuardianRepository) Get(guardianID, studentID uint) (*models.Guardianship, error) {
+	var g models.Guardianship
+
+	err := gr.db.Where("guardian_id = ? AND student_id = ?", guardianID, studentID).First(&g).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (gr *GuardianRepository) Unlink(guardianID, studentID uint) error {
+	return gr.db.Where("guardian_id = ? AND student_id = ?", guardianID, studentID).Delete(&models.Guardianship{}).Error
+}
+
+func (gr *GuardianRepository) ListByGuardianID(guardianID uint) ([]models.Guardianship, error) {
+	var gs []models.Guardianship
+	err := gr.db.Preload("Student.Roles").Where("guardian_id = ?", guardianID).Order("student_id").Find(&gs).Error
+	return gs, err
+}
+
+func (gr *GuardianRepository) ListByStudentID(studentID uint) ([]models.Guardianship, error) {
+	var gs []models.Guardianship
+	err := gr.db.Preload("Guardian.Roles").Where("student_id = ?", studentID).Order("guardian_id").Find(&gs).Error
+	return gs, err
+}
+
+// DeleteByPersonID drops every link the person is on either side of.
+func (gr *GuardianRepository) DeleteByPersonID(personID uint) error {
+	return gr.db.Where("guardian_id = ? OR student_id = ?", personID, personID).Delete(&models.Guardianship{}).Error
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Assignment is homework posted to a class.
+type Assignment struct {
+	ID          uint   `gorm:"primaryKey"`
+	ClassID     uint   `gorm:"not null;index"`
+	Class       Class  `gorm:"foreignKey:ClassID;references:ID" json:"-"`
+	Title       string `gorm:"not null"`
+	Description string
+	DueAt       time.Time `gorm:"not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Submission is a student's answer to an assignment, either as text or as a
+// reference to a file stored elsewhere. A resubmission replaces the answer
+// and is judged late against its own time.
+type Submission struct {
+	ID           uint       `gorm:"primaryKey"`
+	AssignmentID uint       `gorm:"not null;uniqueIndex:idx_submission_assignment_student"`
+	StudentID    uint       `gorm:"not null;uniqueIndex:idx_submission_assignment_student;index"`
+	Assignment   Assignment `gorm:"foreignKey:AssignmentID;references:ID" json:"-"`
+	Student      Person     `gorm:"foreignKey:StudentID;references:ID" json:"-"`
+	Text         string
+	FileRef      string
+	SubmittedAt  time.Time `gorm:"not null"`
+	Late         bool      `gorm:"not null"`
+	Attempts     int       `gorm:"not null;default:1"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+	AttendanceLate    = "late"
+	AttendanceExcused = "excused"
+)
+
+// AttendanceStatuses is every status an attendance record may have.
+var AttendanceStatuses = []string{AttendancePresent, AttendanceAbsent, AttendanceLate, AttendanceExcused}
+
+// Attendance records whether an enrolled student came to one session of a
+// class. Date is the session's calendar day as "YYYY-MM-DD".
+type Attendance struct {
+	ID        uint   `gorm:"primaryKey"`
+	ClassID   uint   `gorm:"not null;uniqueIndex:idx_attendance_class_date_student"`
+	Date      string `gorm:"not null;uniqueIndex:idx_attendance_class_date_student"`
+	StudentID uint   `gorm:"not null;uniqueIndex:idx_attendance_class_date_student;index"`
+	Status    string `gorm:"not null"`
+	Class     Class  `gorm:"foreignKey:ClassID;references:ID" json:"-"`
+	Student   Person `gorm:"foreignKey:StudentID;references:ID" json:"-"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
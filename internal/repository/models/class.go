@@ -6,7 +6,9 @@ type Class struct {
 	ID        uint     `gorm:"primaryKey"`
 	Name      string   `gorm:"not null"`
 	SchoolID  uint     `gorm:"not null"`
+	TermID    uint     `gorm:"index"`
 	TeacherID uint     `gorm:"not null"`
+	Capacity  *uint    // nil means the class has no size limit
 	Teacher   Person   `gorm:"foreignKey:TeacherID;references:ID"`
 	Students  []Person `gorm:"many2many:enrollments;joinForeignKey:ClassID;joinReferences:StudentID"`
 	CreatedAt time.Time
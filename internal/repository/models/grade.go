@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Assessment is a graded piece of work in a class. Weight is relative to the
+// class's other assessments; it does not have to add up to anything.
+type Assessment struct {
+	ID        uint    `gorm:"primaryKey"`
+	ClassID   uint    `gorm:"not null;uniqueIndex:idx_assessment_class_name"`
+	Class     Class   `gorm:"foreignKey:ClassID;references:ID" json:"-"`
+	Name      string  `gorm:"not null;uniqueIndex:idx_assessment_class_name"`
+	Weight    float64 `gorm:"not null"`
+	MaxPoints float64 `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// Score is the points one student earned on one assessment.
+type Score struct {
+	AssessmentID uint       `gorm:"primaryKey"`
+	StudentID    uint       `gorm:"primaryKey"`
+	Assessment   Assessment `gorm:"foreignKey:AssessmentID;references:ID" json:"-"`
+	Student      Person     `gorm:"foreignKey:StudentID;references:ID" json:"-"`
+	Points       float64    `gorm:"not null"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	RelationshipParent        = "parent"
+	RelationshipGrandparent   = "grandparent"
+	RelationshipLegalGuardian = "legal_guardian"
+	RelationshipOther         = "other"
+)
+
+// ValidRelationships is every way a guardian may be related to a student.
+var ValidRelationships = []string{RelationshipParent, RelationshipGrandparent, RelationshipLegalGuardian, RelationshipOther}
+
+const (
+	ContactEmail = "email"
+	ContactPhone = "phone"
+	ContactSMS   = "sms"
+	ContactPost  = "post"
+)
+
+// ValidContactMethods is every way a guardian may ask to be contacted.
+var ValidContactMethods = []string{ContactEmail, ContactPhone, ContactSMS, ContactPost}
+
+// Guardianship links a guardian to one of the students they look after,
+// with how the school should reach them about that student.
+type Guardianship struct {
+	GuardianID       uint   `gorm:"primaryKey"`
+	StudentID        uint   `gorm:"primaryKey;index"`
+	Guardian         Person `gorm:"foreignKey:GuardianID;references:ID" json:"-"`
+	Student          Person `gorm:"foreignKey:StudentID;references:ID" json:"-"`
+	Relationship     string `gorm:"not null"`
+	PreferredContact string `gorm:"not null"`
+	EmergencyContact bool   `gorm:"not null"`
+	ReceivesReports  bool   `gorm:"not null"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
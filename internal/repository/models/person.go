@@ -1,12 +1,80 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	RoleAdmin    = "admin"
+	RoleStaff    = "staff"
+	RoleTeacher  = "teacher"
+	RoleStudent  = "student"
+	RoleGuardian = "guardian"
+)
+
+// ValidRoles is every role a person may hold.
+var ValidRoles = []string{RoleAdmin, RoleStaff, RoleTeacher, RoleStudent, RoleGuardian}
 
 type Person struct {
 	ID              uint   `gorm:"primaryKey"`
 	Name            string `gorm:"not null"`
-	Role            string `gorm:"not null"`
+	Roles           Roles  `gorm:"foreignKey:PersonID;constraint:OnDelete:CASCADE"`
 	StudentSchoolID *uint
+	PasswordHash    string `json:"-"`
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 }
+
+// PersonRole is one row of the person_roles join table.
+type PersonRole struct {
+	PersonID uint   `gorm:"primaryKey"`
+	Role     string `gorm:"primaryKey"`
+}
+
+// Roles is the set of roles a person holds. It is sent to clients as a plain
+// list of role names.
+type Roles []PersonRole
+
+func (p *Person) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAnyRole reports whether the person holds at least one of roles.
+func (p *Person) HasAnyRole(roles ...string) bool {
+	for _, role := range roles {
+		if p.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rs Roles) Names() []string {
+	names := make([]string, 0, len(rs))
+	for _, r := range rs {
+		names = append(names, r.Role)
+	}
+	return names
+}
+
+func (rs Roles) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rs.Names())
+}
+
+func (rs *Roles) UnmarshalJSON(b []byte) error {
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return err
+	}
+	*rs = make(Roles, 0, len(names))
+	for _, name := range names {
+		*rs = append(*rs, PersonRole{Role: name})
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ReportComment is a teacher's remark about a student's work in a class,
+// printed on the student's report card.
+type ReportComment struct {
+	ID        uint   `gorm:"primaryKey"`
+	ClassID   uint   `gorm:"not null;uniqueIndex:idx_report_comment_class_student"`
+	StudentID uint   `gorm:"not null;uniqueIndex:idx_report_comment_class_student;index"`
+	Class     Class  `gorm:"foreignKey:ClassID;references:ID" json:"-"`
+	Student   Person `gorm:"foreignKey:StudentID;references:ID" json:"-"`
+	AuthorID  uint   `gorm:"not null"`
+	Comment   string `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
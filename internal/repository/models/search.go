@@ -0,0 +1,20 @@
+package models
+
+// Kinds of record a search can find.
+const (
+	SearchSchool = "school"
+	SearchClass  = "class"
+	SearchPerson = "person"
+)
+
+// SearchResult is one record matching a search. Snippet is the matched name
+// with each match wrapped in <mark> tags, and a higher Score is a better
+// match. SchoolID is the school a class belongs to or a student attends.
+type SearchResult struct {
+	Type     string  `json:"type"`
+	ID       uint    `json:"id"`
+	Name     string  `json:"name"`
+	Snippet  string  `json:"snippet"`
+	SchoolID *uint   `json:"school_id,omitempty"`
+	Score    float64 `json:"score"`
+}
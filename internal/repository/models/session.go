@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Session is a login. Only a hash of the token is stored, so a leaked
+// database cannot be used to impersonate anyone.
+type Session struct {
+	ID        uint      `gorm:"primaryKey"`
+	TokenHash string    `gorm:"not null;uniqueIndex" json:"-"`
+	PersonID  uint      `gorm:"not null;index"`
+	Person    Person    `gorm:"foreignKey:PersonID;references:ID" json:"-"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
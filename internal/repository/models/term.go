@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// DefaultTermName names the open-ended term that holds classes created
+// before a school set up terms of its own.
+const DefaultTermName = "Default"
+
+// Term is a teaching period of one school. It is open from StartsAt up to,
+// but not including, EndsAt.
+type Term struct {
+	ID        uint      `gorm:"primaryKey"`
+	SchoolID  uint      `gorm:"not null;uniqueIndex:idx_term_school_name"`
+	School    School    `gorm:"foreignKey:SchoolID;references:ID" json:"-"`
+	Name      string    `gorm:"not null;uniqueIndex:idx_term_school_name"`
+	StartsAt  time.Time `gorm:"not null"`
+	EndsAt    time.Time `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// IsOpen reports whether at falls within the term.
+func (t *Term) IsOpen(at time.Time) bool {
+	return !at.Before(t.StartsAt) && at.Before(t.EndsAt)
+}
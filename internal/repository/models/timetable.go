@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Room is a place where a school's classes meet.
+type Room struct {
+	ID        uint   `gorm:"primaryKey"`
+	SchoolID  uint   `gorm:"not null;uniqueIndex:idx_room_school_name"`
+	School    School `gorm:"foreignKey:SchoolID;references:ID" json:"-"`
+	Name      string `gorm:"not null;uniqueIndex:idx_room_school_name"`
+	CreatedAt time.Time
+}
+
+// Slot is one weekly meeting of a class. Weekday follows time.Weekday, so 0
+// is Sunday, and StartTime and EndTime are "HH:MM" so they sort as text.
+type Slot struct {
+	ID        uint   `gorm:"primaryKey"`
+	ClassID   uint   `gorm:"not null;index"`
+	Class     Class  `gorm:"foreignKey:ClassID;references:ID" json:"-"`
+	RoomID    uint   `gorm:"not null;index"`
+	Room      Room   `gorm:"foreignKey:RoomID;references:ID" json:"-"`
+	Weekday   int    `gorm:"not null"`
+	StartTime string `gorm:"not null"`
+	EndTime   string `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
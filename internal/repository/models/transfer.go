@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Transfer records a student moving from one school to another. The school
+// IDs are kept as plain values so the history outlives deleted schools.
+type Transfer struct {
+	ID            uint   `gorm:"primaryKey"`
+	StudentID     uint   `gorm:"not null;index"`
+	Student       Person `gorm:"foreignKey:StudentID;references:ID" json:"-"`
+	FromSchoolID  *uint
+	ToSchoolID    uint      `gorm:"not null"`
+	Reason        string    `gorm:"not null"`
+	TransferredAt time.Time `gorm:"not null"`
+	CreatedAt     time.Time
+}
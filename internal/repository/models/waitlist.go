@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Waitlist holds students waiting for a seat in a full class. Entries are
+// served in ID order.
+type Waitlist struct {
+	ID        uint   `gorm:"primaryKey"`
+	ClassID   uint   `gorm:"not null;uniqueIndex:idx_waitlist_class_student"`
+	StudentID uint   `gorm:"not null;uniqueIndex:idx_waitlist_class_student"`
+	Class     Class  `gorm:"foreignKey:ClassID;references:ID" json:"-"`
+	Student   Person `gorm:"foreignKey:StudentID;references:ID" json:"-"`
+	CreatedAt time.Time
+}
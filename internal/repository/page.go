@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+// ErrInvalidListOptions is returned for a bad limit, sort or cursor.
+var ErrInvalidListOptions = errors.New("invalid list options")
+
+// ListOptions narrows, orders and pages a list query. Sort is "id", "name"
+// or "created_at", prefixed with "-" for descending order; ties are broken by
+// id. After is the NextCursor of the previous page. Each list applies only
+// the filters that make sense for it.
+type ListOptions struct {
+	Limit        int
+	After        string
+	Sort         string
+	NameContains string
+	Role         string
+	SchoolID     uint
+	TeacherID    uint
+}
+
+// Page is one page of a list. NextCursor is empty on the last page and Total
+// counts every row matching the filters.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor"`
+	Total      int64  `json:"total"`
+}
+
+// cursor holds the sort key of the last row on a page.
+type cursor struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
+
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.ID == 0 {
+		return c, ErrInvalidListOptions
+	}
+	return c, nil
+}
+
+func (c cursor) value(field string) any {
+	switch field {
+	case "name":
+		return c.Name
+	case "created_at":
+		return c.CreatedAt
+	}
+	return c.ID
+}
+
+// nameContains matches column against a substring, with LIKE wildcards in
+// the substring taken literally.
+func nameContains(q *gorm.DB, column, s string) *gorm.DB {
+	if s == "" {
+		return q
+	}
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return q.Where(column+` LIKE ? ESCAPE '\'`, "%"+s+"%")
+}
+
+// paginate runs q one page at a time, ordered by opts.Sort on table. key
+// reads the sort key of a row for the next cursor. Preloads are applied only
+// to the page itself, not to the count.
+func paginate[T any](q *gorm.DB, table string, opts ListOptions, key func(*T) cursor, preloads ...string) (Page[T], error) {
+	var page Page[T]
+
+	limit := opts.Limit
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+	if limit < 0 || limit > MaxPageLimit {
+		return page, ErrInvalidListOptions
+	}
+
+	field, desc := strings.CutPrefix(opts.Sort, "-")
+	switch field {
+	case "":
+		field = "id"
+	case "id", "name", "created_at":
+	default:
+		return page, ErrInvalidListOptions
+	}
+	dir, cmp := "ASC", ">"
+	if desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if err := q.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return page, err
+	}
+
+	id := table + ".id"
+	col := table + "." + field
+	q = q.Session(&gorm.Session{})
+	if opts.After != "" {
+		c, err := decodeCursor(opts.After)
+		if err != nil {
+			return page, err
+		}
+		if field == "id" {
+			q = q.Where(fmt.Sprintf("%s %s ?", id, cmp), c.ID)
+		} else {
+			v := c.value(field)
+			q = q.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", col, cmp, col, id, cmp), v, v, c.ID)
+		}
+	}
+	if field != "id" {
+		q = q.Order(col + " " + dir)
+	}
+	q = q.Order(id + " " + dir)
+	for _, p := range preloads {
+		q = q.Preload(p)
+	}
+
+	if err := q.Limit(limit + 1).Find(&page.Items).Error; err != nil {
+		return page, err
+	}
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		page.NextCursor = key(&page.Items[limit-1]).encode()
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	return page, nil
+}
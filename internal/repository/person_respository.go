@@ -15,10 +15,10 @@ func NewPersonRepositrory(db *gorm.DB) *PersonRepositrory {
 	return &PersonRepositrory{db: db}
 }
 
-func (r *PersonRepositrory) Create(name string, role string) (*models.Person, error) {
-	pr := &models.Person{
-		Name: name,
-		Role: role,
+func (r *PersonRepositrory) Create(name string, roles ...string) (*models.Person, error) {
+	pr := &models.Person{Name: name}
+	for _, role := range roles {
+		pr.Roles = append(pr.Roles, models.PersonRole{Role: role})
 	}
 
 	if err := r.db.Create(pr).Error; err != nil {
@@ -30,7 +30,7 @@ func (r *PersonRepositrory) Create(name string, role string) (*models.Person, er
 func (r *PersonRepositrory) GetByID(id uint) (*models.Person, error) {
 	var person models.Person
 
-	err := r.db.First(&person, id).Error
+	err := r.db.Preload("Roles").First(&person, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -43,3 +43,48 @@ func (r *PersonRepositrory) GetByID(id uint) (*models.Person, error) {
 func (pr *PersonRepositrory) UpdateStudentSchoolID(studentID uint, schoolID uint) error {
 	return pr.db.Model(&models.Person{}).Where("id = ?", studentID).Update("student_school_id", schoolID).Error
 }
+
+func (pr *PersonRepositrory) ResetStudentSchoolID(studentID uint) error {
+	return pr.db.Model(&models.Person{}).Where("id = ?", studentID).Update("student_school_id", nil).Error
+}
+
+func (pr *PersonRepositrory) SetPasswordHash(personID uint, hash string) error {
+	return pr.db.Model(&models.Person{}).Where("id = ?", personID).Update("password_hash", hash).Error
+}
+
+func (pr *PersonRepositrory) CountWithPassword() (int64, error) {
+	var n int64
+	err := pr.db.Model(&models.Person{}).Where("password_hash <> ''").Count(&n).Error
+	return n, err
+}
+
+func (pr *PersonRepositrory) UpdateName(personID uint, name string) error {
+	return pr.db.Model(&models.Person{}).Where("id = ?", personID).Update("name", name).Error
+}
+
+// SetRoles replaces every role the person holds with roles.
+func (pr *PersonRepositrory) SetRoles(personID uint, roles ...string) error {
+	if err := pr.db.Where("person_id = ?", personID).Delete(&models.PersonRole{}).Error; err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if err := pr.db.Create(&models.PersonRole{PersonID: personID, Role: role}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearStudentSchoolID detaches every student from the school so they can
+// enroll elsewhere.
+func (pr *PersonRepositrory) ClearStudentSchoolID(schoolID uint) error {
+	return pr.db.Model(&models.Person{}).Where("student_school_id = ?", schoolID).Update("student_school_id", nil).Error
+}
+
+// Delete removes the person together with their roles.
+func (pr *PersonRepositrory) Delete(personID uint) error {
+	if err := pr.db.Where("person_id = ?", personID).Delete(&models.PersonRole{}).Error; err != nil {
+		return err
+	}
+	return pr.db.Delete(&models.Person{}, personID).Error
+}
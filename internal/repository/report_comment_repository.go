@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReportCommentRepository struct {
+	db *gorm.DB
+}
+
+func NewReportCommentRepository(db *gorm.DB) *ReportCommentRepository {
+	return &ReportCommentRepository{db: db}
+}
+
+// Set stores the comment on the student in the class, replacing any earlier
+// one.
+func (rr *ReportCommentRepository) Set(classID, studentID, authorID uint, comment string) error {
+	c := &models.ReportComment{
+		ClassID:   classID,
+		StudentID: studentID,
+		AuthorID:  authorID,
+		Comment:   comment,
+	}
+
+	return rr.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "class_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"author_id", "comment", "updated_at"}),
+	}).Create(c).Error
+}
+
+func (rr *ReportCommentRepository) Get(classID, studentID uint) (*models.ReportComment, error) {
+	var c models.ReportComment
+
+	err := rr.db.Where("class_id = ? AND student_id = ?", classID, studentID).First(&c).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (rr *ReportCommentRepository) DeleteByClassID(classID uint) error {
+	return rr.db.Where("class_id = ?", classID).Delete(&models.ReportComment{}).Error
+}
+
+func (rr *ReportCommentRepository) DeleteByStudentID(studentID uint) error {
+	return rr.db.Where("student_id = ?", studentID).Delete(&models.ReportComment{}).Error
+}
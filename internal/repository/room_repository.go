@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type RoomRepository struct {
+	db *gorm.DB
+}
+
+func NewRoomRepository(db *gorm.DB) *RoomRepository {
+	return &RoomRepository{db: db}
+}
+
+func (rr *RoomRepository) Create(schoolID uint, name string) (*models.Room, error) {
+	room := &models.Room{
+		SchoolID: schoolID,
+		Name:     name,
+	}
+
+	if err := rr.db.Create(room).Error; err != nil {
+		return nil, err
+	}
+
+	return room, nil
+}
+
+func (rr *RoomRepository) GetByID(id uint) (*models.Room, error) {
+	var room models.Room
+	if err := rr.db.First(&room, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &room, nil
+}
+
+func (rr *RoomRepository) ListBySchoolID(schoolID uint) ([]models.Room, error) {
+	var rooms []models.Room
+	if err := rr.db.Where("school_id = ?", schoolID).Order("name ASC").Find(&rooms).Error; err != nil {
+		return nil, err
+	}
+	return rooms, nil
+}
+
+func (rr *RoomRepository) DeleteBySchoolID(schoolID uint) error {
+	return rr.db.Where("school_id = ?", schoolID).Delete(&models.Room{}).Error
+}
@@ -15,12 +15,13 @@ func NewSchoolRepository(db *gorm.DB) *SchoolRepository {
 	return &SchoolRepository{db: db}
 }
 
-func (r *SchoolRepository) List() ([]models.School, error) {
-	var schools []models.School
-	if err := r.db.Preload("Classes").Preload("Classes.Teacher").Order("id ASC").Find(&schools).Error; err != nil {
-		return nil, err
-	}
-	return schools, nil
+// List pages through the schools, filtered by name. Classes are not loaded;
+// ClassRepository.PageBySchoolAndTermID pages through those.
+func (r *SchoolRepository) List(opts ListOptions) (Page[models.School], error) {
+	q := nameContains(r.db.Model(&models.School{}), "schools.name", opts.NameContains)
+	return paginate(q, "schools", opts, func(s *models.School) cursor {
+		return cursor{ID: s.ID, Name: s.Name, CreatedAt: s.CreatedAt}
+	})
 }
 func (r *SchoolRepository) Create(name string) (*models.School, error) {
 	sr := &models.School{
@@ -41,3 +42,11 @@ func (r *SchoolRepository) GetByID(id uint) (*models.School, error) {
 	}
 	return &school, nil
 }
+
+func (r *SchoolRepository) UpdateName(id uint, name string) error {
+	return r.db.Model(&models.School{}).Where("id = ?", id).Update("name", name).Error
+}
+
+func (r *SchoolRepository) Delete(id uint) error {
+	return r.db.Delete(&models.School{}, id).Error
+}
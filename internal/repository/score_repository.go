@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScoreRepository struct {
+	db *gorm.DB
+}
+
+func NewScoreRepository(db *gorm.DB) *ScoreRepository {
+	return &ScoreRepository{db: db}
+}
+
+// Record stores the student's points on the assessment, replacing any
+// earlier score.
+func (sr *ScoreRepository) Record(assessmentID, studentID uint, points float64) error {
+	s := &models.Score{
+		AssessmentID: assessmentID,
+		StudentID:    studentID,
+		Points:       points,
+	}
+
+	return sr.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "assessment_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"points", "updated_at"}),
+	}).Create(s).Error
+}
+
+func (sr *ScoreRepository) ListByStudentID(studentID uint, assessmentIDs []uint) ([]models.Score, error) {
+	var ss []models.Score
+	if len(assessmentIDs) == 0 {
+		return ss, nil
+	}
+
+	err := sr.db.Where("student_id = ? AND assessment_id IN ?", studentID, assessmentIDs).Find(&ss).Error
+	return ss, err
+}
+
+func (sr *ScoreRepository) DeleteByClassID(classID uint) error {
+	assessments := sr.db.Model(&models.Assessment{}).Select("id").Where("class_id = ?", classID)
+	return sr.db.Where("assessment_id IN (?)", assessments).Delete(&models.Score{}).Error
+}
+
+func (sr *ScoreRepository) DeleteByStudentID(studentID uint) error {
+	return sr.db.Where("student_id = ?", studentID).Delete(&models.Score{}).Error
+}
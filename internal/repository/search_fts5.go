@@ -0,0 +1,63 @@
+//go:build sqlite_fts5
+
+// The sqlite_fts5 tag is go-sqlite3's switch for compiling SQLite with FTS5,
+// so this file is only built when that is available. See cmd/main.go.
+
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// migrateSearch creates the search_index FTS5 table, filling it from the
+// existing rows, and the triggers that keep it in step with them.
+func migrateSearch(db *gorm.DB) error {
+	exists := db.Migrator().HasTable("search_index")
+	return db.Transaction(func(tx *gorm.DB) error {
+		if !exists {
+			if err := tx.Exec("CREATE VIRTUAL TABLE search_index USING fts5(name, prefix = '2 3')").Error; err != nil {
+				return err
+			}
+		}
+		for _, st := range searchTables {
+			stmts := []string{
+				fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_search_insert AFTER INSERT ON %[1]s BEGIN
+					INSERT INTO search_index (rowid, name) VALUES (new.id * 4 + %[2]d, new.name); END`, st.table, st.kind),
+				fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_search_update AFTER UPDATE OF name ON %[1]s BEGIN
+					UPDATE search_index SET name = new.name WHERE rowid = new.id * 4 + %[2]d; END`, st.table, st.kind),
+				fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_search_delete AFTER DELETE ON %[1]s BEGIN
+					DELETE FROM search_index WHERE rowid = old.id * 4 + %[2]d; END`, st.table, st.kind),
+			}
+			if !exists {
+				stmts = append(stmts, fmt.Sprintf("INSERT INTO search_index (rowid, name) SELECT id * 4 + %d, name FROM %s", st.kind, st.table))
+			}
+			for _, stmt := range stmts {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// search ranks matches by bm25, so rarer words and shorter names come first.
+func (sr *SearchRepository) search(terms []string, scoped bool, args []any) *gorm.DB {
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+	args = append(args, sql.Named("match", strings.Join(match, " ")))
+
+	q := `SELECT search_index.rowid AS row_id, name, ` + searchSchoolID + ` AS school_id, -bm25(search_index) AS score
+		FROM search_index WHERE search_index MATCH @match`
+	if scoped {
+		q += " AND " + searchScope
+	}
+	q += " ORDER BY bm25(search_index), search_index.rowid LIMIT @limit"
+	return sr.db.Raw(q, args...)
+}
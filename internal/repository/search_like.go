@@ -0,0 +1,76 @@
+//go:build !sqlite_fts5
+
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Without the sqlite_fts5 build tag SQLite has no FTS5, so search scans the
+// name columns with LIKE instead and there is no index to maintain.
+//
+// A database last opened by an FTS5 build still has the index and the
+// triggers feeding it, and every write to a searchable table would fail on
+// the missing module. migrateSearch drops them; the next FTS5 build then
+// rebuilds the index from the tables. SQLite cannot DROP a virtual table
+// whose module is missing, so its schema row is deleted directly after its
+// shadow tables are dropped.
+func migrateSearch(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, st := range searchTables {
+			for _, event := range []string{"insert", "update", "delete"} {
+				if err := tx.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS %s_search_%s", st.table, event)).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if !tx.Migrator().HasTable("search_index") {
+			return nil
+		}
+
+		stmts := []string{}
+		for _, shadow := range []string{"data", "idx", "content", "docsize", "config"} {
+			stmts = append(stmts, "DROP TABLE IF EXISTS search_index_"+shadow)
+		}
+		stmts = append(stmts,
+			"PRAGMA writable_schema = ON",
+			"DELETE FROM sqlite_master WHERE type = 'table' AND name = 'search_index'",
+			"PRAGMA writable_schema = OFF",
+		)
+		for _, stmt := range stmts {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+const searchNames = `(SELECT id * 4 + 1 AS rowid, name FROM schools
+	UNION ALL SELECT id * 4 + 2, name FROM classes
+	UNION ALL SELECT id * 4 + 3, name FROM people) AS search_index`
+
+// search ranks a name equal to the query first, then names starting with its
+// first term, then the rest. Only spaces separate words here, whereas FTS5
+// also splits on punctuation.
+func (sr *SearchRepository) search(terms []string, scoped bool, args []any) *gorm.DB {
+	conds := make([]string, len(terms))
+	for i, term := range terms {
+		conds[i] = fmt.Sprintf("(' ' || name) LIKE @term%d", i)
+		args = append(args, sql.Named(fmt.Sprintf("term%d", i), "% "+term+"%"))
+	}
+	args = append(args, sql.Named("whole", strings.Join(terms, " ")), sql.Named("first", terms[0]+"%"))
+
+	q := `SELECT search_index.rowid AS row_id, name, ` + searchSchoolID + ` AS school_id,
+		CASE WHEN name LIKE @whole THEN 3 WHEN name LIKE @first THEN 2 ELSE 1 END AS score
+		FROM ` + searchNames + ` WHERE ` + strings.Join(conds, " AND ")
+	if scoped {
+		q += " AND " + searchScope
+	}
+	q += " ORDER BY score DESC, name, search_index.rowid LIMIT @limit"
+	return sr.db.Raw(q, args...)
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+	"database/sql"
+	"html"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Every searchable record has a row id of id*4 + its kind, so schools,
+// classes and people share one index without colliding.
+const (
+	searchSchool = 1
+	searchClass  = 2
+	searchPerson = 3
+)
+
+var searchKinds = map[int]string{
+	searchSchool: models.SearchSchool,
+	searchClass:  models.SearchClass,
+	searchPerson: models.SearchPerson,
+}
+
+// searchTables are the tables whose names go into search_index, with the
+// kind each one's rows are stored under.
+var searchTables = []struct {
+	table string
+	kind  int
+}{
+	{"schools", searchSchool},
+	{"classes", searchClass},
+	{"people", searchPerson},
+}
+
+// searchSchoolID is the school of the record in each search_index row, see
+// models.SearchResult.
+const searchSchoolID = `CASE search_index.rowid % 4
+	WHEN 1 THEN search_index.rowid / 4
+	WHEN 2 THEN (SELECT school_id FROM classes WHERE id = search_index.rowid / 4)
+	ELSE (SELECT student_school_id FROM people WHERE id = search_index.rowid / 4) END`
+
+// searchScope keeps the search_index rows belonging to school @school: the
+// school itself, its classes, their teachers and its students.
+const searchScope = `((search_index.rowid % 4 = 1 AND search_index.rowid / 4 = @school)
+	OR (search_index.rowid % 4 = 2 AND search_index.rowid / 4 IN (SELECT id FROM classes WHERE school_id = @school))
+	OR (search_index.rowid % 4 = 3 AND search_index.rowid / 4 IN (
+		SELECT id FROM people WHERE student_school_id = @school
+		UNION SELECT teacher_id FROM classes WHERE school_id = @school)))`
+
+type searchRow struct {
+	RowID    int64
+	Name     string
+	SchoolID *uint
+	Score    float64
+}
+
+type SearchRepository struct {
+	db *gorm.DB
+}
+
+func NewSearchRepository(db *gorm.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// Search finds the schools, classes and people whose names contain a word
+// starting with each of terms, best matches first. Terms are single words of
+// letters and digits. A schoolID other than 0 limits the results to that
+// school.
+func (sr *SearchRepository) Search(terms []string, schoolID uint, limit int) ([]models.SearchResult, error) {
+	var rows []searchRow
+	args := []any{sql.Named("limit", limit)}
+	if schoolID != 0 {
+		args = append(args, sql.Named("school", schoolID))
+	}
+	if err := sr.search(terms, schoolID != 0, args).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]models.SearchResult, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, models.SearchResult{
+			Type:     searchKinds[int(row.RowID%4)],
+			ID:       uint(row.RowID / 4),
+			Name:     row.Name,
+			Snippet:  snippet(row.Name, terms),
+			SchoolID: row.SchoolID,
+			Score:    row.Score,
+		})
+	}
+	return results, nil
+}
+
+// snippet escapes name for HTML and wraps every word start matching one of
+// terms in <mark> tags.
+func snippet(name string, terms []string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i := 0; i < len(runes); {
+		if i == 0 || !isWordRune(runes[i-1]) {
+			if n := matchTerm(runes[i:], terms); n > 0 {
+				b.WriteString("<mark>" + html.EscapeString(string(runes[i:i+n])) + "</mark>")
+				i += n
+				continue
+			}
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+		i++
+	}
+	return b.String()
+}
+
+// matchTerm returns how many runes of the longest term prefixing rs there
+// are, ignoring case.
+func matchTerm(rs []rune, terms []string) int {
+	longest := 0
+	for _, term := range terms {
+		n := len([]rune(term))
+		if n > longest && n <= len(rs) && strings.EqualFold(string(rs[:n]), term) {
+			longest = n
+		}
+	}
+	return longest
+}
+
+// SearchTerms splits a query into the words Search takes, dropping
+// punctuation.
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(query, func(r rune) bool { return !isWordRune(r) })
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (sr *SessionRepository) Create(personID uint, tokenHash string, expiresAt time.Time) (*models.Session, error) {
+	s := &models.Session{
+		PersonID:  personID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}
+
+	if err := sr.db.Create(s).Error; err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (sr *SessionRepository) GetByTokenHash(tokenHash string) (*models.Session, error) {
+	var s models.Session
+
+	err := sr.db.Where("token_hash = ?", tokenHash).First(&s).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (sr *SessionRepository) Revoke(sessionID uint, at time.Time) error {
+	return sr.db.Model(&models.Session{}).Where("id = ? AND revoked_at IS NULL", sessionID).Update("revoked_at", at).Error
+}
+
+func (sr *SessionRepository) RevokeByPersonID(personID uint, at time.Time) (int64, error) {
+	tx := sr.db.Model(&models.Session{}).Where("person_id = ? AND revoked_at IS NULL", personID).Update("revoked_at", at)
+	return tx.RowsAffected, tx.Error
+}
+
+func (sr *SessionRepository) DeleteByPersonID(personID uint) error {
+	return sr.db.Where("person_id = ?", personID).Delete(&models.Session{}).Error
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SlotRepository struct {
+	db *gorm.DB
+}
+
+func NewSlotRepository(db *gorm.DB) *SlotRepository {
+	return &SlotRepository{db: db}
+}
+
+func (sr *SlotRepository) Create(classID, roomID uint, weekday int, start, end string) (*models.Slot, error) {
+	s := &models.Slot{
+		ClassID:   classID,
+		RoomID:    roomID,
+		Weekday:   weekday,
+		StartTime: start,
+		EndTime:   end,
+	}
+
+	if err := sr.db.Create(s).Error; err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (sr *SlotRepository) GetByID(id uint) (*models.Slot, error) {
+	var s models.Slot
+	if err := sr.db.First(&s, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (sr *SlotRepository) Update(slotID, roomID uint, weekday int, start, end string) error {
+	return sr.db.Model(&models.Slot{}).Where("id = ?", slotID).Updates(map[string]any{
+		"room_id":    roomID,
+		"weekday":    weekday,
+		"start_time": start,
+		"end_time":   end,
+	}).Error
+}
+
+func (sr *SlotRepository) Delete(slotID uint) error {
+	return sr.db.Delete(&models.Slot{}, slotID).Error
+}
+
+func (sr *SlotRepository) DeleteByClassID(classID uint) error {
+	return sr.db.Where("class_id = ?", classID).Delete(&models.Slot{}).Error
+}
+
+func (sr *SlotRepository) ListByClassID(classID uint) ([]models.Slot, error) {
+	var slots []models.Slot
+	if err := sr.db.Where("class_id = ?", classID).Order("weekday ASC, start_time ASC").Find(&slots).Error; err != nil {
+		return nil, err
+	}
+	return slots, nil
+}
+
+// ListByClassIDs returns the slots of the classes with their class and room
+// loaded, in weekly order.
+func (sr *SlotRepository) ListByClassIDs(classIDs []uint) ([]models.Slot, error) {
+	slots := []models.Slot{}
+	if len(classIDs) == 0 {
+		return slots, nil
+	}
+	err := sr.db.Preload("Class").Preload("Room").Where("class_id IN ?", classIDs).
+		Order("weekday ASC, start_time ASC, id ASC").Find(&slots).Error
+	if err != nil {
+		return nil, err
+	}
+	return slots, nil
+}
+
+// Clash describes a weekly time range to test against booked slots. Only
+// slots of classes whose term overlaps TermStart..TermEnd count, and the
+// slot and class named by ExcludeSlotID and ExcludeClassID are ignored.
+type Clash struct {
+	Weekday        int
+	Start, End     string
+	TermStart      time.Time
+	TermEnd        time.Time
+	ExcludeSlotID  uint
+	ExcludeClassID uint
+}
+
+func (sr *SlotRepository) clashing(c Clash) *gorm.DB {
+	return sr.db.Model(&models.Slot{}).
+		Joins("JOIN classes ON classes.id = slots.class_id").
+		Joins("JOIN terms ON terms.id = classes.term_id").
+		Where("slots.weekday = ? AND slots.start_time < ? AND slots.end_time > ?", c.Weekday, c.End, c.Start).
+		Where("terms.starts_at < ? AND terms.ends_at > ?", c.TermEnd.UTC(), c.TermStart.UTC()).
+		Where("slots.id <> ? AND slots.class_id <> ?", c.ExcludeSlotID, c.ExcludeClassID)
+}
+
+// RoomBooked reports whether the room already has a slot that clashes.
+func (sr *SlotRepository) RoomBooked(roomID uint, c Clash) (bool, error) {
+	var n int64
+	err := sr.clashing(c).Where("slots.room_id = ?", roomID).Count(&n).Error
+	return n > 0, err
+}
+
+// PersonBooked reports whether the person teaches or attends a class with a
+// slot that clashes.
+func (sr *SlotRepository) PersonBooked(personID uint, c Clash) (bool, error) {
+	var n int64
+	err := sr.clashing(c).
+		Where("classes.teacher_id = ? OR classes.id IN (?)", personID,
+			sr.db.Model(&models.Enrollment{}).Select("class_id").Where("student_id = ?", personID)).
+		Count(&n).Error
+	return n > 0, err
+}
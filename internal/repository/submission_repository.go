@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type SubmissionRepository struct {
+	db *gorm.DB
+}
+
+func NewSubmissionRepository(db *gorm.DB) *SubmissionRepository {
+	return &SubmissionRepository{db: db}
+}
+
+func (sr *SubmissionRepository) Create(s *models.Submission) error {
+	return sr.db.Create(s).Error
+}
+
+// Save writes back a submission loaded with Get.
+func (sr *SubmissionRepository) Save(s *models.Submission) error {
+	return sr.db.Save(s).Error
+}
+
+func (sr *SubmissionRepository) Get(assignmentID, studentID uint) (*models.Submission, error) {
+	var s models.Submission
+
+	err := sr.db.Where("assignment_id = ? AND student_id = ?", assignmentID, studentID).First(&s).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (sr *SubmissionRepository) ListByAssignmentID(assignmentID uint) ([]models.Submission, error) {
+	var ss []models.Submission
+	err := sr.db.Where("assignment_id = ?", assignmentID).Find(&ss).Error
+	return ss, err
+}
+
+func (sr *SubmissionRepository) DeleteByClassID(classID uint) error {
+	assignments := sr.db.Model(&models.Assignment{}).Select("id").Where("class_id = ?", classID)
+	return sr.db.Where("assignment_id IN (?)", assignments).Delete(&models.Submission{}).Error
+}
+
+func (sr *SubmissionRepository) DeleteByStudentID(studentID uint) error {
+	return sr.db.Where("student_id = ?", studentID).Delete(&models.Submission{}).Error
+}
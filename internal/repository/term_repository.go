@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The default term is open for as long as anyone could care about.
+var (
+	defaultTermStart = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	defaultTermEnd   = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+)
+
+type TermRepository struct {
+	db *gorm.DB
+}
+
+func NewTermRepository(db *gorm.DB) *TermRepository {
+	return &TermRepository{db: db}
+}
+
+// Create stores the bounds in UTC. SQLite compares times as text, so every
+// stored and queried time must share one zone.
+func (tr *TermRepository) Create(schoolID uint, name string, startsAt, endsAt time.Time) (*models.Term, error) {
+	t := &models.Term{
+		SchoolID: schoolID,
+		Name:     name,
+		StartsAt: startsAt.UTC(),
+		EndsAt:   endsAt.UTC(),
+	}
+
+	if err := tr.db.Create(t).Error; err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (tr *TermRepository) GetByID(id uint) (*models.Term, error) {
+	var t models.Term
+	if err := tr.db.First(&t, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (tr *TermRepository) ListBySchoolID(schoolID uint) ([]models.Term, error) {
+	var terms []models.Term
+	if err := tr.db.Where("school_id = ?", schoolID).Order("starts_at ASC, id ASC").Find(&terms).Error; err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
+func (tr *TermRepository) CountBySchoolID(schoolID uint) (int64, error) {
+	var n int64
+	err := tr.db.Model(&models.Term{}).Where("school_id = ?", schoolID).Count(&n).Error
+	return n, err
+}
+
+// Current returns the school's term that is open at the given time, or nil
+// when there is none. When terms overlap the one that started last wins.
+func (tr *TermRepository) Current(schoolID uint, at time.Time) (*models.Term, error) {
+	var t models.Term
+	at = at.UTC()
+	err := tr.db.Where("school_id = ? AND starts_at <= ? AND ends_at > ?", schoolID, at, at).
+		Order("starts_at DESC, id DESC").First(&t).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetOrCreateDefault returns the school's open-ended default term.
+func (tr *TermRepository) GetOrCreateDefault(schoolID uint) (*models.Term, error) {
+	var t models.Term
+	err := tr.db.Where("school_id = ? AND name = ?", schoolID, models.DefaultTermName).First(&t).Error
+	if err == nil {
+		return &t, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return tr.Create(schoolID, models.DefaultTermName, defaultTermStart, defaultTermEnd)
+}
+
+func (tr *TermRepository) DeleteBySchoolID(schoolID uint) error {
+	return tr.db.Where("school_id = ?", schoolID).Delete(&models.Term{}).Error
+}
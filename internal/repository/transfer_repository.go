@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TransferRepository struct {
+	db *gorm.DB
+}
+
+func NewTransferRepository(db *gorm.DB) *TransferRepository {
+	return &TransferRepository{db: db}
+}
+
+func (tr *TransferRepository) Create(studentID uint, fromSchoolID *uint, toSchoolID uint, reason string, at time.Time) (*models.Transfer, error) {
+	t := &models.Transfer{
+		StudentID:     studentID,
+		FromSchoolID:  fromSchoolID,
+		ToSchoolID:    toSchoolID,
+		Reason:        reason,
+		TransferredAt: at,
+	}
+
+	if err := tr.db.Create(t).Error; err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (tr *TransferRepository) ListByStudentID(studentID uint) ([]models.Transfer, error) {
+	var transfers []models.Transfer
+	err := tr.db.Where("student_id = ?", studentID).Order("transferred_at ASC, id ASC").Find(&transfers).Error
+	if err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+func (tr *TransferRepository) DeleteByStudentID(studentID uint) error {
+	return tr.db.Where("student_id = ?", studentID).Delete(&models.Transfer{}).Error
+}
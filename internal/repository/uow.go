@@ -7,6 +7,50 @@ type Repos struct {
 	Class      *ClassRepository
 	Enrollment *EnrollmentRepository
 	School     *SchoolRepository
+	Session    *SessionRepository
+	Transfer   *TransferRepository
+	Waitlist   *WaitlistRepository
+	Term       *TermRepository
+	Room       *RoomRepository
+	Slot       *SlotRepository
+	Assessment *AssessmentRepository
+	Score      *ScoreRepository
+	Attendance *AttendanceRepository
+	Assignment *AssignmentRepository
+	Submission *SubmissionRepository
+	Comment    *ReportCommentRepository
+	Guardian   *GuardianRepository
+	Search     *SearchRepository
+	Export     *ExportRepository
+
+	// UnitOfWork is bound to the same handle as the repos above. Calling
+	// WithinTx on it from inside a transaction opens a savepoint.
+	UnitOfWork *UnitOfWork
+}
+
+func NewRepos(db *gorm.DB) Repos {
+	return Repos{
+		Person:     NewPersonRepositrory(db),
+		Class:      NewClassRepository(db),
+		School:     NewSchoolRepository(db),
+		Enrollment: NewEnrollmentRepository(db),
+		Session:    NewSessionRepository(db),
+		Transfer:   NewTransferRepository(db),
+		Waitlist:   NewWaitlistRepository(db),
+		Term:       NewTermRepository(db),
+		Room:       NewRoomRepository(db),
+		Slot:       NewSlotRepository(db),
+		Assessment: NewAssessmentRepository(db),
+		Score:      NewScoreRepository(db),
+		Attendance: NewAttendanceRepository(db),
+		Assignment: NewAssignmentRepository(db),
+		Submission: NewSubmissionRepository(db),
+		Comment:    NewReportCommentRepository(db),
+		Guardian:   NewGuardianRepository(db),
+		Search:     NewSearchRepository(db),
+		Export:     NewExportRepository(db),
+		UnitOfWork: NewUnitOfWork(db),
+	}
 }
 
 type UnitOfWork struct {
@@ -19,12 +63,6 @@ func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
 
 func (uow *UnitOfWork) WithinTx(fn func(r Repos) error) error {
 	return uow.db.Transaction(func(tx *gorm.DB) error {
-		r := Repos{
-			Person:     NewPersonRepositrory(tx),
-			Class:      NewClassRepository(tx),
-			School:     NewSchoolRepository(tx),
-			Enrollment: NewEnrollmentRepository(tx),
-		}
-		return fn(r)
+		return fn(NewRepos(tx))
 	})
 }
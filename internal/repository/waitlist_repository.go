@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"OldSchool/internal/repository/models"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type WaitlistRepository struct {
+	db *gorm.DB
+}
+
+func NewWaitlistRepository(db *gorm.DB) *WaitlistRepository {
+	return &WaitlistRepository{db: db}
+}
+
+func (wr *WaitlistRepository) Add(classID, studentID uint) (*models.Waitlist, error) {
+	w := &models.Waitlist{
+		ClassID:   classID,
+		StudentID: studentID,
+	}
+
+	if err := wr.db.Create(w).Error; err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Position returns the student's 1-based place on the class waitlist, or 0
+// when they are not on it.
+func (wr *WaitlistRepository) Position(classID, studentID uint) (int, error) {
+	var entry models.Waitlist
+	err := wr.db.Where("class_id = ? AND student_id = ?", classID, studentID).First(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var ahead int64
+	err = wr.db.Model(&models.Waitlist{}).Where("class_id = ? AND id < ?", classID, entry.ID).Count(&ahead).Error
+	if err != nil {
+		return 0, err
+	}
+	return int(ahead) + 1, nil
+}
+
+func (wr *WaitlistRepository) CountByClassID(classID uint) (int64, error) {
+	var n int64
+	err := wr.db.Model(&models.Waitlist{}).Where("class_id = ?", classID).Count(&n).Error
+	return n, err
+}
+
+// First returns the entry at the head of the class waitlist, or nil when
+// nobody is waiting.
+func (wr *WaitlistRepository) First(classID uint) (*models.Waitlist, error) {
+	var entry models.Waitlist
+	err := wr.db.Where("class_id = ?", classID).Order("id ASC").First(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (wr *WaitlistRepository) ListByClassID(classID uint) ([]models.Waitlist, error) {
+	var entries []models.Waitlist
+	if err := wr.db.Where("class_id = ?", classID).Order("id ASC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (wr *WaitlistRepository) Delete(classID, studentID uint) (int64, error) {
+	tx := wr.db.Where("class_id = ? AND student_id = ?", classID, studentID).Delete(&models.Waitlist{})
+	return tx.RowsAffected, tx.Error
+}
+
+func (wr *WaitlistRepository) DeleteByClassID(classID uint) error {
+	return wr.db.Where("class_id = ?", classID).Delete(&models.Waitlist{}).Error
+}
+
+func (wr *WaitlistRepository) DeleteByStudentID(studentID uint) error {
+	return wr.db.Where("student_id = ?", studentID).Delete(&models.Waitlist{}).Error
+}
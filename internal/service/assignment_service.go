@@ -0,0 +1,223 @@
+package service
+
+import (
+	"OldSchool/internal/repository"
+	"OldSchool/internal/repository/models"
+	"strings"
+	"time"
+)
+
+type AssignmentRepo interface {
+	GetByID(id uint) (*models.Assignment, error)
+	ListByClassID(classID uint) ([]models.Assignment, error)
+}
+
+type SubmissionRepo interface {
+	ListByAssignmentID(assignmentID uint) ([]models.Submission, error)
+}
+
+type ClassRepoForAssignment interface {
+	GetByID(id uint) (*models.Class, error)
+}
+
+type EnrollmentRepoForAssignment interface {
+	ListStudentsByClassID(classID uint) ([]models.Person, error)
+}
+
+type AssignmentService struct {
+	assignmentRepo AssignmentRepo
+	submissionRepo SubmissionRepo
+	classRepo      ClassRepoForAssignment
+	enrollmentRepo EnrollmentRepoForAssignment
+	uow            UnitOfWork
+	now            func() time.Time
+}
+
+func NewAssignmentService(assignmentRepo AssignmentRepo, submissionRepo SubmissionRepo, classRepo ClassRepoForAssignment, enrollmentRepo EnrollmentRepoForAssignment, uow UnitOfWork) *AssignmentService {
+	return &AssignmentService{
+		assignmentRepo: assignmentRepo,
+		submissionRepo: submissionRepo,
+		classRepo:      classRepo,
+		enrollmentRepo: enrollmentRepo,
+		uow:            uow,
+		now:            time.Now,
+	}
+}
+
+// SubmissionStatus is one enrolled student's line in the teacher's view of
+// an assignment. Submission is nil for students who have not handed in.
+type SubmissionStatus struct {
+	StudentID   uint               `json:"student_id"`
+	StudentName string             `json:"student_name"`
+	Submitted   bool               `json:"submitted"`
+	Submission  *models.Submission `json:"submission"`
+}
+
+func (as *AssignmentService) Create(classID uint, title, description string, dueAt time.Time) (*models.Assignment, error) {
+	title = strings.TrimSpace(title)
+	if classID == 0 || title == "" || dueAt.IsZero() {
+		return nil, ErrInvalidInput
+	}
+
+	var created *models.Assignment
+	err := as.uow.WithinTx(func(r repository.Repos) error {
+		cl, err := r.Class.GetByID(classID)
+		if err != nil {
+			return err
+		}
+		if cl == nil {
+			return ErrNotFound
+		}
+		created, err = r.Assignment.Create(classID, title, strings.TrimSpace(description), dueAt.UTC())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (as *AssignmentService) List(classID uint) ([]models.Assignment, error) {
+	if classID == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	cl, err := as.classRepo.GetByID(classID)
+	if err != nil {
+		return nil, err
+	}
+	if cl == nil {
+		return nil, ErrNotFound
+	}
+	return as.assignmentRepo.ListByClassID(classID)
+}
+
+// ClassIDOf returns the class an assignment was posted to.
+func (as *AssignmentService) ClassIDOf(assignmentID uint) (uint, error) {
+	if assignmentID == 0 {
+		return 0, ErrInvalidInput
+	}
+
+	a, err := as.assignmentRepo.GetByID(assignmentID)
+	if err != nil {
+		return 0, err
+	}
+	if a == nil {
+		return 0, ErrNotFound
+	}
+	return a.ClassID, nil
+}
+
+// Submit hands in the student's first answer to an assignment. The student
+// must be enrolled in its class, and answers after the due date are flagged
+// late.
+func (as *AssignmentService) Submit(studentID, assignmentID uint, text, fileRef string) (*models.Submission, error) {
+	return as.submit(studentID, assignmentID, text, fileRef, false)
+}
+
+// Resubmit replaces an answer the student handed in before.
+func (as *AssignmentService) Resubmit(studentID, assignmentID uint, text, fileRef string) (*models.Submission, error) {
+	return as.submit(studentID, assignmentID, text, fileRef, true)
+}
+
+func (as *AssignmentService) submit(studentID, assignmentID uint, text, fileRef string, again bool) (*models.Submission, error) {
+	text = strings.TrimSpace(text)
+	fileRef = strings.TrimSpace(fileRef)
+	if studentID == 0 || assignmentID == 0 || (text == "" && fileRef == "") {
+		return nil, ErrInvalidInput
+	}
+
+	var saved *models.Submission
+	err := as.uow.WithinTx(func(r repository.Repos) error {
+		a, err := r.Assignment.GetByID(assignmentID)
+		if err != nil {
+			return err
+		}
+		if a == nil {
+			return ErrNotFound
+		}
+		enrolled, err := r.Enrollment.Exists(a.ClassID, studentID)
+		if err != nil {
+			return err
+		}
+		if !enrolled {
+			return ErrNotEnrolled
+		}
+
+		s, err := r.Submission.Get(assignmentID, studentID)
+		if err != nil {
+			return err
+		}
+		at := as.now().UTC()
+		switch {
+		case s == nil && again:
+			return ErrNotSubmitted
+		case s != nil && !again:
+			return ErrAlreadySubmitted
+		case s == nil:
+			s = &models.Submission{
+				AssignmentID: assignmentID,
+				StudentID:    studentID,
+				Attempts:     1,
+			}
+		default:
+			s.Attempts++
+		}
+		s.Text = text
+		s.FileRef = fileRef
+		s.SubmittedAt = at
+		s.Late = at.After(a.DueAt)
+
+		if s.ID == 0 {
+			err = r.Submission.Create(s)
+		} else {
+			err = r.Submission.Save(s)
+		}
+		saved = s
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// Submissions lists every student enrolled in the assignment's class with
+// their submission, if any.
+func (as *AssignmentService) Submissions(assignmentID uint) ([]SubmissionStatus, error) {
+	if assignmentID == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	a, err := as.assignmentRepo.GetByID(assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, ErrNotFound
+	}
+	students, err := as.enrollmentRepo.ListStudentsByClassID(a.ClassID)
+	if err != nil {
+		return nil, err
+	}
+	submissions, err := as.submissionRepo.ListByAssignmentID(assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	byStudent := make(map[uint]*models.Submission, len(submissions))
+	for i := range submissions {
+		byStudent[submissions[i].StudentID] = &submissions[i]
+	}
+
+	statuses := []SubmissionStatus{}
+	for _, st := range students {
+		s := byStudent[st.ID]
+		statuses = append(statuses, SubmissionStatus{
+			StudentID:   st.ID,
+			StudentName: st.Name,
+			Submitted:   s != nil,
+			Submission:  s,
+		})
+	}
+	return statuses, nil
+}
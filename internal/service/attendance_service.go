@@ -0,0 +1,233 @@
+package service
+
+import (
+	"OldSchool/internal/repository"
+	"OldSchool/internal/repository/models"
+	"slices"
+	"time"
+)
+
+// DefaultAttendanceThreshold is the attendance rate below which a student is
+// reported when the caller does not pick a threshold.
+const DefaultAttendanceThreshold = 0.8
+
+type AttendanceRepo interface {
+	ListByClassAndDate(classID uint, date string) ([]models.Attendance, error)
+	Summarize(classID, studentID uint) (repository.AttendanceSummary, error)
+	SummarizeByClassIDs(classIDs []uint) ([]repository.AttendanceSummary, error)
+}
+
+type ClassRepoForAttendance interface {
+	GetByID(id uint) (*models.Class, error)
+	ListIDsBySchoolID(schoolID uint) ([]uint, error)
+	FilterIDsByTermID(ids []uint, termID uint) ([]uint, error)
+	FilterIDsByOpenTerm(ids []uint, at time.Time) ([]uint, error)
+}
+
+type AttendanceService struct {
+	attendanceRepo AttendanceRepo
+	classRepo      ClassRepoForAttendance
+	schoolRepo     SchoolRepo
+	personRepo     PersonRepo
+	uow            UnitOfWork
+	now            func() time.Time
+}
+
+func NewAttendanceService(attendanceRepo AttendanceRepo, classRepo ClassRepoForAttendance, schoolRepo SchoolRepo, personRepo PersonRepo, uow UnitOfWork) *AttendanceService {
+	return &AttendanceService{
+		attendanceRepo: attendanceRepo,
+		classRepo:      classRepo,
+		schoolRepo:     schoolRepo,
+		personRepo:     personRepo,
+		uow:            uow,
+		now:            time.Now,
+	}
+}
+
+// AttendanceInput is one student's status in a session.
+type AttendanceInput struct {
+	StudentID uint
+	Status    string
+}
+
+// AttendanceRate sums up a student's attendance in one class. Late counts as
+// attended and excused sessions are left out, so Rate is
+// (present + late) / (present + late + absent). It is nil until the student
+// has a session that counts.
+type AttendanceRate struct {
+	ClassID   uint     `json:"class_id"`
+	StudentID uint     `json:"student_id"`
+	Present   int64    `json:"present"`
+	Absent    int64    `json:"absent"`
+	Late      int64    `json:"late"`
+	Excused   int64    `json:"excused"`
+	Rate      *float64 `json:"rate"`
+}
+
+func attendanceRate(s repository.AttendanceSummary) AttendanceRate {
+	ar := AttendanceRate{
+		ClassID:   s.ClassID,
+		StudentID: s.StudentID,
+		Present:   s.Present,
+		Absent:    s.Absent,
+		Late:      s.Late,
+		Excused:   s.Excused,
+	}
+	if counted := s.Present + s.Late + s.Absent; counted > 0 {
+		rate := float64(s.Present+s.Late) / float64(counted)
+		ar.Rate = &rate
+	}
+	return ar
+}
+
+// sessionDate checks a "YYYY-MM-DD" date and returns it as midnight UTC.
+func sessionDate(date string) (time.Time, error) {
+	d, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return time.Time{}, ErrInvalidInput
+	}
+	return d, nil
+}
+
+// Record stores attendance for one session of the class. Every student must
+// be enrolled in the class and the date must fall within its term; otherwise
+// nothing is saved.
+func (as *AttendanceService) Record(classID uint, date string, records []AttendanceInput) error {
+	if classID == 0 || len(records) == 0 {
+		return ErrInvalidInput
+	}
+	day, err := sessionDate(date)
+	if err != nil {
+		return err
+	}
+
+	return as.uow.WithinTx(func(r repository.Repos) error {
+		cl, err := r.Class.GetByID(classID)
+		if err != nil {
+			return err
+		}
+		if cl == nil {
+			return ErrNotFound
+		}
+		term, err := r.Term.GetByID(cl.TermID)
+		if err != nil {
+			return err
+		}
+		// the session day only has to overlap the term
+		if term == nil || !day.Before(term.EndsAt) || !day.AddDate(0, 0, 1).After(term.StartsAt) {
+			return ErrInvalidInput
+		}
+
+		for _, rec := range records {
+			if rec.StudentID == 0 || !slices.Contains(models.AttendanceStatuses, rec.Status) {
+				return ErrInvalidInput
+			}
+			enrolled, err := r.Enrollment.Exists(classID, rec.StudentID)
+			if err != nil {
+				return err
+			}
+			if !enrolled {
+				return ErrNotEnrolled
+			}
+			if err := r.Attendance.Record(classID, rec.StudentID, date, rec.Status); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Session returns the attendance recorded for the class on the date.
+func (as *AttendanceService) Session(classID uint, date string) ([]models.Attendance, error) {
+	if classID == 0 {
+		return nil, ErrInvalidInput
+	}
+	if _, err := sessionDate(date); err != nil {
+		return nil, err
+	}
+
+	cl, err := as.classRepo.GetByID(classID)
+	if err != nil {
+		return nil, err
+	}
+	if cl == nil {
+		return nil, ErrNotFound
+	}
+	return as.attendanceRepo.ListByClassAndDate(classID, date)
+}
+
+// Rate returns the student's attendance in the class.
+func (as *AttendanceService) Rate(classID, studentID uint) (*AttendanceRate, error) {
+	if classID == 0 || studentID == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	cl, err := as.classRepo.GetByID(classID)
+	if err != nil {
+		return nil, err
+	}
+	if cl == nil {
+		return nil, ErrNotFound
+	}
+	p, err := as.personRepo.GetByID(studentID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrNotFound
+	}
+
+	s, err := as.attendanceRepo.Summarize(classID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	ar := attendanceRate(s)
+	return &ar, nil
+}
+
+// BelowThreshold lists every student of the school whose attendance in one
+// of their classes is under threshold, a rate between 0 and 1. A threshold of
+// 0 means DefaultAttendanceThreshold, and a termID of 0 means the terms open
+// now.
+func (as *AttendanceService) BelowThreshold(schoolID, termID uint, threshold float64) ([]AttendanceRate, error) {
+	if schoolID == 0 || threshold < 0 || threshold > 1 {
+		return nil, ErrInvalidInput
+	}
+	if threshold == 0 {
+		threshold = DefaultAttendanceThreshold
+	}
+
+	s, err := as.schoolRepo.GetByID(schoolID)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, ErrNotFound
+	}
+
+	classIDs, err := as.classRepo.ListIDsBySchoolID(schoolID)
+	if err != nil {
+		return nil, err
+	}
+	if termID != 0 {
+		classIDs, err = as.classRepo.FilterIDsByTermID(classIDs, termID)
+	} else {
+		classIDs, err = as.classRepo.FilterIDsByOpenTerm(classIDs, as.now())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := as.attendanceRepo.SummarizeByClassIDs(classIDs)
+	if err != nil {
+		return nil, err
+	}
+	low := []AttendanceRate{}
+	for _, s := range summaries {
+		ar := attendanceRate(s)
+		if ar.Rate != nil && *ar.Rate < threshold {
+			low = append(low, ar)
+		}
+	}
+	return low, nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"OldSchool/internal/repository/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	DefaultSessionTTL = 12 * time.Hour
+	MinPasswordLength = 8
+)
+
+type SessionRepo interface {
+	Create(personID uint, tokenHash string, expiresAt time.Time) (*models.Session, error)
+	GetByTokenHash(tokenHash string) (*models.Session, error)
+	Revoke(sessionID uint, at time.Time) error
+	RevokeByPersonID(personID uint, at time.Time) (int64, error)
+}
+
+type PersonRepoForAuth interface {
+	Create(name string, roles ...string) (*models.Person, error)
+	GetByID(id uint) (*models.Person, error)
+	SetPasswordHash(personID uint, hash string) error
+	CountWithPassword() (int64, error)
+}
+
+type AuthService struct {
+	personRepo  PersonRepoForAuth
+	sessionRepo SessionRepo
+	ttl         time.Duration
+	now         func() time.Time
+}
+
+func NewAuthService(personRepo PersonRepoForAuth, sessionRepo SessionRepo, ttl time.Duration) *AuthService {
+	return &AuthService{
+		personRepo:  personRepo,
+		sessionRepo: sessionRepo,
+		ttl:         ttl,
+		now:         time.Now,
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", ErrInvalidInput
+	}
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(h), nil
+}
+
+// Login checks the person's password and opens a session. The returned token
+// is only ever handed out here; the database keeps its hash.
+func (as *AuthService) Login(personID uint, password string) (string, *models.Session, error) {
+	if personID == 0 || password == "" {
+		return "", nil, ErrInvalidInput
+	}
+
+	p, err := as.personRepo.GetByID(personID)
+	if err != nil {
+		return "", nil, err
+	}
+	if p == nil || p.PasswordHash == "" {
+		return "", nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(p.PasswordHash), []byte(password)); err != nil {
+		return "", nil, ErrInvalidCredentials
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	s, err := as.sessionRepo.Create(p.ID, hashToken(token), as.now().Add(as.ttl))
+	if err != nil {
+		return "", nil, err
+	}
+	return token, s, nil
+}
+
+// Authenticate returns the person behind a live session token.
+func (as *AuthService) Authenticate(token string) (*models.Person, *models.Session, error) {
+	if token == "" {
+		return nil, nil, ErrUnauthenticated
+	}
+
+	s, err := as.sessionRepo.GetByTokenHash(hashToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	if s == nil || s.RevokedAt != nil || !as.now().Before(s.ExpiresAt) {
+		return nil, nil, ErrUnauthenticated
+	}
+
+	p, err := as.personRepo.GetByID(s.PersonID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p == nil {
+		return nil, nil, ErrUnauthenticated
+	}
+	return p, s, nil
+}
+
+func (as *AuthService) Logout(token string) error {
+	_, s, err := as.Authenticate(token)
+	if err != nil {
+		return err
+	}
+	return as.sessionRepo.Revoke(s.ID, as.now())
+}
+
+// RevokeSessions ends every open session of a person and returns how many
+// were revoked.
+func (as *AuthService) RevokeSessions(personID uint) (int64, error) {
+	if personID == 0 {
+		return 0, ErrInvalidInput
+	}
+	p, err := as.personRepo.GetByID(personID)
+	if err != nil {
+		return 0, err
+	}
+	if p == nil {
+		return 0, ErrNotFound
+	}
+	return as.sessionRepo.RevokeByPersonID(personID, as.now())
+}
+
+func (as *AuthService) SetPassword(personID uint, password string) error {
+	if personID == 0 {
+		return ErrInvalidInput
+	}
+	p, err := as.personRepo.GetByID(personID)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return ErrNotFound
+	}
+
+	h, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return as.personRepo.SetPasswordHash(personID, h)
+}
+
+// ChangePassword lets a person replace their own password. Existing sessions
+// are revoked so a stolen token stops working.
+func (as *AuthService) ChangePassword(personID uint, oldPassword, newPassword string) error {
+	p, err := as.personRepo.GetByID(personID)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return ErrNotFound
+	}
+	if p.PasswordHash != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(p.PasswordHash), []byte(oldPassword)); err != nil {
+			return ErrInvalidCredentials
+		}
+	}
+
+	if err := as.SetPassword(personID, newPassword); err != nil {
+		return err
+	}
+	_, err = as.sessionRepo.RevokeByPersonID(personID, as.now())
+	return err
+}
+
+// Bootstrap creates the first admin account when nobody can log in yet. It
+// returns nil when credentials already exist.
+func (as *AuthService) Bootstrap(name, password string) (*models.Person, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrInvalidInput
+	}
+
+	n, err := as.personRepo.CountWithPassword()
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		return nil, nil
+	}
+
+	h, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := as.personRepo.Create(name, models.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if err := as.personRepo.SetPasswordHash(p.ID, h); err != nil {
+		return nil, err
+	}
+	p.PasswordHash = h
+	return p, nil
+}
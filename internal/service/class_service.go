@@ -3,14 +3,13 @@ package service
 import (
 	"OldSchool/internal/repository"
 	"OldSchool/internal/repository/models"
-	"errors"
+	"slices"
 	"strings"
-
-	"gorm.io/gorm"
+	"time"
 )
 
 type ClassRepo interface {
-	Create(name string, schoolID uint, teacherID uint) (*models.Class, error)
+	Create(name string, schoolID uint, teacherID uint, termID uint) (*models.Class, error)
 	GetByID(id uint) (*models.Class, error)
 	ListIDsByTeacherID(teacherID uint) ([]uint, error)
 	UpdateTeacher(classID, teacherID uint) error
@@ -18,7 +17,14 @@ type ClassRepo interface {
 type EnrollmentRepo interface {
 	Exists(classID uint, studentID uint) (bool, error)
 	Add(classID, studentID uint) (*models.Enrollment, error)
+	ListClassIDsByStudentID(studentID uint) ([]uint, error)
 	ListStudentsByClassID(classID uint) ([]models.Person, error)
+	PageStudentsByClassID(classID uint, opts repository.ListOptions) (repository.Page[models.Person], error)
+}
+
+type WaitlistRepo interface {
+	Position(classID, studentID uint) (int, error)
+	ListByClassID(classID uint) ([]models.Waitlist, error)
 }
 
 type UnitOfWork interface {
@@ -30,18 +36,30 @@ type ClassService struct {
 	personRepo     PersonRepo
 	uow            UnitOfWork
 	enrollmentRepo EnrollmentRepo
+	waitlistRepo   WaitlistRepo
+	now            func() time.Time
 }
 
-func NewClassService(classRepo ClassRepo, personRepo PersonRepo, uow UnitOfWork, enrollmentRepo EnrollmentRepo) *ClassService {
+func NewClassService(classRepo ClassRepo, personRepo PersonRepo, uow UnitOfWork, enrollmentRepo EnrollmentRepo, waitlistRepo WaitlistRepo) *ClassService {
 	return &ClassService{
 		uow:            uow,
 		classRepo:      classRepo,
 		personRepo:     personRepo,
 		enrollmentRepo: enrollmentRepo,
+		waitlistRepo:   waitlistRepo,
+		now:            time.Now,
 	}
 }
 
+// Create adds a class to the school's current term.
 func (cs *ClassService) Create(name string, schoolID uint, teacherID uint) (*models.Class, error) {
+	return cs.CreateInTerm(name, schoolID, teacherID, 0, 0)
+}
+
+// CreateInTerm adds a class to the given term of the school, or to its
+// current term when termID is 0. A capacity other than 0 limits the class
+// from the start, see SetCapacity.
+func (cs *ClassService) CreateInTerm(name string, schoolID uint, teacherID uint, termID uint, capacity uint) (*models.Class, error) {
 	name = strings.TrimSpace(name)
 	if name == "" || schoolID == 0 || teacherID == 0 {
 		return nil, ErrInvalidInput
@@ -56,11 +74,38 @@ func (cs *ClassService) Create(name string, schoolID uint, teacherID uint) (*mod
 		return nil, ErrNotFound
 	}
 
-	if teacher.Role != "teacher" {
+	if !teacher.HasRole(models.RoleTeacher) {
 		return nil, ErrRoleMismatch
 	}
 
-	return cs.classRepo.Create(name, schoolID, teacherID)
+	var created *models.Class
+	err = cs.uow.WithinTx(func(r repository.Repos) error {
+		s, err := r.School.GetByID(schoolID)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return ErrNotFound
+		}
+
+		t, err := resolveTerm(r, schoolID, termID, cs.now())
+		if err != nil {
+			return err
+		}
+		created, err = r.Class.Create(name, schoolID, teacherID, t.ID)
+		if err != nil || capacity == 0 {
+			return err
+		}
+		if err := r.Class.SetCapacity(created.ID, &capacity); err != nil {
+			return err
+		}
+		created.Capacity = &capacity
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
 
 }
 
@@ -69,89 +114,131 @@ func (cs *ClassService) UpdateTeacher(classID uint, teacherID uint) error {
 		return ErrInvalidInput
 	}
 
-	cl, err := cs.classRepo.GetByID(classID)
-	if err != nil {
-		return err
-	}
-	if cl == nil {
-		return ErrNotFound
+	_, err := cs.Update(classID, "", teacherID, nil)
+	return err
+}
+
+func (cs *ClassService) ListStudents(classID uint) ([]models.Person, error) {
+	if classID == 0 {
+		return nil, ErrInvalidInput
 	}
 
-	p, err := cs.personRepo.GetByID(teacherID)
+	class, err := cs.classRepo.GetByID(classID)
 	if err != nil {
-		return err
-	}
-	if p == nil {
-		return ErrNotFound
+		return nil, err
 	}
-	if p.Role != "teacher" {
-		return ErrRoleMismatch
+	if class == nil {
+		return nil, ErrNotFound
 	}
 
-	if cl.TeacherID == teacherID {
-		return nil
+	return cs.enrollmentRepo.ListStudentsByClassID(classID)
+
+}
+
+// ListStudentsPage is ListStudents a page at a time.
+func (cs *ClassService) ListStudentsPage(classID uint, opts repository.ListOptions) (repository.Page[models.Person], error) {
+	if classID == 0 {
+		return repository.Page[models.Person]{}, ErrInvalidInput
 	}
 
-	if err := cs.classRepo.UpdateTeacher(classID, teacherID); err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrNotFound
-		}
-		return err
+	class, err := cs.classRepo.GetByID(classID)
+	if err != nil {
+		return repository.Page[models.Person]{}, err
 	}
-	return nil
+	if class == nil {
+		return repository.Page[models.Person]{}, ErrNotFound
+	}
+
+	page, err := cs.enrollmentRepo.PageStudentsByClassID(classID, opts)
+	return page, listError(err)
 }
 
-func (cs *ClassService) ListStudents(classID uint) ([]models.Person, error) {
+// IsTeacherOf reports whether teacherID teaches the class. A missing class is
+// ErrNotFound.
+func (cs *ClassService) IsTeacherOf(teacherID uint, classID uint) (bool, error) {
 	if classID == 0 {
-		return nil, ErrInvalidInput
+		return false, ErrInvalidInput
 	}
 
 	class, err := cs.classRepo.GetByID(classID)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
 	if class == nil {
-		return nil, ErrNotFound
+		return false, ErrNotFound
 	}
 
-	return cs.enrollmentRepo.ListStudentsByClassID(classID)
+	return class.TeacherID == teacherID, nil
+}
+
+// TeachesStudent reports whether the student is enrolled in any class the
+// teacher teaches.
+func (cs *ClassService) TeachesStudent(teacherID uint, studentID uint) (bool, error) {
+	if teacherID == 0 || studentID == 0 {
+		return false, ErrInvalidInput
+	}
+	taught, err := cs.classRepo.ListIDsByTeacherID(teacherID)
+	if err != nil || len(taught) == 0 {
+		return false, err
+	}
+	enrolled, err := cs.enrollmentRepo.ListClassIDsByStudentID(studentID)
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(enrolled, func(id uint) bool { return slices.Contains(taught, id) }), nil
+}
 
+// IsEnrolledIn reports whether the student has a seat in the class.
+func (cs *ClassService) IsEnrolledIn(studentID uint, classID uint) (bool, error) {
+	if classID == 0 {
+		return false, ErrInvalidInput
+	}
+	return cs.enrollmentRepo.Exists(classID, studentID)
 }
 
 func (cs *ClassService) AddStudentToClass(studentID uint, classID uint) error {
+	_, err := cs.Enroll(studentID, classID)
+	return err
+}
+
+// Enroll adds the student to the class, or to its waitlist when the class is
+// full. It returns the student's waitlist position, which is 0 when they got
+// a seat.
+func (cs *ClassService) Enroll(studentID uint, classID uint) (int, error) {
 	if studentID == 0 || classID == 0 {
-		return ErrInvalidInput
+		return 0, ErrInvalidInput
 	}
 
 	student, err := cs.personRepo.GetByID(studentID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if student == nil {
-		return ErrNotFound
+		return 0, ErrNotFound
 	}
-	if student.Role == "teacher" {
-		return ErrRoleMismatch
+	if !student.HasRole(models.RoleStudent) {
+		return 0, ErrRoleMismatch
 	}
 
 	class, err := cs.classRepo.GetByID(classID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if class == nil {
-		return ErrNotFound
+		return 0, ErrNotFound
 	}
 
 	exists, err := cs.enrollmentRepo.Exists(classID, studentID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if exists {
-		return ErrDuplicateEnrollment
+		return 0, ErrDuplicateEnrollment
 	}
 
-	return cs.uow.WithinTx(func(r repository.Repos) error {
+	var position int
+	err = cs.uow.WithinTx(func(r repository.Repos) error {
 		st, err := r.Person.GetByID(studentID)
 		if err != nil {
 			return err
@@ -159,7 +246,7 @@ func (cs *ClassService) AddStudentToClass(studentID uint, classID uint) error {
 		if st == nil {
 			return ErrNotFound
 		}
-		if st.Role == "teacher" {
+		if !st.HasRole(models.RoleStudent) {
 			return ErrRoleMismatch
 		}
 
@@ -171,23 +258,195 @@ func (cs *ClassService) AddStudentToClass(studentID uint, classID uint) error {
 			return ErrNotFound
 		}
 
-		exists, err := r.Enrollment.Exists(classID, studentID)
+		position, err = enrollOrWaitlist(r, st, cl, cs.now())
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return position, nil
+}
+
+// Update renames a class, hands it to teacherID and changes its capacity as
+// SetCapacity does. Empty fields and a nil capacity are not changed.
+func (cs *ClassService) Update(classID uint, name string, teacherID uint, capacity *uint) (*models.Class, error) {
+	name = strings.TrimSpace(name)
+	if classID == 0 || (name == "" && teacherID == 0 && capacity == nil) {
+		return nil, ErrInvalidInput
+	}
+
+	var updated *models.Class
+	err := cs.uow.WithinTx(func(r repository.Repos) error {
+		cl, err := r.Class.GetByID(classID)
+		if err != nil {
+			return err
+		}
+		if cl == nil {
+			return ErrNotFound
+		}
+
+		if teacherID != 0 {
+			t, err := r.Person.GetByID(teacherID)
+			if err != nil {
+				return err
+			}
+			if t == nil {
+				return ErrNotFound
+			}
+			if !t.HasRole(models.RoleTeacher) {
+				return ErrRoleMismatch
+			}
+			if err := checkPersonFree(r, teacherID, cl, ErrTeacherConflict); err != nil {
+				return err
+			}
+			if err := r.Class.UpdateTeacher(classID, teacherID); err != nil {
+				return err
+			}
+		}
+		if name != "" {
+			if err := r.Class.UpdateName(classID, name); err != nil {
+				return err
+			}
+		}
+		if capacity != nil {
+			if err := setCapacity(r, classID, *capacity, cs.now()); err != nil {
+				return err
+			}
+		}
+
+		updated, err = r.Class.GetByID(classID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Delete removes a class. A class with enrolled students is refused unless
+// cascade is set, in which case the enrollments are dropped with it.
+func (cs *ClassService) Delete(classID uint, cascade bool) error {
+	if classID == 0 {
+		return ErrInvalidInput
+	}
+
+	return cs.uow.WithinTx(func(r repository.Repos) error {
+		cl, err := r.Class.GetByID(classID)
 		if err != nil {
 			return err
 		}
-		if exists {
-			return ErrDuplicateEnrollment
+		if cl == nil {
+			return ErrNotFound
+		}
+
+		n, err := r.Enrollment.CountByClassID(classID)
+		if err != nil {
+			return err
+		}
+		if n > 0 && !cascade {
+			return ErrClassHasStudents
 		}
 
-		if st.StudentSchoolID == nil {
-			if err := r.Person.UpdateStudentSchoolID(studentID, cl.SchoolID); err != nil {
+		students, err := r.Enrollment.ListStudentsByClassID(classID)
+		if err != nil {
+			return err
+		}
+		if err := purgeClass(r, classID); err != nil {
+			return err
+		}
+		for _, st := range students {
+			if err := releaseSchoolIfUnenrolled(r, st.ID); err != nil {
 				return err
 			}
-		} else if *st.StudentSchoolID != cl.SchoolID {
-			return ErrDifferentSchool
 		}
-		_, err = r.Enrollment.Add(classID, studentID)
+		return nil
+	})
+}
+
+// purgeClass deletes a class together with everything hanging off it.
+func purgeClass(r repository.Repos, classID uint) error {
+	if err := r.Enrollment.DeleteByClassID(classID); err != nil {
+		return err
+	}
+	if err := r.Waitlist.DeleteByClassID(classID); err != nil {
+		return err
+	}
+	if err := r.Slot.DeleteByClassID(classID); err != nil {
+		return err
+	}
+	if err := r.Score.DeleteByClassID(classID); err != nil {
+		return err
+	}
+	if err := r.Assessment.DeleteByClassID(classID); err != nil {
 		return err
+	}
+	if err := r.Attendance.DeleteByClassID(classID); err != nil {
+		return err
+	}
+	if err := r.Submission.DeleteByClassID(classID); err != nil {
+		return err
+	}
+	if err := r.Assignment.DeleteByClassID(classID); err != nil {
+		return err
+	}
+	if err := r.Comment.DeleteByClassID(classID); err != nil {
+		return err
+	}
+	return r.Class.Delete(classID)
+}
+
+// RemoveStudentFromClass undoes AddStudentToClass. When it was the student's
+// last class they are no longer bound to its school. The freed seat goes to
+// the first student on the waitlist.
+func (cs *ClassService) RemoveStudentFromClass(studentID uint, classID uint) error {
+	if studentID == 0 || classID == 0 {
+		return ErrInvalidInput
+	}
+
+	return cs.uow.WithinTx(func(r repository.Repos) error {
+		st, err := r.Person.GetByID(studentID)
+		if err != nil {
+			return err
+		}
+		if st == nil {
+			return ErrNotFound
+		}
+
+		cl, err := r.Class.GetByID(classID)
+		if err != nil {
+			return err
+		}
+		if cl == nil {
+			return ErrNotFound
+		}
+
+		exists, err := r.Enrollment.Exists(classID, studentID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNotEnrolled
+		}
 
+		if err := r.Enrollment.Delete(classID, studentID); err != nil {
+			return err
+		}
+		if err := releaseSchoolIfUnenrolled(r, studentID); err != nil {
+			return err
+		}
+		return promoteWaitlist(r, classID, cs.now())
 	})
 }
+
+// releaseSchoolIfUnenrolled clears the student's school once they attend no
+// class at all, so their next enrollment may be at any school.
+func releaseSchoolIfUnenrolled(r repository.Repos, studentID uint) error {
+	remaining, err := r.Enrollment.ListClassIDsByStudentID(studentID)
+	if err != nil {
+		return err
+	}
+	if len(remaining) > 0 {
+		return nil
+	}
+	return r.Person.ResetStudentSchoolID(studentID)
+}
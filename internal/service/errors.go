@@ -4,10 +4,31 @@ import "errors"
 
 
 var (
-	ErrInvalidInput        = errors.New("invalid input")
-	ErrNotFound            = errors.New("not found")
-	ErrRoleMismatch        = errors.New("role mismatch")
-	ErrDuplicateEnrollment = errors.New("student already enrolled in this class")
-	ErrDifferentSchool     = errors.New("student cannot enroll in multiple schools")
-	ErrSchoolAlreadyExists = errors.New("school with this name already exists")
+	ErrInvalidInput            = errors.New("invalid input")
+	ErrNotFound                = errors.New("not found")
+	ErrRoleMismatch            = errors.New("role mismatch")
+	ErrDuplicateEnrollment     = errors.New("student already enrolled in this class")
+	ErrDifferentSchool         = errors.New("student cannot enroll in multiple schools")
+	ErrSchoolAlreadyExists     = errors.New("school with this name already exists")
+	ErrUnauthenticated         = errors.New("authentication required")
+	ErrInvalidCredentials      = errors.New("invalid credentials")
+	ErrPermissionDenied        = errors.New("permission denied")
+	ErrSchoolHasClasses        = errors.New("school still has classes")
+	ErrClassHasStudents        = errors.New("class still has students")
+	ErrPersonHasClasses        = errors.New("person still teaches or attends classes")
+	ErrNotEnrolled             = errors.New("student is not enrolled in this class")
+	ErrAlreadyWaitlisted       = errors.New("student already on the waitlist for this class")
+	ErrNotWaitlisted           = errors.New("student is not on the waitlist for this class")
+	ErrTermClosed              = errors.New("term is not open for enrollment")
+	ErrNoCurrentTerm           = errors.New("school has no current term")
+	ErrTermAlreadyExists       = errors.New("term with this name already exists")
+	ErrRoomAlreadyExists       = errors.New("room with this name already exists")
+	ErrRoomConflict            = errors.New("room is already booked at this time")
+	ErrTeacherConflict         = errors.New("teacher is already busy at this time")
+	ErrStudentConflict         = errors.New("student is already busy at this time")
+	ErrAssessmentAlreadyExists = errors.New("assessment with this name already exists")
+	ErrAlreadySubmitted        = errors.New("assignment already submitted, resubmit to replace it")
+	ErrNotSubmitted            = errors.New("assignment has not been submitted yet")
+	ErrImportFailed            = errors.New("import has row errors, nothing was imported")
+	ErrExportTooLarge          = errors.New("export is too large to send, use the export command")
 )
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"OldSchool/internal/repository/models"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Formats an export can be written in.
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatXLSX   = "xlsx"
+)
+
+// DefaultExportFormat is the format used when none is asked for. Unlike csv
+// it holds every table.
+const DefaultExportFormat = ExportFormatNDJSON
+
+// Tables an export can hold. They are always written in this order.
+const (
+	ExportSchools     = "schools"
+	ExportClasses     = "classes"
+	ExportPeople      = "people"
+	ExportEnrollments = "enrollments"
+)
+
+var ExportTables = []string{ExportSchools, ExportClasses, ExportPeople, ExportEnrollments}
+
+type ExportRepo interface {
+	EachSchool(schoolID uint, fn func(*models.School) error) error
+	EachClass(schoolID, termID uint, fn func(*models.Class) error) error
+	EachPerson(schoolID uint, role string, fn func(*models.Person) error) error
+	EachEnrollment(schoolID, termID uint, fn func(*models.Enrollment) error) error
+}
+
+// ExportOptions picks what goes into an export. No Tables means all of them.
+// Columns are either a plain column name, kept in every table that has it, or
+// table.column; a table none of them apply to keeps all its columns. SchoolID
+// limits the export to one school and its people, TermID limits classes and
+// enrollments to one term, and Role limits people to those holding it.
+// MaxBytes, when not 0, fails the export with ErrExportTooLarge once it
+// would write more than that.
+type ExportOptions struct {
+	Tables   []string
+	Columns  []string
+	SchoolID uint
+	TermID   uint
+	Role     string
+	MaxBytes int
+}
+
+type ExportService struct {
+	exportRepo ExportRepo
+	schoolRepo SchoolRepo
+}
+
+func NewExportService(exportRepo ExportRepo, schoolRepo SchoolRepo) *ExportService {
+	return &ExportService{
+		exportRepo: exportRepo,
+		schoolRepo: schoolRepo,
+	}
+}
+
+// exportTable is the columns of one table and how to read its rows, each row
+// holding a value per column.
+type exportTable struct {
+	columns []string
+	each    func(repo ExportRepo, opts ExportOptions, emit func(row []any) error) error
+}
+
+var exportTables = map[string]exportTable{
+	ExportSchools: {
+		columns: []string{"id", "name", "created_at"},
+		each: func(repo ExportRepo, opts ExportOptions, emit func([]any) error) error {
+			return repo.EachSchool(opts.SchoolID, func(s *models.School) error {
+				return emit([]any{s.ID, s.Name, exportTime(s.CreatedAt)})
+			})
+		},
+	},
+	ExportClasses: {
+		columns: []string{"id", "name", "school_id", "term_id", "teacher_id", "teacher_name", "capacity", "created_at"},
+		each: func(repo ExportRepo, opts ExportOptions, emit func([]any) error) error {
+			return repo.EachClass(opts.SchoolID, opts.TermID, func(c *models.Class) error {
+				var capacity any
+				if c.Capacity != nil {
+					capacity = *c.Capacity
+				}
+				return emit([]any{c.ID, c.Name, c.SchoolID, c.TermID, c.TeacherID, c.Teacher.Name, capacity, exportTime(c.CreatedAt)})
+			})
+		},
+	},
+	ExportPeople: {
+		columns: []string{"id", "name", "roles", "school_id", "created_at"},
+		each: func(repo ExportRepo, opts ExportOptions, emit func([]any) error) error {
+			return repo.EachPerson(opts.SchoolID, opts.Role, func(p *models.Person) error {
+				roles := p.Roles.Names()
+				slices.Sort(roles)
+				var schoolID any
+				if p.StudentSchoolID != nil {
+					schoolID = *p.StudentSchoolID
+				}
+				return emit([]any{p.ID, p.Name, strings.Join(roles, ";"), schoolID, exportTime(p.CreatedAt)})
+			})
+		},
+	},
+	ExportEnrollments: {
+		columns: []string{"class_id", "class_name", "student_id", "student_name", "school_id", "term_id", "created_at"},
+		each: func(repo ExportRepo, opts ExportOptions, emit func([]any) error) error {
+			return repo.EachEnrollment(opts.SchoolID, opts.TermID, func(e *models.Enrollment) error {
+				return emit([]any{e.ClassID, e.Class.Name, e.StudentID, e.Student.Name, e.Class.SchoolID, e.Class.TermID, exportTime(e.CreatedAt)})
+			})
+		},
+	},
+}
+
+// exportTime writes times in UTC to the second, so the same data always
+// exports the same.
+func exportTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Export writes the chosen tables to w as it reads them, ordered by id. The
+// csv format holds a single table; ndjson tags every line with its table and
+// xlsx puts each table on its own sheet.
+func (es *ExportService) Export(w io.Writer, format string, opts ExportOptions) error {
+	tables, err := exportTableNames(format, opts.Tables)
+	if err != nil {
+		return err
+	}
+	selected, err := exportColumns(tables, opts.Columns)
+	if err != nil {
+		return err
+	}
+	if opts.Role != "" && !slices.Contains(models.ValidRoles, opts.Role) {
+		return ErrInvalidInput
+	}
+	if opts.SchoolID != 0 {
+		s, err := es.schoolRepo.GetByID(opts.SchoolID)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return ErrNotFound
+		}
+	}
+
+	if opts.MaxBytes > 0 {
+		w = &limitWriter{w: w, n: opts.MaxBytes}
+	}
+	ew := newExportWriter(w, format)
+	for _, name := range tables {
+		table, idx := exportTables[name], selected[name]
+		columns := make([]string, len(idx))
+		for i, c := range idx {
+			columns[i] = table.columns[c]
+		}
+		if err := ew.table(name, columns); err != nil {
+			return err
+		}
+
+		values := make([]any, len(idx))
+		err := table.each(es.exportRepo, opts, func(row []any) error {
+			for i, c := range idx {
+				values[i] = row[c]
+			}
+			return ew.row(values)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return ew.close()
+}
+
+// exportTableNames checks the format and tables and puts the tables in
+// export order.
+func exportTableNames(format string, names []string) ([]string, error) {
+	switch format {
+	case ExportFormatCSV, ExportFormatNDJSON, ExportFormatXLSX:
+	default:
+		return nil, ErrInvalidInput
+	}
+	if len(names) == 0 {
+		names = ExportTables
+	}
+
+	tables := []string{}
+	for _, name := range ExportTables {
+		if slices.Contains(names, name) {
+			tables = append(tables, name)
+		}
+	}
+	for _, name := range names {
+		if !slices.Contains(ExportTables, name) {
+			return nil, ErrInvalidInput
+		}
+	}
+	if format == ExportFormatCSV && len(tables) != 1 {
+		return nil, ErrInvalidInput
+	}
+	return tables, nil
+}
+
+// exportColumns resolves the column selection into the indexes of each
+// table's columns to write, see ExportOptions.
+func exportColumns(tables []string, columns []string) (map[string][]int, error) {
+	selected := map[string][]int{}
+	for _, col := range columns {
+		tableName, name, qualified := strings.Cut(strings.TrimSpace(col), ".")
+		if !qualified {
+			tableName, name = "", tableName
+		}
+
+		found := false
+		for _, t := range tables {
+			if qualified && t != tableName {
+				continue
+			}
+			i := slices.Index(exportTables[t].columns, name)
+			if i < 0 {
+				continue
+			}
+			found = true
+			if !slices.Contains(selected[t], i) {
+				selected[t] = append(selected[t], i)
+			}
+		}
+		if !found {
+			return nil, ErrInvalidInput
+		}
+	}
+
+	for _, t := range tables {
+		if len(selected[t]) == 0 {
+			for i := range exportTables[t].columns {
+				selected[t] = append(selected[t], i)
+			}
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// exportWriter writes the tables of an export one after another.
+type exportWriter interface {
+	table(name string, columns []string) error
+	row(values []any) error
+	close() error
+}
+
+func newExportWriter(w io.Writer, format string) exportWriter {
+	switch format {
+	case ExportFormatNDJSON:
+		return &ndjsonWriter{w: bufio.NewWriter(w)}
+	case ExportFormatXLSX:
+		return &xlsxWriter{zw: zip.NewWriter(w)}
+	}
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+// limitWriter fails with ErrExportTooLarge instead of writing past n bytes.
+type limitWriter struct {
+	w io.Writer
+	n int
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if len(p) > lw.n {
+		return 0, ErrExportTooLarge
+	}
+	lw.n -= len(p)
+	return lw.w.Write(p)
+}
+
+// cellText is how a value reads in a text cell. Missing values are empty.
+func cellText(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (cw *csvWriter) table(_ string, columns []string) error {
+	return cw.w.Write(columns)
+}
+
+// row quotes text cells that a spreadsheet would read as a formula with a
+// leading ', so a name like =HYPERLINK(...) stays text when the file is
+// opened.
+func (cw *csvWriter) row(values []any) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = cellText(v)
+		if _, text := v.(string); text && record[i] != "" && strings.ContainsRune("=+-@\t\r", rune(record[i][0])) {
+			record[i] = "'" + record[i]
+		}
+	}
+	return cw.w.Write(record)
+}
+
+func (cw *csvWriter) close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// ndjsonWriter writes one JSON object per row, its table first and then its
+// columns in order.
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	name    []byte
+	columns [][]byte
+}
+
+func (nw *ndjsonWriter) table(name string, columns []string) error {
+	nw.name, _ = json.Marshal(name)
+	nw.columns = make([][]byte, len(columns))
+	for i, col := range columns {
+		nw.columns[i], _ = json.Marshal(col)
+	}
+	return nil
+}
+
+func (nw *ndjsonWriter) row(values []any) error {
+	nw.w.WriteString(`{"table":`)
+	nw.w.Write(nw.name)
+	for i, v := range values {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		nw.w.WriteByte(',')
+		nw.w.Write(nw.columns[i])
+		nw.w.WriteByte(':')
+		nw.w.Write(b)
+	}
+	_, err := nw.w.WriteString("}\n")
+	return err
+}
+
+func (nw *ndjsonWriter) close() error {
+	return nw.w.Flush()
+}
+
+// xlsxWriter writes an Office Open XML workbook with a sheet per table.
+// Every part carries the same timestamp so the same data gives the same
+// bytes.
+type xlsxWriter struct {
+	zw     *zip.Writer
+	sheet  io.Writer
+	sheets []string
+}
+
+var xlsxModified = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+const xlsxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+func (xw *xlsxWriter) part(name string) (io.Writer, error) {
+	return xw.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: xlsxModified})
+}
+
+func (xw *xlsxWriter) table(name string, columns []string) error {
+	if err := xw.endSheet(); err != nil {
+		return err
+	}
+	xw.sheets = append(xw.sheets, name)
+
+	var err error
+	if xw.sheet, err = xw.part(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(xw.sheets))); err != nil {
+		return err
+	}
+	_, err = io.WriteString(xw.sheet, xlsxHeader+
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return err
+	}
+
+	header := make([]any, len(columns))
+	for i, col := range columns {
+		header[i] = col
+	}
+	return xw.row(header)
+}
+
+func (xw *xlsxWriter) row(values []any) error {
+	if _, err := io.WriteString(xw.sheet, "<row>"); err != nil {
+		return err
+	}
+	for _, v := range values {
+		var err error
+		switch v := v.(type) {
+		case nil:
+			_, err = io.WriteString(xw.sheet, "<c/>")
+		case uint, int, int64, float64:
+			_, err = fmt.Fprintf(xw.sheet, "<c><v>%v</v></c>", v)
+		default:
+			if _, err = io.WriteString(xw.sheet, `<c t="inlineStr"><is><t xml:space="preserve">`); err == nil {
+				if err = xml.EscapeText(xw.sheet, []byte(cellText(v))); err == nil {
+					_, err = io.WriteString(xw.sheet, "</t></is></c>")
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(xw.sheet, "</row>")
+	return err
+}
+
+func (xw *xlsxWriter) endSheet() error {
+	if xw.sheet == nil {
+		return nil
+	}
+	_, err := io.WriteString(xw.sheet, "</sheetData></worksheet>")
+	xw.sheet = nil
+	return err
+}
+
+// close writes the parts that list the sheets and finishes the zip.
+func (xw *xlsxWriter) close() error {
+	if err := xw.endSheet(); err != nil {
+		return err
+	}
+
+	contentTypes := xlsxHeader +
+		`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`
+	workbook := xlsxHeader +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`
+	workbookRels := xlsxHeader +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`
+	for i, name := range xw.sheets {
+		n := i + 1
+		contentTypes += fmt.Sprintf(`<Override PartName="/xl/worksheets/sheet%d.xml" `+
+			`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		workbook += fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, name, n, n)
+		workbookRels += fmt.Sprintf(`<Relationship Id="rId%d" `+
+			`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" `+
+			`Target="worksheets/sheet%d.xml"/>`, n, n)
+	}
+	rootRels := xlsxHeader +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	for _, p := range []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypes + `</Types>`},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", workbook + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", workbookRels + `</Relationships>`},
+	} {
+		w, err := xw.part(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, p.body); err != nil {
+			return err
+		}
+	}
+	return xw.zw.Close()
+}
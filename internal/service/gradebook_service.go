@@ -0,0 +1,239 @@
+package service
+
+import (
+	"OldSchool/internal/repository"
+	"OldSchool/internal/repository/models"
+	"strings"
+	"time"
+)
+
+type AssessmentRepo interface {
+	ListByClassIDs(classIDs []uint) ([]models.Assessment, error)
+}
+
+type ScoreRepo interface {
+	ListByStudentID(studentID uint, assessmentIDs []uint) ([]models.Score, error)
+}
+
+type ClassRepoForGradebook interface {
+	GetByID(id uint) (*models.Class, error)
+	FilterIDsByTermID(ids []uint, termID uint) ([]uint, error)
+	FilterIDsByOpenTerm(ids []uint, at time.Time) ([]uint, error)
+}
+
+type GradebookService struct {
+	assessmentRepo AssessmentRepo
+	scoreRepo      ScoreRepo
+	classRepo      ClassRepoForGradebook
+	enrollmentRepo EnrollmentRepoForWhoAmI
+	personRepo     PersonRepo
+	uow            UnitOfWork
+	now            func() time.Time
+}
+
+func NewGradebookService(assessmentRepo AssessmentRepo, scoreRepo ScoreRepo, classRepo ClassRepoForGradebook, enrollmentRepo EnrollmentRepoForWhoAmI, personRepo PersonRepo, uow UnitOfWork) *GradebookService {
+	return &GradebookService{
+		assessmentRepo: assessmentRepo,
+		scoreRepo:      scoreRepo,
+		classRepo:      classRepo,
+		enrollmentRepo: enrollmentRepo,
+		personRepo:     personRepo,
+		uow:            uow,
+		now:            time.Now,
+	}
+}
+
+// ScoreInput is one student's points in a bulk score update.
+type ScoreInput struct {
+	StudentID uint
+	Points    float64
+}
+
+// AssessmentGrade is a student's result on one assessment. Points is nil
+// until a score has been recorded.
+type AssessmentGrade struct {
+	AssessmentID uint     `json:"assessment_id"`
+	Name         string   `json:"name"`
+	Weight       float64  `json:"weight"`
+	MaxPoints    float64  `json:"max_points"`
+	Points       *float64 `json:"points"`
+}
+
+// ClassGrade is a student's standing in one class. FinalGrade is the weighted
+// average percentage over the assessments scored so far, nil before the
+// first score.
+type ClassGrade struct {
+	ClassID     uint              `json:"class_id"`
+	ClassName   string            `json:"class_name"`
+	Assessments []AssessmentGrade `json:"assessments"`
+	FinalGrade  *float64          `json:"final_grade"`
+}
+
+func (gs *GradebookService) CreateAssessment(classID uint, name string, weight, maxPoints float64) (*models.Assessment, error) {
+	name = strings.TrimSpace(name)
+	if classID == 0 || name == "" || weight <= 0 || maxPoints <= 0 {
+		return nil, ErrInvalidInput
+	}
+
+	var created *models.Assessment
+	err := gs.uow.WithinTx(func(r repository.Repos) error {
+		cl, err := r.Class.GetByID(classID)
+		if err != nil {
+			return err
+		}
+		if cl == nil {
+			return ErrNotFound
+		}
+		created, err = r.Assessment.Create(classID, name, weight, maxPoints)
+		if isUniqueConstraintErr(err) {
+			return ErrAssessmentAlreadyExists
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// RecordScores stores scores for an assessment on behalf of teacherID, who
+// must teach its class. Every student must be enrolled in the class and every
+// score must lie within the assessment's points; otherwise nothing is saved.
+func (gs *GradebookService) RecordScores(teacherID, assessmentID uint, scores []ScoreInput) error {
+	if teacherID == 0 || assessmentID == 0 || len(scores) == 0 {
+		return ErrInvalidInput
+	}
+
+	return gs.uow.WithinTx(func(r repository.Repos) error {
+		a, err := r.Assessment.GetByID(assessmentID)
+		if err != nil {
+			return err
+		}
+		if a == nil {
+			return ErrNotFound
+		}
+		cl, err := r.Class.GetByID(a.ClassID)
+		if err != nil {
+			return err
+		}
+		if cl == nil {
+			return ErrNotFound
+		}
+		if cl.TeacherID != teacherID {
+			return ErrPermissionDenied
+		}
+
+		for _, s := range scores {
+			if s.StudentID == 0 || s.Points < 0 || s.Points > a.MaxPoints {
+				return ErrInvalidInput
+			}
+			enrolled, err := r.Enrollment.Exists(a.ClassID, s.StudentID)
+			if err != nil {
+				return err
+			}
+			if !enrolled {
+				return ErrNotEnrolled
+			}
+			if err := r.Score.Record(assessmentID, s.StudentID, s.Points); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// StudentGrades returns the student's grades in every class they are enrolled
+// in during the given term. A termID of 0 means the terms open now.
+func (gs *GradebookService) StudentGrades(studentID uint, termID uint) ([]ClassGrade, error) {
+	if studentID == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	p, err := gs.personRepo.GetByID(studentID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrNotFound
+	}
+
+	classIDs, err := gs.enrollmentRepo.ListClassIDsByStudentID(studentID)
+	if err != nil {
+		return nil, err
+	}
+	if termID != 0 {
+		classIDs, err = gs.classRepo.FilterIDsByTermID(classIDs, termID)
+	} else {
+		classIDs, err = gs.classRepo.FilterIDsByOpenTerm(classIDs, gs.now())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return studentClassGrades(gs.assessmentRepo, gs.scoreRepo, gs.classRepo, studentID, classIDs)
+}
+
+// studentClassGrades works out the student's grade in each of the classes.
+func studentClassGrades(assessmentRepo AssessmentRepo, scoreRepo ScoreRepo, classRepo ClassRepoForGradebook, studentID uint, classIDs []uint) ([]ClassGrade, error) {
+	assessments, err := assessmentRepo.ListByClassIDs(classIDs)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(assessments))
+	for _, a := range assessments {
+		ids = append(ids, a.ID)
+	}
+	scores, err := scoreRepo.ListByStudentID(studentID, ids)
+	if err != nil {
+		return nil, err
+	}
+	points := make(map[uint]float64, len(scores))
+	for _, s := range scores {
+		points[s.AssessmentID] = s.Points
+	}
+
+	byClass := make(map[uint][]models.Assessment)
+	for _, a := range assessments {
+		byClass[a.ClassID] = append(byClass[a.ClassID], a)
+	}
+	grades := []ClassGrade{}
+	for _, classID := range classIDs {
+		cl, err := classRepo.GetByID(classID)
+		if err != nil {
+			return nil, err
+		}
+		if cl == nil {
+			continue
+		}
+		grades = append(grades, classGrade(cl, byClass[classID], points))
+	}
+	return grades, nil
+}
+
+func classGrade(cl *models.Class, assessments []models.Assessment, points map[uint]float64) ClassGrade {
+	g := ClassGrade{
+		ClassID:     cl.ID,
+		ClassName:   cl.Name,
+		Assessments: []AssessmentGrade{},
+	}
+
+	var weighted, weights float64
+	for _, a := range assessments {
+		ag := AssessmentGrade{
+			AssessmentID: a.ID,
+			Name:         a.Name,
+			Weight:       a.Weight,
+			MaxPoints:    a.MaxPoints,
+		}
+		if p, ok := points[a.ID]; ok {
+			ag.Points = &p
+			weighted += a.Weight * p / a.MaxPoints
+			weights += a.Weight
+		}
+		g.Assessments = append(g.Assessments, ag)
+	}
+	if weights > 0 {
+		final := 100 * weighted / weights
+		g.FinalGrade = &final
+	}
+	return g
+}
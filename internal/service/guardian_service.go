@@ -0,0 +1,185 @@
+package service
+
+import (
+	"OldSchool/internal/repository"
+	"OldSchool/internal/repository/models"
+	"slices"
+)
+
+type GuardianRepo interface {
+	Get(guardianID, studentID uint) (*models.Guardianship, error)
+	ListByGuardianID(guardianID uint) ([]models.Guardianship, error)
+	ListByStudentID(studentID uint) ([]models.Guardianship, error)
+}
+
+type GuardianService struct {
+	guardianRepo GuardianRepo
+	personRepo   PersonRepo
+	person       *PersonService
+	uow          UnitOfWork
+}
+
+func NewGuardianService(guardianRepo GuardianRepo, personRepo PersonRepo, person *PersonService, uow UnitOfWork) *GuardianService {
+	return &GuardianService{
+		guardianRepo: guardianRepo,
+		personRepo:   personRepo,
+		person:       person,
+		uow:          uow,
+	}
+}
+
+// GuardianLink is one side of a guardianship together with its details.
+// Person is the guardian when listing a student's guardians and the student
+// when listing a guardian's children.
+type GuardianLink struct {
+	Person           models.Person `json:"person"`
+	Relationship     string        `json:"relationship"`
+	PreferredContact string        `json:"preferred_contact"`
+	EmergencyContact bool          `json:"emergency_contact"`
+	ReceivesReports  bool          `json:"receives_reports"`
+}
+
+// Child is a student as their guardian sees them in /who/am/i.
+type Child struct {
+	GuardianLink
+	ClassIDs []uint `json:"class_ids"`
+}
+
+func guardianLink(g models.Guardianship, p models.Person) GuardianLink {
+	return GuardianLink{
+		Person:           p,
+		Relationship:     g.Relationship,
+		PreferredContact: g.PreferredContact,
+		EmergencyContact: g.EmergencyContact,
+		ReceivesReports:  g.ReceivesReports,
+	}
+}
+
+// Link records guardianID as a guardian of studentID, or updates the details
+// when they are linked already. The preferred contact defaults to email.
+func (gs *GuardianService) Link(guardianID, studentID uint, relationship, preferredContact string, emergency, reports bool) (*models.Guardianship, error) {
+	if preferredContact == "" {
+		preferredContact = models.ContactEmail
+	}
+	if guardianID == 0 || studentID == 0 || guardianID == studentID ||
+		!slices.Contains(models.ValidRelationships, relationship) ||
+		!slices.Contains(models.ValidContactMethods, preferredContact) {
+		return nil, ErrInvalidInput
+	}
+
+	var linked *models.Guardianship
+	err := gs.uow.WithinTx(func(r repository.Repos) error {
+		guardian, err := r.Person.GetByID(guardianID)
+		if err != nil {
+			return err
+		}
+		student, err := r.Person.GetByID(studentID)
+		if err != nil {
+			return err
+		}
+		if guardian == nil || student == nil {
+			return ErrNotFound
+		}
+		if !guardian.HasRole(models.RoleGuardian) || !student.HasRole(models.RoleStudent) {
+			return ErrRoleMismatch
+		}
+
+		err = r.Guardian.Link(&models.Guardianship{
+			GuardianID:       guardianID,
+			StudentID:        studentID,
+			Relationship:     relationship,
+			PreferredContact: preferredContact,
+			EmergencyContact: emergency,
+			ReceivesReports:  reports,
+		})
+		if err != nil {
+			return err
+		}
+		linked, err = r.Guardian.Get(guardianID, studentID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return linked, nil
+}
+
+func (gs *GuardianService) Unlink(guardianID, studentID uint) error {
+	if guardianID == 0 || studentID == 0 {
+		return ErrInvalidInput
+	}
+
+	return gs.uow.WithinTx(func(r repository.Repos) error {
+		g, err := r.Guardian.Get(guardianID, studentID)
+		if err != nil {
+			return err
+		}
+		if g == nil {
+			return ErrNotFound
+		}
+		return r.Guardian.Unlink(guardianID, studentID)
+	})
+}
+
+// IsGuardianOf reports whether guardianID is linked to the student.
+func (gs *GuardianService) IsGuardianOf(guardianID, studentID uint) (bool, error) {
+	g, err := gs.guardianRepo.Get(guardianID, studentID)
+	if err != nil {
+		return false, err
+	}
+	return g != nil, nil
+}
+
+func (gs *GuardianService) Guardians(studentID uint) ([]GuardianLink, error) {
+	if studentID == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	p, err := gs.personRepo.GetByID(studentID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrNotFound
+	}
+	guardianships, err := gs.guardianRepo.ListByStudentID(studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	links := []GuardianLink{}
+	for _, g := range guardianships {
+		links = append(links, guardianLink(g, g.Guardian))
+	}
+	return links, nil
+}
+
+// Children lists the guardian's students with the classes each attends in
+// the term, found the same way WhoAmIInTerm finds them.
+func (gs *GuardianService) Children(guardianID, termID uint) ([]Child, error) {
+	if guardianID == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	links, err := gs.guardianRepo.ListByGuardianID(guardianID)
+	if err != nil {
+		return nil, err
+	}
+
+	children := []Child{}
+	for _, g := range links {
+		_, memberships, err := gs.person.WhoAmIInTerm(g.StudentID, termID)
+		if err != nil {
+			return nil, err
+		}
+		classIDs := memberships[models.RoleStudent]
+		if classIDs == nil {
+			classIDs = []uint{}
+		}
+		children = append(children, Child{
+			GuardianLink: guardianLink(g, g.Student),
+			ClassIDs:     classIDs,
+		})
+	}
+	return children, nil
+}
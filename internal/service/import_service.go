@@ -0,0 +1,267 @@
+package service
+
+import (
+	"OldSchool/internal/repository"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Row types of an import.
+const (
+	ImportSchool     = "school"
+	ImportPerson     = "person"
+	ImportClass      = "class"
+	ImportEnrollment = "enrollment"
+)
+
+// ImportColumns are the columns an import may have, named by its header row
+// in any order. Only type is required; each row type reads the columns it
+// needs:
+//
+//	school:     name
+//	person:     name, roles (separated by ";")
+//	class:      name, school, teacher
+//	enrollment: class, student
+//
+// Any row may give a ref, which later rows use in the school, teacher, class
+// and student columns to point at the record it created. A number that is
+// not a ref is the id of an existing record.
+var ImportColumns = []string{"type", "ref", "name", "roles", "school", "teacher", "class", "student"}
+
+// RowError is what went wrong with one line of an import.
+type RowError struct {
+	Line    int    `json:"line"`
+	Type    string `json:"type,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportReport sums up an import. Created counts records by row type, and
+// Waitlisted counts the enrollments that ended up on a waitlist.
+type ImportReport struct {
+	DryRun     bool           `json:"dry_run"`
+	Rows       int            `json:"rows"`
+	Created    map[string]int `json:"created"`
+	Waitlisted int            `json:"waitlisted"`
+	Errors     []RowError     `json:"errors"`
+}
+
+// errImportRolledBack undoes a dry run or a failed import.
+var errImportRolledBack = errors.New("import rolled back")
+
+type ImportService struct {
+	uow UnitOfWork
+}
+
+func NewImportService(uow UnitOfWork) *ImportService {
+	return &ImportService{uow: uow}
+}
+
+// Import reads CSV rows, see ImportColumns, and creates what they describe
+// through the same service calls single requests use. Every row is tried so
+// the report lists all row errors. The import is committed only when no row
+// failed and dryRun is not set; a real run with row errors is rolled back and
+// returns its report with ErrImportFailed.
+func (is *ImportService) Import(in io.Reader, dryRun bool) (*ImportReport, error) {
+	report := &ImportReport{DryRun: dryRun, Created: map[string]int{}, Errors: []RowError{}}
+	err := is.uow.WithinTx(func(r repository.Repos) error {
+		imp := &importer{s: NewServices(r), report: report, refs: map[string]map[string]uint{}}
+		if err := imp.run(in); err != nil {
+			return err
+		}
+		if dryRun || len(report.Errors) > 0 {
+			return errImportRolledBack
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRolledBack) {
+		return nil, err
+	}
+	if len(report.Errors) > 0 && !dryRun {
+		return report, ErrImportFailed
+	}
+	return report, nil
+}
+
+type importer struct {
+	s      *Services
+	report *ImportReport
+	cols   map[string]int
+	// refs maps each row type's refs to the ids of the records they made.
+	refs map[string]map[string]uint
+}
+
+// importRow is one CSV record with the line it started on.
+type importRow struct {
+	line   int
+	fields []string
+	cols   map[string]int
+}
+
+func (row importRow) get(col string) string {
+	i, ok := row.cols[col]
+	if !ok || i >= len(row.fields) {
+		return ""
+	}
+	return strings.TrimSpace(row.fields[i])
+}
+
+func (imp *importer) run(in io.Reader) error {
+	cr := csv.NewReader(in)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return ErrInvalidInput
+	}
+	if err != nil {
+		return imp.readError(err)
+	}
+	if !imp.readHeader(header) {
+		return nil
+	}
+
+	for {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			if err := imp.readError(err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		line, _ := cr.FieldPos(0)
+		row := importRow{line: line, fields: fields, cols: imp.cols}
+		imp.report.Rows++
+		if err := imp.row(row); err != nil {
+			imp.fail(line, row.get("type"), err)
+		}
+	}
+}
+
+// readError reports a malformed CSV line as a row error. Anything else
+// stops the import.
+func (imp *importer) readError(err error) error {
+	var pe *csv.ParseError
+	if !errors.As(err, &pe) {
+		return err
+	}
+	imp.fail(pe.StartLine, "", pe.Err)
+	return nil
+}
+
+// readHeader maps the header's columns and reports whether it is usable.
+func (imp *importer) readHeader(header []string) bool {
+	imp.cols = map[string]int{}
+	good := true
+	for i, col := range header {
+		col = strings.ToLower(strings.TrimSpace(col))
+		if _, dup := imp.cols[col]; dup || !slices.Contains(ImportColumns, col) {
+			imp.fail(1, "", fmt.Errorf("unknown or repeated column %q", col))
+			good = false
+			continue
+		}
+		imp.cols[col] = i
+	}
+	if _, ok := imp.cols["type"]; !ok {
+		imp.fail(1, "", errors.New("missing type column"))
+		good = false
+	}
+	return good
+}
+
+func (imp *importer) fail(line int, typ string, err error) {
+	imp.report.Errors = append(imp.report.Errors, RowError{Line: line, Type: typ, Message: err.Error()})
+}
+
+func (imp *importer) row(row importRow) error {
+	typ := row.get("type")
+	ref := row.get("ref")
+	if ref != "" {
+		if _, dup := imp.refs[typ][ref]; dup {
+			return fmt.Errorf("ref %q is already used by another %s", ref, typ)
+		}
+	}
+
+	var id uint
+	switch typ {
+	case ImportSchool:
+		s, err := imp.s.School.Create(row.get("name"))
+		if err != nil {
+			return err
+		}
+		id = s.ID
+	case ImportPerson:
+		roles := strings.Split(row.get("roles"), ";")
+		p, err := imp.s.Person.Create(row.get("name"), roles...)
+		if err != nil {
+			return err
+		}
+		id = p.ID
+	case ImportClass:
+		schoolID, err := imp.resolve(row, "school", ImportSchool)
+		if err != nil {
+			return err
+		}
+		teacherID, err := imp.resolve(row, "teacher", ImportPerson)
+		if err != nil {
+			return err
+		}
+		c, err := imp.s.Class.Create(row.get("name"), schoolID, teacherID)
+		if err != nil {
+			return err
+		}
+		id = c.ID
+	case ImportEnrollment:
+		classID, err := imp.resolve(row, "class", ImportClass)
+		if err != nil {
+			return err
+		}
+		studentID, err := imp.resolve(row, "student", ImportPerson)
+		if err != nil {
+			return err
+		}
+		position, err := imp.s.Class.Enroll(studentID, classID)
+		if err != nil {
+			return err
+		}
+		if position > 0 {
+			imp.report.Waitlisted++
+		}
+	default:
+		return fmt.Errorf("unknown row type %q", typ)
+	}
+
+	imp.report.Created[typ]++
+	if ref != "" && id != 0 {
+		if imp.refs[typ] == nil {
+			imp.refs[typ] = map[string]uint{}
+		}
+		imp.refs[typ][ref] = id
+	}
+	return nil
+}
+
+// resolve turns the row's col into the id of a record of the given type,
+// looking it up first among the refs of earlier rows.
+func (imp *importer) resolve(row importRow, col string, typ string) (uint, error) {
+	v := row.get(col)
+	if v == "" {
+		return 0, fmt.Errorf("missing %s", col)
+	}
+	if id, ok := imp.refs[typ][v]; ok {
+		return id, nil
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("unknown %s %q", col, v)
+	}
+	return uint(id), nil
+}
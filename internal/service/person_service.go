@@ -1,17 +1,22 @@
 package service
 
 import (
+	"OldSchool/internal/repository"
 	"OldSchool/internal/repository/models"
+	"slices"
 	"strings"
+	"time"
 )
 
 type PersonRepo interface {
-	Create(name string, role string) (*models.Person, error)
+	Create(name string, roles ...string) (*models.Person, error)
 	GetByID(id uint) (*models.Person, error)
 }
 
 type ClassRepoWhoAmI interface {
 	ListIDsByTeacherID(teacheriD uint) ([]uint, error)
+	FilterIDsByTermID(ids []uint, termID uint) ([]uint, error)
+	FilterIDsByOpenTerm(ids []uint, at time.Time) ([]uint, error)
 }
 
 type EnrollmentRepoForWhoAmI interface {
@@ -22,37 +27,115 @@ type PersonService struct {
 	personRepo     PersonRepo
 	classRepo      ClassRepoWhoAmI
 	enrollmentRepo EnrollmentRepoForWhoAmI
+	uow            UnitOfWork
+	now            func() time.Time
 }
 
-func NewPersonService(personRepo PersonRepo, classRepo ClassRepoWhoAmI, enrollmentRepo EnrollmentRepoForWhoAmI) *PersonService {
+func NewPersonService(personRepo PersonRepo, classRepo ClassRepoWhoAmI, enrollmentRepo EnrollmentRepoForWhoAmI, uow UnitOfWork) *PersonService {
 	return &PersonService{
 		personRepo:     personRepo,
 		classRepo:      classRepo,
 		enrollmentRepo: enrollmentRepo,
+		uow:            uow,
+		now:            time.Now,
 	}
 }
 
-func (pr *PersonService) Create(name, role string) (*models.Person, error) {
+// Create adds a person holding every given role. At least one role is
+// required and each must be one of models.ValidRoles.
+func (pr *PersonService) Create(name string, roles ...string) (*models.Person, error) {
 	name = strings.TrimSpace(name)
-	role = strings.TrimSpace(role)
-
 	if name == "" {
 		return nil, ErrInvalidInput
 	}
 
-	if role != "teacher" && role != "student" {
+	set, err := roleSet(roles)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := pr.personRepo.Create(name, set...)
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// CreateWithPassword is Create for a person who can log in right away. The
+// person and their password are saved together, so a refused password
+// leaves nobody behind.
+func (pr *PersonService) CreateWithPassword(name, password string, roles ...string) (*models.Person, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
 		return nil, ErrInvalidInput
 	}
 
-	created, err := pr.personRepo.Create(name, role)
+	set, err := roleSet(roles)
+	if err != nil {
+		return nil, err
+	}
+	h, err := hashPassword(password)
 	if err != nil {
 		return nil, err
 	}
 
+	var created *models.Person
+	err = pr.uow.WithinTx(func(r repository.Repos) error {
+		created, err = r.Person.Create(name, set...)
+		if err != nil {
+			return err
+		}
+		return r.Person.SetPasswordHash(created.ID, h)
+	})
+	if err != nil {
+		return nil, err
+	}
 	return created, nil
 }
 
-func (pr *PersonService) WhoAmI(personID uint) (*models.Person, []uint, error) {
+// roleSet trims and de-duplicates roles. It fails unless there is at least
+// one role and every role is one of models.ValidRoles.
+func roleSet(roles []string) ([]string, error) {
+	set := make([]string, 0, len(roles))
+	for _, role := range roles {
+		role = strings.TrimSpace(role)
+		if !slices.Contains(models.ValidRoles, role) {
+			return nil, ErrInvalidInput
+		}
+		if !slices.Contains(set, role) {
+			set = append(set, role)
+		}
+	}
+	if len(set) == 0 {
+		return nil, ErrInvalidInput
+	}
+	return set, nil
+}
+
+// Memberships maps each role a person holds to the classes they are part of
+// in that role. Roles with no class membership map to an empty list.
+type Memberships map[string][]uint
+
+// ClassIDs returns every class in the memberships once, in ascending order.
+func (m Memberships) ClassIDs() []uint {
+	ids := []uint{}
+	for _, classIDs := range m {
+		ids = append(ids, classIDs...)
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// WhoAmI returns the person with their memberships in classes of currently
+// open terms.
+func (pr *PersonService) WhoAmI(personID uint) (*models.Person, Memberships, error) {
+	return pr.WhoAmIInTerm(personID, 0)
+}
+
+// WhoAmIInTerm is WhoAmI limited to classes of the given term. A termID of 0
+// means whichever term is open now at each class's school.
+func (pr *PersonService) WhoAmIInTerm(personID uint, termID uint) (*models.Person, Memberships, error) {
 	p, err := pr.personRepo.GetByID(personID)
 	if err != nil {
 		return nil, nil, err
@@ -62,20 +145,169 @@ func (pr *PersonService) WhoAmI(personID uint) (*models.Person, []uint, error) {
 		return nil, nil, ErrNotFound
 	}
 
-	switch p.Role {
-	case "student":
-		classIDs, err := pr.enrollmentRepo.ListClassIDsByStudentID(p.ID)
+	m := Memberships{}
+	for _, role := range p.Roles.Names() {
+		var classIDs []uint
+		switch role {
+		case models.RoleStudent:
+			classIDs, err = pr.enrollmentRepo.ListClassIDsByStudentID(p.ID)
+		case models.RoleTeacher:
+			classIDs, err = pr.classRepo.ListIDsByTeacherID(p.ID)
+		}
 		if err != nil {
 			return nil, nil, err
 		}
-		return p, classIDs, nil
-	case "teacher":
-		classIDs, err := pr.classRepo.ListIDsByTeacherID(p.ID)
+		if termID != 0 {
+			classIDs, err = pr.classRepo.FilterIDsByTermID(classIDs, termID)
+		} else {
+			classIDs, err = pr.classRepo.FilterIDsByOpenTerm(classIDs, pr.now())
+		}
 		if err != nil {
 			return nil, nil, err
 		}
-		return p, classIDs, nil
-	default:
-		return nil, nil, ErrRoleMismatch
+		m[role] = classIDs
+	}
+	return p, m, nil
+}
+
+// Update renames a person and, when roles is not nil, replaces their roles.
+// Dropping the teacher role from someone who still teaches, or the student
+// role from someone still enrolled, is refused.
+func (pr *PersonService) Update(personID uint, name string, roles []string) (*models.Person, error) {
+	name = strings.TrimSpace(name)
+	if personID == 0 || (name == "" && roles == nil) {
+		return nil, ErrInvalidInput
+	}
+
+	var set []string
+	if roles != nil {
+		var err error
+		if set, err = roleSet(roles); err != nil {
+			return nil, err
+		}
+	}
+
+	var updated *models.Person
+	err := pr.uow.WithinTx(func(r repository.Repos) error {
+		p, err := r.Person.GetByID(personID)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return ErrNotFound
+		}
+
+		if set != nil {
+			if err := checkRoleRemoval(r, p, set); err != nil {
+				return err
+			}
+			if err := r.Person.SetRoles(personID, set...); err != nil {
+				return err
+			}
+		}
+		if name != "" {
+			if err := r.Person.UpdateName(personID, name); err != nil {
+				return err
+			}
+		}
+
+		updated, err = r.Person.GetByID(personID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func checkRoleRemoval(r repository.Repos, p *models.Person, roles []string) error {
+	if p.HasRole(models.RoleTeacher) && !slices.Contains(roles, models.RoleTeacher) {
+		taught, err := r.Class.ListIDsByTeacherID(p.ID)
+		if err != nil {
+			return err
+		}
+		if len(taught) > 0 {
+			return ErrPersonHasClasses
+		}
+	}
+	if p.HasRole(models.RoleStudent) && !slices.Contains(roles, models.RoleStudent) {
+		enrolled, err := r.Enrollment.ListClassIDsByStudentID(p.ID)
+		if err != nil {
+			return err
+		}
+		if len(enrolled) > 0 {
+			return ErrPersonHasClasses
+		}
+	}
+	return nil
+}
+
+// Delete removes a person along with their roles, sessions and transfer
+// history. Someone who still teaches a class is always refused, since their
+// classes need a new teacher first. Enrollments are dropped only when cascade
+// is set.
+func (pr *PersonService) Delete(personID uint, cascade bool) error {
+	if personID == 0 {
+		return ErrInvalidInput
 	}
+
+	return pr.uow.WithinTx(func(r repository.Repos) error {
+		p, err := r.Person.GetByID(personID)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return ErrNotFound
+		}
+
+		taught, err := r.Class.ListIDsByTeacherID(personID)
+		if err != nil {
+			return err
+		}
+		if len(taught) > 0 {
+			return ErrPersonHasClasses
+		}
+
+		enrolled, err := r.Enrollment.ListClassIDsByStudentID(personID)
+		if err != nil {
+			return err
+		}
+		if len(enrolled) > 0 && !cascade {
+			return ErrPersonHasClasses
+		}
+
+		if err := r.Enrollment.DeleteByStudentID(personID); err != nil {
+			return err
+		}
+		if err := r.Waitlist.DeleteByStudentID(personID); err != nil {
+			return err
+		}
+		if err := r.Score.DeleteByStudentID(personID); err != nil {
+			return err
+		}
+		if err := r.Attendance.DeleteByStudentID(personID); err != nil {
+			return err
+		}
+		if err := r.Submission.DeleteByStudentID(personID); err != nil {
+			return err
+		}
+		if err := r.Comment.DeleteByStudentID(personID); err != nil {
+			return err
+		}
+		if err := r.Guardian.DeleteByPersonID(personID); err != nil {
+			return err
+		}
+		for _, classID := range enrolled {
+			if err := promoteWaitlist(r, classID, pr.now()); err != nil {
+				return err
+			}
+		}
+		if err := r.Session.DeleteByPersonID(personID); err != nil {
+			return err
+		}
+		if err := r.Transfer.DeleteByStudentID(personID); err != nil {
+			return err
+		}
+		return r.Person.Delete(personID)
+	})
 }
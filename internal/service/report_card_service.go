@@ -0,0 +1,265 @@
+package service
+
+import (
+	"OldSchool/internal/repository"
+	"OldSchool/internal/repository/models"
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	ReportFormatJSON = "json"
+	ReportFormatText = "text"
+	ReportFormatHTML = "html"
+)
+
+type ReportCommentRepo interface {
+	Get(classID, studentID uint) (*models.ReportComment, error)
+}
+
+type ReportCardService struct {
+	person         *PersonService
+	schoolRepo     SchoolRepo
+	termRepo       TermRepo
+	classRepo      ClassRepoForGradebook
+	personRepo     PersonRepo
+	assessmentRepo AssessmentRepo
+	scoreRepo      ScoreRepo
+	attendanceRepo AttendanceRepo
+	commentRepo    ReportCommentRepo
+	uow            UnitOfWork
+	now            func() time.Time
+}
+
+func NewReportCardService(person *PersonService, schoolRepo SchoolRepo, termRepo TermRepo, classRepo ClassRepoForGradebook, personRepo PersonRepo, assessmentRepo AssessmentRepo, scoreRepo ScoreRepo, attendanceRepo AttendanceRepo, commentRepo ReportCommentRepo, uow UnitOfWork) *ReportCardService {
+	return &ReportCardService{
+		person:         person,
+		schoolRepo:     schoolRepo,
+		termRepo:       termRepo,
+		classRepo:      classRepo,
+		personRepo:     personRepo,
+		assessmentRepo: assessmentRepo,
+		scoreRepo:      scoreRepo,
+		attendanceRepo: attendanceRepo,
+		commentRepo:    commentRepo,
+		uow:            uow,
+		now:            time.Now,
+	}
+}
+
+// ReportCard is everything known about one student for a term. School is nil
+// for a student without a school, and Term is nil when no term was asked for
+// and the school has none open.
+type ReportCard struct {
+	StudentID   uint              `json:"student_id"`
+	StudentName string            `json:"student_name"`
+	School      *models.School    `json:"school"`
+	Term        *models.Term      `json:"term"`
+	Classes     []ReportCardClass `json:"classes"`
+	GeneratedAt time.Time         `json:"generated_at"`
+}
+
+// ReportCardClass is one class on a report card.
+type ReportCardClass struct {
+	ClassID        uint     `json:"class_id"`
+	ClassName      string   `json:"class_name"`
+	TeacherID      uint     `json:"teacher_id"`
+	TeacherName    string   `json:"teacher_name"`
+	FinalGrade     *float64 `json:"final_grade"`
+	AttendanceRate *float64 `json:"attendance_rate"`
+	Comment        string   `json:"comment"`
+}
+
+// Build puts together the student's report card for the term, or for the
+// terms open now when termID is 0. The classes are the ones WhoAmIInTerm
+// lists for the student.
+func (rs *ReportCardService) Build(studentID, termID uint) (*ReportCard, error) {
+	if studentID == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	p, memberships, err := rs.person.WhoAmIInTerm(studentID, termID)
+	if err != nil {
+		return nil, err
+	}
+	if !p.HasRole(models.RoleStudent) {
+		return nil, ErrRoleMismatch
+	}
+
+	card := &ReportCard{
+		StudentID:   p.ID,
+		StudentName: p.Name,
+		Classes:     []ReportCardClass{},
+		GeneratedAt: rs.now().UTC(),
+	}
+	if p.StudentSchoolID != nil {
+		if card.School, err = rs.schoolRepo.GetByID(*p.StudentSchoolID); err != nil {
+			return nil, err
+		}
+	}
+
+	if termID != 0 {
+		t, err := rs.termRepo.GetByID(termID)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return nil, ErrNotFound
+		}
+		card.Term = t
+	} else if card.School != nil {
+		if card.Term, err = rs.termRepo.Current(card.School.ID, rs.now()); err != nil {
+			return nil, err
+		}
+	}
+
+	grades, err := studentClassGrades(rs.assessmentRepo, rs.scoreRepo, rs.classRepo, p.ID, memberships[models.RoleStudent])
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range grades {
+		entry, err := rs.reportCardClass(p.ID, g)
+		if err != nil {
+			return nil, err
+		}
+		card.Classes = append(card.Classes, entry)
+	}
+	return card, nil
+}
+
+func (rs *ReportCardService) reportCardClass(studentID uint, g ClassGrade) (ReportCardClass, error) {
+	entry := ReportCardClass{
+		ClassID:    g.ClassID,
+		ClassName:  g.ClassName,
+		FinalGrade: g.FinalGrade,
+	}
+
+	cl, err := rs.classRepo.GetByID(g.ClassID)
+	if err != nil || cl == nil {
+		return entry, err
+	}
+	entry.TeacherID = cl.TeacherID
+	teacher, err := rs.personRepo.GetByID(cl.TeacherID)
+	if err != nil {
+		return entry, err
+	}
+	if teacher != nil {
+		entry.TeacherName = teacher.Name
+	}
+
+	s, err := rs.attendanceRepo.Summarize(g.ClassID, studentID)
+	if err != nil {
+		return entry, err
+	}
+	entry.AttendanceRate = attendanceRate(s).Rate
+
+	c, err := rs.commentRepo.Get(g.ClassID, studentID)
+	if err != nil {
+		return entry, err
+	}
+	if c != nil {
+		entry.Comment = c.Comment
+	}
+	return entry, nil
+}
+
+// Comment sets the comment printed on the student's report card for the
+// class, replacing any earlier one.
+func (rs *ReportCardService) Comment(authorID, classID, studentID uint, comment string) error {
+	comment = strings.TrimSpace(comment)
+	if authorID == 0 || classID == 0 || studentID == 0 || comment == "" {
+		return ErrInvalidInput
+	}
+
+	return rs.uow.WithinTx(func(r repository.Repos) error {
+		cl, err := r.Class.GetByID(classID)
+		if err != nil {
+			return err
+		}
+		if cl == nil {
+			return ErrNotFound
+		}
+		enrolled, err := r.Enrollment.Exists(classID, studentID)
+		if err != nil {
+			return err
+		}
+		if !enrolled {
+			return ErrNotEnrolled
+		}
+		return r.Comment.Set(classID, studentID, authorID, comment)
+	})
+}
+
+var reportCardFuncs = map[string]any{
+	"percent": func(v *float64, scale float64) string {
+		if v == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", *v*scale)
+	},
+	"date": func(t time.Time) string { return t.Format(time.DateOnly) },
+}
+
+const reportCardText = `REPORT CARD
+Student: {{.StudentName}} (#{{.StudentID}})
+School:  {{with .School}}{{.Name}}{{else}}-{{end}}
+Term:    {{with .Term}}{{.Name}} ({{date .StartsAt}} to {{date .EndsAt}}){{else}}-{{end}}
+{{range .Classes}}
+{{.ClassName}}
+  Teacher:    {{.TeacherName}}
+  Grade:      {{percent .FinalGrade 1}}
+  Attendance: {{percent .AttendanceRate 100}}
+{{- if .Comment}}
+  Comment:    {{.Comment}}
+{{- end}}
+{{else}}
+No classes this term.
+{{end}}
+Generated {{date .GeneratedAt}}
+`
+
+const reportCardHTML = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Report card: {{.StudentName}}</title></head>
+<body>
+<h1>Report card</h1>
+<p>Student: {{.StudentName}} (#{{.StudentID}})<br>
+School: {{with .School}}{{.Name}}{{else}}-{{end}}<br>
+Term: {{with .Term}}{{.Name}} ({{date .StartsAt}} to {{date .EndsAt}}){{else}}-{{end}}</p>
+<table border="1" cellpadding="4">
+<tr><th>Class</th><th>Teacher</th><th>Grade</th><th>Attendance</th><th>Comment</th></tr>
+{{range .Classes}}<tr><td>{{.ClassName}}</td><td>{{.TeacherName}}</td><td>{{percent .FinalGrade 1}}</td><td>{{percent .AttendanceRate 100}}</td><td>{{.Comment}}</td></tr>
+{{else}}<tr><td colspan="5">No classes this term.</td></tr>
+{{end}}</table>
+<p>Generated {{date .GeneratedAt}}</p>
+</body>
+</html>
+`
+
+var (
+	reportCardTextTmpl = template.Must(template.New("text").Funcs(reportCardFuncs).Parse(reportCardText))
+	reportCardHTMLTmpl = htmltemplate.Must(htmltemplate.New("html").Funcs(reportCardFuncs).Parse(reportCardHTML))
+)
+
+// Render prints the report card as plain text or as an HTML page. User
+// supplied names and comments are escaped in the HTML version.
+func (rc *ReportCard) Render(format string) (string, error) {
+	var b bytes.Buffer
+	var err error
+	switch format {
+	case ReportFormatText:
+		err = reportCardTextTmpl.Execute(&b, rc)
+	case ReportFormatHTML:
+		err = reportCardHTMLTmpl.Execute(&b, rc)
+	default:
+		return "", ErrInvalidInput
+	}
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -1,32 +1,41 @@
 package service
 
 import (
+	"OldSchool/internal/repository"
 	"OldSchool/internal/repository/models"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/mattn/go-sqlite3"
 )
 
 type SchoolRepo interface {
 	Create(name string) (*models.School, error)
-	List() ([]models.School, error)
+	List(opts repository.ListOptions) (repository.Page[models.School], error)
 	GetByID(id uint) (*models.School, error)
 }
 
 type ClassRepoForSchool interface {
-	ListBySchoolID(schoolID uint) ([]models.Class, error)
+	ListBySchoolAndTermID(schoolID, termID uint) ([]models.Class, error)
+	PageBySchoolAndTermID(schoolID, termID uint, opts repository.ListOptions) (repository.Page[models.Class], error)
 }
 
 type SchoolService struct {
 	schoolRepo SchoolRepo
 	classRepo  ClassRepoForSchool
+	termRepo   TermRepo
+	uow        UnitOfWork
+	now        func() time.Time
 }
 
-func NewSchoolService(schoolRepo SchoolRepo, classRepo ClassRepoForSchool) *SchoolService {
+func NewSchoolService(schoolRepo SchoolRepo, classRepo ClassRepoForSchool, termRepo TermRepo, uow UnitOfWork) *SchoolService {
 	return &SchoolService{
 		schoolRepo: schoolRepo,
 		classRepo:  classRepo,
+		termRepo:   termRepo,
+		uow:        uow,
+		now:        time.Now,
 	}
 }
 
@@ -55,20 +64,152 @@ func (ss *SchoolService) Create(name string) (*models.School, error) {
 	return created, nil
 }
 
-func (ss *SchoolService) List() ([]models.School, error) {
-	return ss.schoolRepo.List()
+// List pages through the schools.
+func (ss *SchoolService) List(opts repository.ListOptions) (repository.Page[models.School], error) {
+	page, err := ss.schoolRepo.List(opts)
+	return page, listError(err)
 }
 
+// ListClasses lists the school's classes in its current term.
 func (ss *SchoolService) ListClasses(schoolID uint) ([]models.Class, error) {
+	return ss.ListClassesInTerm(schoolID, 0)
+}
+
+// ListClassesInTerm lists the school's classes in the given term, or in its
+// current term when termID is 0. With no current term the list is empty.
+func (ss *SchoolService) ListClassesInTerm(schoolID uint, termID uint) ([]models.Class, error) {
+	termID, err := ss.classTerm(schoolID, termID)
+	if err != nil {
+		return nil, err
+	}
+	if termID == 0 {
+		return []models.Class{}, nil
+	}
+	return ss.classRepo.ListBySchoolAndTermID(schoolID, termID)
+}
+
+// ListClassesPage is ListClassesInTerm a page at a time.
+func (ss *SchoolService) ListClassesPage(schoolID uint, termID uint, opts repository.ListOptions) (repository.Page[models.Class], error) {
+	termID, err := ss.classTerm(schoolID, termID)
+	if err != nil {
+		return repository.Page[models.Class]{}, err
+	}
+	if termID == 0 {
+		return repository.Page[models.Class]{Items: []models.Class{}}, nil
+	}
+	page, err := ss.classRepo.PageBySchoolAndTermID(schoolID, termID, opts)
+	return page, listError(err)
+}
+
+// classTerm checks the school and resolves the term its classes are listed
+// for. It returns 0 when termID is 0 and the school has no current term.
+func (ss *SchoolService) classTerm(schoolID uint, termID uint) (uint, error) {
 	if schoolID == 0 {
-		return nil, ErrInvalidInput
+		return 0, ErrInvalidInput
 	}
 	s, err := ss.schoolRepo.GetByID(schoolID)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	if s == nil {
-		return nil, ErrNotFound
+		return 0, ErrNotFound
+	}
+
+	if termID == 0 {
+		t, err := ss.termRepo.Current(schoolID, ss.now())
+		if err != nil || t == nil {
+			return 0, err
+		}
+		return t.ID, nil
+	}
+	t, err := ss.termRepo.GetByID(termID)
+	if err != nil {
+		return 0, err
+	}
+	if t == nil || t.SchoolID != schoolID {
+		return 0, ErrNotFound
+	}
+	return termID, nil
+}
+
+// listError turns bad paging options into ErrInvalidInput.
+func listError(err error) error {
+	if errors.Is(err, repository.ErrInvalidListOptions) {
+		return ErrInvalidInput
+	}
+	return err
+}
+
+func (ss *SchoolService) Update(schoolID uint, name string) (*models.School, error) {
+	name = strings.TrimSpace(name)
+	if schoolID == 0 || name == "" {
+		return nil, ErrInvalidInput
+	}
+
+	var updated *models.School
+	err := ss.uow.WithinTx(func(r repository.Repos) error {
+		s, err := r.School.GetByID(schoolID)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return ErrNotFound
+		}
+
+		if err := r.School.UpdateName(schoolID, name); err != nil {
+			if isUniqueConstraintErr(err) {
+				return ErrSchoolAlreadyExists
+			}
+			return err
+		}
+		updated, err = r.School.GetByID(schoolID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Delete removes a school. A school with classes is refused unless cascade is
+// set, in which case its classes and their enrollments go too and its
+// students are free to enroll at another school.
+func (ss *SchoolService) Delete(schoolID uint, cascade bool) error {
+	if schoolID == 0 {
+		return ErrInvalidInput
 	}
-	return ss.classRepo.ListBySchoolID(schoolID)
+
+	return ss.uow.WithinTx(func(r repository.Repos) error {
+		s, err := r.School.GetByID(schoolID)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return ErrNotFound
+		}
+
+		classIDs, err := r.Class.ListIDsBySchoolID(schoolID)
+		if err != nil {
+			return err
+		}
+		if len(classIDs) > 0 && !cascade {
+			return ErrSchoolHasClasses
+		}
+
+		for _, classID := range classIDs {
+			if err := purgeClass(r, classID); err != nil {
+				return err
+			}
+		}
+		if err := r.Person.ClearStudentSchoolID(schoolID); err != nil {
+			return err
+		}
+		if err := r.Term.DeleteBySchoolID(schoolID); err != nil {
+			return err
+		}
+		if err := r.Room.DeleteBySchoolID(schoolID); err != nil {
+			return err
+		}
+		return r.School.Delete(schoolID)
+	})
 }
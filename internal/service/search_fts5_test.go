@@ -0,0 +1,24 @@
+//go:build sqlite_fts5
+
+package service
+
+import "testing"
+
+func TestSearch_FTS5SplitsWordsOnPunctuation(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("Sean O'Brien", "teacher")
+	env.Class.Create("Arts-and-Crafts", s.ID, teacher.ID)
+
+	results, err := env.Search.Search("brien", 0, 0)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(results) != 1 || results[0].ID != teacher.ID {
+		t.Fatalf("expected the teacher, got %+v", results)
+	}
+	if results, _ = env.Search.Search("crafts", 0, 0); len(results) != 1 || results[0].Type != "class" {
+		t.Fatalf("expected the class, got %+v", results)
+	}
+}
@@ -0,0 +1,64 @@
+//go:build !sqlite_fts5
+
+package service
+
+import (
+	"OldSchool/internal/repository"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearch_FallbackOpensFTS5Database(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := repository.InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+
+	// leave behind what an FTS5 build creates; this build cannot create
+	// the virtual table itself, so its schema row is written directly
+	for _, stmt := range []string{
+		"PRAGMA writable_schema = ON",
+		`INSERT INTO sqlite_master (type, name, tbl_name, rootpage, sql) VALUES ('table', 'search_index', 'search_index', 0,
+			'CREATE VIRTUAL TABLE search_index USING fts5(name, prefix = ''2 3'')')`,
+		"PRAGMA writable_schema = OFF",
+		"CREATE TABLE 'search_index_data'(id INTEGER PRIMARY KEY, block BLOB)",
+		"CREATE TABLE 'search_index_idx'(segid, term, pgno, PRIMARY KEY(segid, term)) WITHOUT ROWID",
+		"CREATE TABLE 'search_index_content'(id INTEGER PRIMARY KEY, c0)",
+		"CREATE TABLE 'search_index_docsize'(id INTEGER PRIMARY KEY, sz BLOB)",
+		"CREATE TABLE 'search_index_config'(k PRIMARY KEY, v) WITHOUT ROWID",
+		`CREATE TRIGGER schools_search_insert AFTER INSERT ON schools BEGIN
+			INSERT INTO search_index (rowid, name) VALUES (new.id * 4 + 1, new.name); END`,
+		`CREATE TRIGGER people_search_update AFTER UPDATE OF name ON people BEGIN
+			UPDATE search_index SET name = new.name WHERE rowid = new.id * 4 + 3; END`,
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	sqlDB, _ := db.DB()
+	_ = sqlDB.Close()
+
+	db, err = repository.InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("InitDB on an FTS5 database failed: %v", err)
+	}
+	sqlDB, _ = db.DB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	var left int64
+	db.Raw("SELECT count(*) FROM sqlite_master WHERE name LIKE '%search%'").Scan(&left)
+	if left != 0 {
+		t.Fatalf("expected the FTS5 index and triggers to be dropped, %d left", left)
+	}
+
+	schoolRepo := repository.NewSchoolRepository(db)
+	s, err := schoolRepo.Create("Northfield")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	results, err := NewSearchService(repository.NewSearchRepository(db), schoolRepo).Search("north", 0, 0)
+	if err != nil || len(results) != 1 || results[0].ID != s.ID {
+		t.Fatalf("expected the school, got %+v, %v", results, err)
+	}
+}
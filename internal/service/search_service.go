@@ -0,0 +1,53 @@
+package service
+
+import (
+	"OldSchool/internal/repository"
+	"OldSchool/internal/repository/models"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+type SearchRepo interface {
+	Search(terms []string, schoolID uint, limit int) ([]models.SearchResult, error)
+}
+
+type SearchService struct {
+	searchRepo SearchRepo
+	schoolRepo SchoolRepo
+}
+
+func NewSearchService(searchRepo SearchRepo, schoolRepo SchoolRepo) *SearchService {
+	return &SearchService{
+		searchRepo: searchRepo,
+		schoolRepo: schoolRepo,
+	}
+}
+
+// Search looks up schools, classes and people by name. Each word of the query
+// must start a word of the name, so a partial name finds the whole one. A
+// schoolID other than 0 keeps only that school, its classes, their teachers
+// and its students. A limit of 0 means DefaultSearchLimit.
+func (ss *SearchService) Search(query string, schoolID uint, limit int) ([]models.SearchResult, error) {
+	terms := repository.SearchTerms(query)
+	if len(terms) == 0 || limit < 0 || limit > MaxSearchLimit {
+		return nil, ErrInvalidInput
+	}
+	if limit == 0 {
+		limit = DefaultSearchLimit
+	}
+
+	if schoolID != 0 {
+		s, err := ss.schoolRepo.GetByID(schoolID)
+		if err != nil {
+			return nil, err
+		}
+		if s == nil {
+			return nil, ErrNotFound
+		}
+	}
+
+	return ss.searchRepo.Search(terms, schoolID, limit)
+}
@@ -2,15 +2,33 @@ package service
 
 import (
 	"OldSchool/internal/repository"
+	"archive/zip"
+	"bytes"
 	"fmt"
+	"io"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 type testEnv struct {
-	School *SchoolService
-	Person *PersonService
-	Class  *ClassService
+	School     *SchoolService
+	Person     *PersonService
+	Class      *ClassService
+	Auth       *AuthService
+	Transfer   *TransferService
+	Term       *TermService
+	Timetable  *TimetableService
+	Gradebook  *GradebookService
+	Attendance *AttendanceService
+	Assignment *AssignmentService
+	ReportCard *ReportCardService
+	Guardian   *GuardianService
+	Search     *SearchService
+	Import     *ImportService
+	Export     *ExportService
 }
 
 func setup(t *testing.T) testEnv {
@@ -34,19 +52,37 @@ func setup(t *testing.T) testEnv {
 	personRepo := repository.NewPersonRepositrory(db)
 	classRepo := repository.NewClassRepository(db)
 	enrollRepo := repository.NewEnrollmentRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	transferRepo := repository.NewTransferRepository(db)
+	termRepo := repository.NewTermRepository(db)
 
 	// uow
 	uow := repository.NewUnitOfWork(db)
 
 	// services
-	schoolSvc := NewSchoolService(schoolRepo, classRepo)
-	personSvc := NewPersonService(personRepo, classRepo, enrollRepo)
-	classSvc := NewClassService(classRepo, personRepo, uow, enrollRepo)
+	schoolSvc := NewSchoolService(schoolRepo, classRepo, termRepo, uow)
+	personSvc := NewPersonService(personRepo, classRepo, enrollRepo, uow)
+	classSvc := NewClassService(classRepo, personRepo, uow, enrollRepo, repository.NewWaitlistRepository(db))
+	authSvc := NewAuthService(personRepo, sessionRepo, DefaultSessionTTL)
+	transferSvc := NewTransferService(transferRepo, personRepo, uow)
+	termSvc := NewTermService(termRepo, schoolRepo)
 
 	return testEnv{
-		School: schoolSvc,
-		Person: personSvc,
-		Class:  classSvc,
+		School:     schoolSvc,
+		Person:     personSvc,
+		Class:      classSvc,
+		Auth:       authSvc,
+		Transfer:   transferSvc,
+		Term:       termSvc,
+		Timetable:  NewTimetableService(repository.NewRoomRepository(db), repository.NewSlotRepository(db), classRepo, enrollRepo, schoolRepo, personRepo, uow),
+		Gradebook:  NewGradebookService(repository.NewAssessmentRepository(db), repository.NewScoreRepository(db), classRepo, enrollRepo, personRepo, uow),
+		Attendance: NewAttendanceService(repository.NewAttendanceRepository(db), classRepo, schoolRepo, personRepo, uow),
+		Assignment: NewAssignmentService(repository.NewAssignmentRepository(db), repository.NewSubmissionRepository(db), classRepo, enrollRepo, uow),
+		ReportCard: NewReportCardService(personSvc, schoolRepo, termRepo, classRepo, personRepo, repository.NewAssessmentRepository(db), repository.NewScoreRepository(db), repository.NewAttendanceRepository(db), repository.NewReportCommentRepository(db), uow),
+		Guardian:   NewGuardianService(repository.NewGuardianRepository(db), personRepo, personSvc, uow),
+		Search:     NewSearchService(repository.NewSearchRepository(db), schoolRepo),
+		Import:     NewImportService(uow),
+		Export:     NewExportService(repository.NewExportRepository(db), schoolRepo),
 	}
 }
 
@@ -68,12 +104,31 @@ func TestCreateSchool_Duplicate(t *testing.T) {
 func TestCreatePerson_InvalidRole(t *testing.T) {
 	env := setup(t)
 
-	_, err := env.Person.Create("Ali", "admin")
+	// admin is a real role now, so use one that does not exist
+	_, err := env.Person.Create("Ali", "janitor")
 	if err != ErrInvalidInput {
 		t.Fatalf("expected ErrInvalidInput, got %v", err)
 	}
 }
 
+func TestCreatePerson_WithPassword(t *testing.T) {
+	env := setup(t)
+
+	if _, err := env.Person.CreateWithPassword("Ann", "short", "student"); err != ErrInvalidInput {
+		t.Fatalf("expected ErrInvalidInput for short password, got %v", err)
+	}
+	p, err := env.Person.CreateWithPassword("Ann", "student-password", "student")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if p.ID != 1 {
+		t.Fatalf("expected the refused person not to be saved, got id %d", p.ID)
+	}
+	if _, _, err := env.Auth.Login(p.ID, "student-password"); err != nil {
+		t.Fatalf("expected login with the new password, got %v", err)
+	}
+}
+
 func TestCreatePerson_OK(t *testing.T) {
 	env := setup(t)
 
@@ -177,7 +232,8 @@ func TestWhoAmI_TeacherAndStudent(t *testing.T) {
 	_ = env.Class.AddStudentToClass(student.ID, c1.ID)
 
 	// ---- teacher ----
-	_, classIDs, err := env.Person.WhoAmI(teacher.ID)
+	_, teacherM, err := env.Person.WhoAmI(teacher.ID)
+	classIDs := teacherM.ClassIDs()
 	if err != nil {
 		t.Fatalf("expected nil, got %v", err)
 	}
@@ -191,7 +247,8 @@ func TestWhoAmI_TeacherAndStudent(t *testing.T) {
 	}
 
 	// ---- student ----
-	_, studentClassIDs, err := env.Person.WhoAmI(student.ID)
+	_, studentM, err := env.Person.WhoAmI(student.ID)
+	studentClassIDs := studentM.ClassIDs()
 	if err != nil {
 		t.Fatalf("expected nil, got %v", err)
 	}
@@ -200,3 +257,1169 @@ func TestWhoAmI_TeacherAndStudent(t *testing.T) {
 		t.Fatalf("expected [%d], got %v", c1.ID, studentClassIDs)
 	}
 }
+
+func TestWhoAmI_TeachingAssistantHoldsBothRoles(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	ta, err := env.Person.Create("TA", "student", "teacher", "student")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(ta.Roles) != 2 {
+		t.Fatalf("expected duplicate roles to collapse, got %v", ta.Roles.Names())
+	}
+
+	taught, err := env.Class.Create("C1", s.ID, ta.ID)
+	if err != nil {
+		t.Fatalf("expected TA to teach, got %v", err)
+	}
+	attended, _ := env.Class.Create("C2", s.ID, teacher.ID)
+	if err := env.Class.AddStudentToClass(ta.ID, attended.ID); err != nil {
+		t.Fatalf("expected TA to enroll, got %v", err)
+	}
+	if err := env.Class.AddStudentToClass(ta.ID, taught.ID); err != ErrRoleMismatch {
+		t.Fatalf("expected ErrRoleMismatch enrolling in own class, got %v", err)
+	}
+
+	_, m, err := env.Person.WhoAmI(ta.ID)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if got := m["teacher"]; len(got) != 1 || got[0] != taught.ID {
+		t.Fatalf("unexpected teacher memberships: %v", got)
+	}
+	if got := m["student"]; len(got) != 1 || got[0] != attended.ID {
+		t.Fatalf("unexpected student memberships: %v", got)
+	}
+}
+
+func TestAuth_LoginAuthenticateAndRevoke(t *testing.T) {
+	env := setup(t)
+
+	admin, err := env.Auth.Bootstrap("root", "correct horse")
+	if err != nil || admin == nil {
+		t.Fatalf("expected admin to be created, got %v, %v", admin, err)
+	}
+	if again, err := env.Auth.Bootstrap("root2", "correct horse"); err != nil || again != nil {
+		t.Fatalf("expected second bootstrap to be a no-op, got %v, %v", again, err)
+	}
+
+	if _, _, err := env.Auth.Login(admin.ID, "wrong"); err != ErrInvalidCredentials {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+
+	token, _, err := env.Auth.Login(admin.ID, "correct horse")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	p, _, err := env.Auth.Authenticate(token)
+	if err != nil || p.ID != admin.ID {
+		t.Fatalf("expected session for admin, got %v, %v", p, err)
+	}
+
+	if _, err := env.Auth.RevokeSessions(admin.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if _, _, err := env.Auth.Authenticate(token); err != ErrUnauthenticated {
+		t.Fatalf("expected ErrUnauthenticated after revoke, got %v", err)
+	}
+}
+
+func TestAuth_SessionExpires(t *testing.T) {
+	env := setup(t)
+
+	stu, _ := env.Person.Create("Stu", "student")
+	if err := env.Auth.SetPassword(stu.ID, "short"); err != ErrInvalidInput {
+		t.Fatalf("expected ErrInvalidInput for short password, got %v", err)
+	}
+	if err := env.Auth.SetPassword(stu.ID, "student-password"); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	token, _, err := env.Auth.Login(stu.ID, "student-password")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	env.Auth.now = func() time.Time { return time.Now().Add(DefaultSessionTTL + time.Minute) }
+	if _, _, err := env.Auth.Authenticate(token); err != ErrUnauthenticated {
+		t.Fatalf("expected ErrUnauthenticated for expired session, got %v", err)
+	}
+}
+
+func TestDeleteSchool_RefusesClassesUnlessCascade(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	class, _ := env.Class.Create("C1", s.ID, teacher.ID)
+	student, _ := env.Person.Create("Stu", "student")
+	if err := env.Class.AddStudentToClass(student.ID, class.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	if err := env.School.Delete(s.ID, false); err != ErrSchoolHasClasses {
+		t.Fatalf("expected ErrSchoolHasClasses, got %v", err)
+	}
+	if err := env.School.Delete(s.ID, true); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if _, err := env.School.ListClasses(s.ID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound for deleted school, got %v", err)
+	}
+
+	// the student is free to join another school
+	s2, _ := env.School.Create("S2")
+	c2, _ := env.Class.Create("C2", s2.ID, teacher.ID)
+	if err := env.Class.AddStudentToClass(student.ID, c2.ID); err != nil {
+		t.Fatalf("expected enrollment at new school, got %v", err)
+	}
+}
+
+func TestUpdateSchool_Rename(t *testing.T) {
+	env := setup(t)
+
+	s1, _ := env.School.Create("S1")
+	env.School.Create("S2")
+
+	if _, err := env.School.Update(s1.ID, "S2"); err != ErrSchoolAlreadyExists {
+		t.Fatalf("expected ErrSchoolAlreadyExists, got %v", err)
+	}
+	if _, err := env.School.Update(999, "S3"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	updated, err := env.School.Update(s1.ID, "  S3 ")
+	if err != nil || updated.Name != "S3" {
+		t.Fatalf("expected rename to S3, got %v, %v", updated, err)
+	}
+}
+
+func TestUpdatePerson_RolesAndName(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("Tecaher", "teacher")
+	env.Class.Create("C1", s.ID, teacher.ID)
+
+	if _, err := env.Person.Update(teacher.ID, "", []string{"student"}); err != ErrPersonHasClasses {
+		t.Fatalf("expected ErrPersonHasClasses, got %v", err)
+	}
+
+	updated, err := env.Person.Update(teacher.ID, "Teacher", []string{"teacher", "staff"})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if updated.Name != "Teacher" || !updated.HasRole("staff") || !updated.HasRole("teacher") {
+		t.Fatalf("unexpected person after update: %+v", updated)
+	}
+}
+
+func TestDeletePerson_Dependants(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	class, _ := env.Class.Create("C1", s.ID, teacher.ID)
+	student, _ := env.Person.Create("Stu", "student")
+	_ = env.Class.AddStudentToClass(student.ID, class.ID)
+
+	if err := env.Person.Delete(teacher.ID, true); err != ErrPersonHasClasses {
+		t.Fatalf("expected ErrPersonHasClasses for teacher, got %v", err)
+	}
+	if err := env.Person.Delete(student.ID, false); err != ErrPersonHasClasses {
+		t.Fatalf("expected ErrPersonHasClasses for enrolled student, got %v", err)
+	}
+	if err := env.Person.Delete(student.ID, true); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if _, _, err := env.Person.WhoAmI(student.ID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	students, _ := env.Class.ListStudents(class.ID)
+	if len(students) != 0 {
+		t.Fatalf("expected enrollment to be gone, got %d students", len(students))
+	}
+}
+
+func TestDeleteClass_RefusesStudentsUnlessCascade(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	class, _ := env.Class.Create("C1", s.ID, teacher.ID)
+	student, _ := env.Person.Create("Stu", "student")
+	_ = env.Class.AddStudentToClass(student.ID, class.ID)
+
+	if err := env.Class.Delete(class.ID, false); err != ErrClassHasStudents {
+		t.Fatalf("expected ErrClassHasStudents, got %v", err)
+	}
+	if err := env.Class.Delete(class.ID, true); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if err := env.Class.Delete(class.ID, true); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// with the class gone the teacher can be deleted
+	if err := env.Person.Delete(teacher.ID, false); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+}
+
+func TestRemoveStudentFromClass_ReleasesSchoolAfterLastClass(t *testing.T) {
+	env := setup(t)
+
+	s1, _ := env.School.Create("S1")
+	s2, _ := env.School.Create("S2")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	c1, _ := env.Class.Create("C1", s1.ID, teacher.ID)
+	c2, _ := env.Class.Create("C2", s1.ID, teacher.ID)
+	other, _ := env.Class.Create("C3", s2.ID, teacher.ID)
+	student, _ := env.Person.Create("Stu", "student")
+
+	_ = env.Class.AddStudentToClass(student.ID, c1.ID)
+	_ = env.Class.AddStudentToClass(student.ID, c2.ID)
+
+	if err := env.Class.RemoveStudentFromClass(student.ID, other.ID); err != ErrNotEnrolled {
+		t.Fatalf("expected ErrNotEnrolled, got %v", err)
+	}
+
+	if err := env.Class.RemoveStudentFromClass(student.ID, c1.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	// still in C2, so still bound to S1
+	if err := env.Class.AddStudentToClass(student.ID, other.ID); err != ErrDifferentSchool {
+		t.Fatalf("expected ErrDifferentSchool, got %v", err)
+	}
+
+	if err := env.Class.RemoveStudentFromClass(student.ID, c2.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if err := env.Class.AddStudentToClass(student.ID, other.ID); err != nil {
+		t.Fatalf("expected enrollment at S2 after leaving S1, got %v", err)
+	}
+}
+
+func TestTransfer_MovesStudentAndRecordsHistory(t *testing.T) {
+	env := setup(t)
+
+	s1, _ := env.School.Create("S1")
+	s2, _ := env.School.Create("S2")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	old, _ := env.Class.Create("C1", s1.ID, teacher.ID)
+	next, _ := env.Class.Create("C2", s2.ID, teacher.ID)
+	student, _ := env.Person.Create("Stu", "student")
+	_ = env.Class.AddStudentToClass(student.ID, old.ID)
+
+	transfer, err := env.Transfer.Transfer(student.ID, s2.ID, "moved house", []uint{next.ID})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if transfer.FromSchoolID == nil || *transfer.FromSchoolID != s1.ID || transfer.ToSchoolID != s2.ID {
+		t.Fatalf("unexpected transfer record: %+v", transfer)
+	}
+
+	_, m, _ := env.Person.WhoAmI(student.ID)
+	if ids := m.ClassIDs(); len(ids) != 1 || ids[0] != next.ID {
+		t.Fatalf("expected student only in C2, got %v", ids)
+	}
+
+	history, err := env.Transfer.History(student.ID)
+	if err != nil || len(history) != 1 || history[0].Reason != "moved house" {
+		t.Fatalf("unexpected history: %+v, %v", history, err)
+	}
+}
+
+func TestTransfer_RollsBackOnBadClass(t *testing.T) {
+	env := setup(t)
+
+	s1, _ := env.School.Create("S1")
+	s2, _ := env.School.Create("S2")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	old, _ := env.Class.Create("C1", s1.ID, teacher.ID)
+	next, _ := env.Class.Create("C2", s2.ID, teacher.ID)
+	student, _ := env.Person.Create("Stu", "student")
+	_ = env.Class.AddStudentToClass(student.ID, old.ID)
+
+	// C1 belongs to the old school, so the whole transfer is undone
+	_, err := env.Transfer.Transfer(student.ID, s2.ID, "", []uint{next.ID, old.ID})
+	if err != ErrDifferentSchool {
+		t.Fatalf("expected ErrDifferentSchool, got %v", err)
+	}
+
+	_, m, _ := env.Person.WhoAmI(student.ID)
+	if ids := m.ClassIDs(); len(ids) != 1 || ids[0] != old.ID {
+		t.Fatalf("expected student still in C1, got %v", ids)
+	}
+	if history, _ := env.Transfer.History(student.ID); len(history) != 0 {
+		t.Fatalf("expected no history after rollback, got %d", len(history))
+	}
+	if err := env.Class.AddStudentToClass(student.ID, next.ID); err != ErrDifferentSchool {
+		t.Fatalf("expected student still bound to S1, got %v", err)
+	}
+}
+
+func TestEnroll_FullClassWaitlistsAndPromotes(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	class, _ := env.Class.Create("C1", s.ID, teacher.ID)
+	if err := env.Class.SetCapacity(class.ID, 1); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	a, _ := env.Person.Create("A", "student")
+	b, _ := env.Person.Create("B", "student")
+	c, _ := env.Person.Create("C", "student")
+
+	if pos, err := env.Class.Enroll(a.ID, class.ID); err != nil || pos != 0 {
+		t.Fatalf("expected seat for A, got %d, %v", pos, err)
+	}
+	if pos, err := env.Class.Enroll(b.ID, class.ID); err != nil || pos != 1 {
+		t.Fatalf("expected B first on waitlist, got %d, %v", pos, err)
+	}
+	if pos, err := env.Class.Enroll(c.ID, class.ID); err != nil || pos != 2 {
+		t.Fatalf("expected C second on waitlist, got %d, %v", pos, err)
+	}
+	if _, err := env.Class.Enroll(c.ID, class.ID); err != ErrAlreadyWaitlisted {
+		t.Fatalf("expected ErrAlreadyWaitlisted, got %v", err)
+	}
+
+	// A leaves, so B takes the seat and C moves up
+	if err := env.Class.RemoveStudentFromClass(a.ID, class.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	students, _ := env.Class.ListStudents(class.ID)
+	if len(students) != 1 || students[0].ID != b.ID {
+		t.Fatalf("expected B to be promoted, got %v", students)
+	}
+	if pos, err := env.Class.WaitlistPosition(c.ID, class.ID); err != nil || pos != 1 {
+		t.Fatalf("expected C first on waitlist, got %d, %v", pos, err)
+	}
+
+	if err := env.Class.LeaveWaitlist(c.ID, class.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if _, err := env.Class.WaitlistPosition(c.ID, class.ID); err != ErrNotWaitlisted {
+		t.Fatalf("expected ErrNotWaitlisted, got %v", err)
+	}
+}
+
+func TestWaitlist_ClashingStudentKeepsPlace(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	other, _ := env.Person.Create("T2", "teacher")
+	math, _ := env.Class.Create("Math", s.ID, teacher.ID)
+	art, _ := env.Class.Create("Art", s.ID, other.ID)
+	r1, _ := env.Timetable.CreateRoom(s.ID, "R1")
+	r2, _ := env.Timetable.CreateRoom(s.ID, "R2")
+	env.Timetable.AddSlot(math.ID, r1.ID, 1, "09:00", "10:00")
+	_ = env.Class.SetCapacity(math.ID, 1)
+
+	a, _ := env.Person.Create("A", "student")
+	b, _ := env.Person.Create("B", "student")
+	c, _ := env.Person.Create("C", "student")
+	for _, id := range []uint{a.ID, b.ID, c.ID} {
+		if _, err := env.Class.Enroll(id, math.ID); err != nil {
+			t.Fatalf("enroll %d: %v", id, err)
+		}
+	}
+
+	// B takes up Art at the same hour while waiting for Math
+	if _, err := env.Timetable.AddSlot(art.ID, r2.ID, 1, "09:00", "10:00"); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if _, err := env.Class.Enroll(b.ID, art.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	if err := env.Class.RemoveStudentFromClass(a.ID, math.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	students, _ := env.Class.ListStudents(math.ID)
+	if len(students) != 1 || students[0].ID != c.ID {
+		t.Fatalf("expected C to be promoted past B, got %v", students)
+	}
+	if pos, err := env.Class.WaitlistPosition(b.ID, math.ID); err != nil || pos != 1 {
+		t.Fatalf("expected B to stay first on the waitlist, got %d, %v", pos, err)
+	}
+}
+
+func TestWaitlist_BlockedStudentDoesNotHoldFreeSeat(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	other, _ := env.Person.Create("T2", "teacher")
+	math, _ := env.Class.Create("Math", s.ID, teacher.ID)
+	art, _ := env.Class.Create("Art", s.ID, other.ID)
+	r1, _ := env.Timetable.CreateRoom(s.ID, "R1")
+	r2, _ := env.Timetable.CreateRoom(s.ID, "R2")
+	env.Timetable.AddSlot(math.ID, r1.ID, 1, "09:00", "10:00")
+	env.Timetable.AddSlot(art.ID, r2.ID, 1, "09:00", "10:00")
+	_ = env.Class.SetCapacity(math.ID, 1)
+
+	a, _ := env.Person.Create("A", "student")
+	b, _ := env.Person.Create("B", "student")
+	d, _ := env.Person.Create("D", "student")
+	env.Class.Enroll(a.ID, math.ID)
+	if pos, err := env.Class.Enroll(b.ID, math.ID); err != nil || pos != 1 {
+		t.Fatalf("expected B first on waitlist, got %d, %v", pos, err)
+	}
+	if _, err := env.Class.Enroll(b.ID, art.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	// A leaves, B cannot take the seat, so it stays free for D
+	if err := env.Class.RemoveStudentFromClass(a.ID, math.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if pos, err := env.Class.Enroll(d.ID, math.ID); err != nil || pos != 0 {
+		t.Fatalf("expected a seat for D, got %d, %v", pos, err)
+	}
+	students, _ := env.Class.ListStudents(math.ID)
+	if len(students) != 1 || students[0].ID != d.ID {
+		t.Fatalf("expected D to be enrolled, got %v", students)
+	}
+	if pos, err := env.Class.WaitlistPosition(b.ID, math.ID); err != nil || pos != 1 {
+		t.Fatalf("expected B to stay first on the waitlist, got %d, %v", pos, err)
+	}
+}
+
+func TestSetCapacity_RaisingPromotesWaitlist(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	class, _ := env.Class.Create("C1", s.ID, teacher.ID)
+	_ = env.Class.SetCapacity(class.ID, 1)
+
+	for _, name := range []string{"A", "B", "C"} {
+		st, _ := env.Person.Create(name, "student")
+		if _, err := env.Class.Enroll(st.ID, class.ID); err != nil {
+			t.Fatalf("enroll %s: %v", name, err)
+		}
+	}
+
+	if _, err := env.Class.Update(class.ID, "", 0, new(uint)); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	students, _ := env.Class.ListStudents(class.ID)
+	if len(students) != 3 {
+		t.Fatalf("expected unlimited class to take everyone, got %d", len(students))
+	}
+	if waiting, _ := env.Class.Waitlist(class.ID); len(waiting) != 0 {
+		t.Fatalf("expected empty waitlist, got %d", len(waiting))
+	}
+}
+
+func TestTerms_ScopeClassesAndEnrollment(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	student, _ := env.Person.Create("Stu", "student")
+
+	now := time.Now()
+	last, err := env.Term.Create(s.ID, "Last year", now.AddDate(-1, 0, 0), now.AddDate(0, -1, 0))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	current, _ := env.Term.Create(s.ID, "This year", now.AddDate(0, -1, 0), now.AddDate(0, 6, 0))
+	if _, err := env.Term.Create(s.ID, "This year", now, now.AddDate(1, 0, 0)); err != ErrTermAlreadyExists {
+		t.Fatalf("expected ErrTermAlreadyExists, got %v", err)
+	}
+
+	old, err := env.Class.CreateInTerm("Old", s.ID, teacher.ID, last.ID, 0)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	fresh, err := env.Class.Create("Fresh", s.ID, teacher.ID)
+	if err != nil || fresh.TermID != current.ID {
+		t.Fatalf("expected class in current term, got %+v, %v", fresh, err)
+	}
+
+	if err := env.Class.AddStudentToClass(student.ID, old.ID); err != ErrTermClosed {
+		t.Fatalf("expected ErrTermClosed, got %v", err)
+	}
+	if err := env.Class.AddStudentToClass(student.ID, fresh.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	classes, _ := env.School.ListClasses(s.ID)
+	if len(classes) != 1 || classes[0].ID != fresh.ID {
+		t.Fatalf("expected only the current class, got %v", classes)
+	}
+	classes, _ = env.School.ListClassesInTerm(s.ID, last.ID)
+	if len(classes) != 1 || classes[0].ID != old.ID {
+		t.Fatalf("expected only last year's class, got %v", classes)
+	}
+
+	_, m, _ := env.Person.WhoAmI(teacher.ID)
+	if ids := m.ClassIDs(); len(ids) != 1 || ids[0] != fresh.ID {
+		t.Fatalf("expected current classes only, got %v", ids)
+	}
+	_, m, _ = env.Person.WhoAmIInTerm(teacher.ID, last.ID)
+	if ids := m.ClassIDs(); len(ids) != 1 || ids[0] != old.ID {
+		t.Fatalf("expected last year's classes only, got %v", ids)
+	}
+}
+
+func TestTimetable_RejectsDoubleBooking(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	t1, _ := env.Person.Create("T1", "teacher")
+	t2, _ := env.Person.Create("T2", "teacher")
+	math, _ := env.Class.Create("Math", s.ID, t1.ID)
+	art, _ := env.Class.Create("Art", s.ID, t2.ID)
+	r1, _ := env.Timetable.CreateRoom(s.ID, "R1")
+	r2, _ := env.Timetable.CreateRoom(s.ID, "R2")
+
+	if _, err := env.Timetable.CreateRoom(s.ID, "R1"); err != ErrRoomAlreadyExists {
+		t.Fatalf("expected ErrRoomAlreadyExists, got %v", err)
+	}
+	if _, err := env.Timetable.AddSlot(math.ID, r1.ID, 1, "10:00", "09:00"); err != ErrInvalidInput {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+
+	if _, err := env.Timetable.AddSlot(math.ID, r1.ID, 1, "9:00", "10:00"); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if _, err := env.Timetable.AddSlot(art.ID, r1.ID, 1, "09:30", "10:30"); err != ErrRoomConflict {
+		t.Fatalf("expected ErrRoomConflict, got %v", err)
+	}
+	// back to back is fine
+	artSlot, err := env.Timetable.AddSlot(art.ID, r1.ID, 1, "10:00", "11:00")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if _, err := env.Timetable.UpdateSlot(artSlot.ID, r2.ID, 1, "09:00", "10:00"); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	// T1 now teaches at 09:00 on Monday, same as Art
+	if err := env.Class.UpdateTeacher(art.ID, t1.ID); err != ErrTeacherConflict {
+		t.Fatalf("expected ErrTeacherConflict, got %v", err)
+	}
+
+	st, _ := env.Person.Create("A", "student")
+	if _, err := env.Class.Enroll(st.ID, math.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if _, err := env.Class.Enroll(st.ID, art.ID); err != ErrStudentConflict {
+		t.Fatalf("expected ErrStudentConflict, got %v", err)
+	}
+}
+
+func TestTimetable_PersonWeek(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	math, _ := env.Class.Create("Math", s.ID, teacher.ID)
+	room, _ := env.Timetable.CreateRoom(s.ID, "R1")
+	env.Timetable.AddSlot(math.ID, room.ID, 3, "13:00", "14:00")
+	env.Timetable.AddSlot(math.ID, room.ID, 1, "09:00", "10:00")
+
+	st, _ := env.Person.Create("A", "student")
+	if _, err := env.Class.Enroll(st.ID, math.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	week, err := env.Timetable.PersonTimetable(st.ID, 0)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(week) != 2 || week[0].Weekday != 1 || week[1].Weekday != 3 {
+		t.Fatalf("expected Monday then Wednesday, got %+v", week)
+	}
+	if week[0].Role != "student" || week[0].RoomName != "R1" || week[0].ClassName != "Math" {
+		t.Fatalf("unexpected entry %+v", week[0])
+	}
+
+	week, _ = env.Timetable.PersonTimetable(teacher.ID, 0)
+	if len(week) != 2 || week[0].Role != "teacher" {
+		t.Fatalf("expected teacher's week, got %+v", week)
+	}
+}
+
+func TestGradebook_WeightedFinalGrade(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	other, _ := env.Person.Create("T2", "teacher")
+	class, _ := env.Class.Create("C1", s.ID, teacher.ID)
+	a, _ := env.Person.Create("A", "student")
+	b, _ := env.Person.Create("B", "student")
+	if _, err := env.Class.Enroll(a.ID, class.ID); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	quiz, err := env.Gradebook.CreateAssessment(class.ID, "Quiz", 1, 10)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	exam, _ := env.Gradebook.CreateAssessment(class.ID, "Exam", 3, 50)
+	if _, err := env.Gradebook.CreateAssessment(class.ID, "Exam", 1, 10); err != ErrAssessmentAlreadyExists {
+		t.Fatalf("expected ErrAssessmentAlreadyExists, got %v", err)
+	}
+
+	if err := env.Gradebook.RecordScores(other.ID, quiz.ID, []ScoreInput{{StudentID: a.ID, Points: 5}}); err != ErrPermissionDenied {
+		t.Fatalf("expected ErrPermissionDenied, got %v", err)
+	}
+	if err := env.Gradebook.RecordScores(teacher.ID, quiz.ID, []ScoreInput{{StudentID: a.ID, Points: 11}}); err != ErrInvalidInput {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+	// B is not enrolled, so A's score is not saved either
+	err = env.Gradebook.RecordScores(teacher.ID, quiz.ID, []ScoreInput{{StudentID: a.ID, Points: 5}, {StudentID: b.ID, Points: 5}})
+	if err != ErrNotEnrolled {
+		t.Fatalf("expected ErrNotEnrolled, got %v", err)
+	}
+	grades, _ := env.Gradebook.StudentGrades(a.ID, 0)
+	if len(grades) != 1 || grades[0].FinalGrade != nil {
+		t.Fatalf("expected no grade yet, got %+v", grades)
+	}
+
+	if err := env.Gradebook.RecordScores(teacher.ID, quiz.ID, []ScoreInput{{StudentID: a.ID, Points: 5}}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if err := env.Gradebook.RecordScores(teacher.ID, exam.ID, []ScoreInput{{StudentID: a.ID, Points: 50}}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	grades, err = env.Gradebook.StudentGrades(a.ID, 0)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	// (1*0.5 + 3*1.0) / 4
+	if len(grades) != 1 || grades[0].FinalGrade == nil || *grades[0].FinalGrade != 87.5 {
+		t.Fatalf("expected final grade 87.5, got %+v", grades)
+	}
+	if len(grades[0].Assessments) != 2 || *grades[0].Assessments[0].Points != 5 {
+		t.Fatalf("unexpected assessments %+v", grades[0].Assessments)
+	}
+
+	if err := env.Class.Delete(class.ID, true); err != nil {
+		t.Fatalf("expected cascade delete to remove scores, got %v", err)
+	}
+}
+
+func TestAttendance_RatesAndLowAttendance(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	class, _ := env.Class.Create("C1", s.ID, teacher.ID)
+	a, _ := env.Person.Create("A", "student")
+	b, _ := env.Person.Create("B", "student")
+	outsider, _ := env.Person.Create("C", "student")
+	env.Class.Enroll(a.ID, class.ID)
+	env.Class.Enroll(b.ID, class.ID)
+
+	err := env.Attendance.Record(class.ID, "2026-03-02", []AttendanceInput{{StudentID: a.ID, Status: "present"}, {StudentID: outsider.ID, Status: "present"}})
+	if err != ErrNotEnrolled {
+		t.Fatalf("expected ErrNotEnrolled, got %v", err)
+	}
+	if err := env.Attendance.Record(class.ID, "2026-03-02", []AttendanceInput{{StudentID: a.ID, Status: "asleep"}}); err != ErrInvalidInput {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+	if err := env.Attendance.Record(class.ID, "03/02/2026", []AttendanceInput{{StudentID: a.ID, Status: "present"}}); err != ErrInvalidInput {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+
+	sessions := map[string][2]string{
+		"2026-03-02": {"present", "absent"},
+		"2026-03-03": {"late", "absent"},
+		"2026-03-04": {"absent", "present"},
+		"2026-03-05": {"excused", "present"},
+	}
+	for date, st := range sessions {
+		err := env.Attendance.Record(class.ID, date, []AttendanceInput{{StudentID: a.ID, Status: st[0]}, {StudentID: b.ID, Status: st[1]}})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	}
+	// correcting a record replaces it
+	if err := env.Attendance.Record(class.ID, "2026-03-04", []AttendanceInput{{StudentID: a.ID, Status: "present"}}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	rate, err := env.Attendance.Rate(class.ID, a.ID)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if rate.Present != 2 || rate.Late != 1 || rate.Excused != 1 || rate.Rate == nil || *rate.Rate != 1 {
+		t.Fatalf("unexpected rate for A %+v", rate)
+	}
+
+	low, err := env.Attendance.BelowThreshold(s.ID, 0, 0)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(low) != 1 || low[0].StudentID != b.ID || *low[0].Rate != 0.5 {
+		t.Fatalf("expected only B below threshold, got %+v", low)
+	}
+	if low, _ := env.Attendance.BelowThreshold(s.ID, 0, 0.4); len(low) != 0 {
+		t.Fatalf("expected nobody below 0.4, got %+v", low)
+	}
+}
+
+func TestAssignments_SubmitResubmitAndLateFlag(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	class, _ := env.Class.Create("C1", s.ID, teacher.ID)
+	a, _ := env.Person.Create("A", "student")
+	b, _ := env.Person.Create("B", "student")
+	outsider, _ := env.Person.Create("C", "student")
+	env.Class.Enroll(a.ID, class.ID)
+	env.Class.Enroll(b.ID, class.ID)
+
+	due := time.Date(2026, 3, 10, 23, 59, 0, 0, time.UTC)
+	hw, err := env.Assignment.Create(class.ID, "Essay", "500 words", due)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	env.Assignment.now = func() time.Time { return due.Add(-time.Hour) }
+	if _, err := env.Assignment.Submit(outsider.ID, hw.ID, "mine", ""); err != ErrNotEnrolled {
+		t.Fatalf("expected ErrNotEnrolled, got %v", err)
+	}
+	if _, err := env.Assignment.Resubmit(a.ID, hw.ID, "draft", ""); err != ErrNotSubmitted {
+		t.Fatalf("expected ErrNotSubmitted, got %v", err)
+	}
+	sub, err := env.Assignment.Submit(a.ID, hw.ID, "draft", "")
+	if err != nil || sub.Late {
+		t.Fatalf("expected on-time submission, got %+v, %v", sub, err)
+	}
+	if _, err := env.Assignment.Submit(a.ID, hw.ID, "again", ""); err != ErrAlreadySubmitted {
+		t.Fatalf("expected ErrAlreadySubmitted, got %v", err)
+	}
+
+	env.Assignment.now = func() time.Time { return due.Add(time.Hour) }
+	sub, err = env.Assignment.Resubmit(a.ID, hw.ID, "", "files/essay-v2.pdf")
+	if err != nil || !sub.Late || sub.Attempts != 2 || sub.FileRef != "files/essay-v2.pdf" || sub.Text != "" {
+		t.Fatalf("expected late second attempt, got %+v, %v", sub, err)
+	}
+
+	statuses, err := env.Assignment.Submissions(hw.ID)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("expected both enrolled students, got %+v", statuses)
+	}
+	for _, st := range statuses {
+		if want := st.StudentID == a.ID; st.Submitted != want {
+			t.Fatalf("unexpected status %+v", st)
+		}
+	}
+}
+
+func TestReportCard_CollectsAndRenders(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("Ms <Smith>", "teacher")
+	class, _ := env.Class.Create("Math", s.ID, teacher.ID)
+	st, _ := env.Person.Create("A", "student")
+	env.Class.Enroll(st.ID, class.ID)
+
+	quiz, _ := env.Gradebook.CreateAssessment(class.ID, "Quiz", 1, 10)
+	env.Gradebook.RecordScores(teacher.ID, quiz.ID, []ScoreInput{{StudentID: st.ID, Points: 9}})
+	env.Attendance.Record(class.ID, "2026-03-02", []AttendanceInput{{StudentID: st.ID, Status: "present"}})
+	env.Attendance.Record(class.ID, "2026-03-03", []AttendanceInput{{StudentID: st.ID, Status: "absent"}})
+	if err := env.ReportCard.Comment(teacher.ID, class.ID, st.ID, "Works hard"); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if err := env.ReportCard.Comment(teacher.ID, class.ID, teacher.ID, "Not a student here"); err != ErrNotEnrolled {
+		t.Fatalf("expected ErrNotEnrolled, got %v", err)
+	}
+
+	card, err := env.ReportCard.Build(st.ID, 0)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if card.School == nil || card.School.ID != s.ID || card.Term == nil || len(card.Classes) != 1 {
+		t.Fatalf("unexpected report card %+v", card)
+	}
+	c := card.Classes[0]
+	if c.TeacherName != teacher.Name || *c.FinalGrade != 90 || *c.AttendanceRate != 0.5 || c.Comment != "Works hard" {
+		t.Fatalf("unexpected class entry %+v", c)
+	}
+
+	text, err := card.Render(ReportFormatText)
+	if err != nil || !strings.Contains(text, "Grade:      90.0%") || !strings.Contains(text, "Attendance: 50.0%") {
+		t.Fatalf("unexpected text report %q, %v", text, err)
+	}
+	html, err := card.Render(ReportFormatHTML)
+	if err != nil || !strings.Contains(html, "Ms &lt;Smith&gt;") {
+		t.Fatalf("expected escaped teacher name in html report, got %q, %v", html, err)
+	}
+	if _, err := card.Render("pdf"); err != ErrInvalidInput {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+
+	if _, err := env.ReportCard.Build(teacher.ID, 0); err != ErrRoleMismatch {
+		t.Fatalf("expected ErrRoleMismatch, got %v", err)
+	}
+}
+
+func TestGuardian_LinkAndChildren(t *testing.T) {
+	env := setup(t)
+
+	s, _ := env.School.Create("S1")
+	teacher, _ := env.Person.Create("T1", "teacher")
+	class, _ := env.Class.Create("C1", s.ID, teacher.ID)
+	child, _ := env.Person.Create("Kid", "student")
+	env.Class.Enroll(child.ID, class.ID)
+	mum, _ := env.Person.Create("Mum", "guardian")
+
+	if _, err := env.Guardian.Link(teacher.ID, child.ID, "parent", "", false, false); err != ErrRoleMismatch {
+		t.Fatalf("expected ErrRoleMismatch, got %v", err)
+	}
+	if _, err := env.Guardian.Link(mum.ID, child.ID, "aunt", "", false, false); err != ErrInvalidInput {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+	g, err := env.Guardian.Link(mum.ID, child.ID, "parent", "", true, true)
+	if err != nil || g.PreferredContact != "email" || !g.EmergencyContact {
+		t.Fatalf("expected link with email default, got %+v, %v", g, err)
+	}
+	// linking again updates the details
+	if g, err = env.Guardian.Link(mum.ID, child.ID, "parent", "sms", false, true); err != nil || g.PreferredContact != "sms" || g.EmergencyContact {
+		t.Fatalf("expected updated link, got %+v, %v", g, err)
+	}
+
+	children, err := env.Guardian.Children(mum.ID, 0)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(children) != 1 || children[0].Person.ID != child.ID || len(children[0].ClassIDs) != 1 || children[0].ClassIDs[0] != class.ID {
+		t.Fatalf("unexpected children %+v", children)
+	}
+	guardians, _ := env.Guardian.Guardians(child.ID)
+	if len(guardians) != 1 || guardians[0].Person.Name != "Mum" || guardians[0].Relationship != "parent" {
+		t.Fatalf("unexpected guardians %+v", guardians)
+	}
+
+	if err := env.Person.Delete(mum.ID, false); err != nil {
+		t.Fatalf("expected guardian delete to drop links, got %v", err)
+	}
+	if err := env.Guardian.Unlink(mum.ID, child.ID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestLists_PaginateFilterAndSort(t *testing.T) {
+	env := setup(t)
+
+	for _, name := range []string{"Alpha", "Bravo", "Charlie", "Delta", "Echo"} {
+		env.School.Create(name)
+	}
+
+	first, err := env.School.List(repository.ListOptions{Limit: 2, Sort: "name"})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if first.Total != 5 || len(first.Items) != 2 || first.Items[0].Name != "Alpha" || first.NextCursor == "" {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+	var names []string
+	for page := first; ; {
+		for _, s := range page.Items {
+			names = append(names, s.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		page, err = env.School.List(repository.ListOptions{Limit: 2, Sort: "name", After: page.NextCursor})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	}
+	if strings.Join(names, ",") != "Alpha,Bravo,Charlie,Delta,Echo" {
+		t.Fatalf("expected every school once in order, got %v", names)
+	}
+
+	desc, _ := env.School.List(repository.ListOptions{Sort: "-name", NameContains: "a"})
+	if desc.Total != 4 || desc.Items[0].Name != "Delta" || desc.Items[3].Name != "Alpha" {
+		t.Fatalf("unexpected filtered page %+v", desc)
+	}
+
+	if _, err := env.School.List(repository.ListOptions{After: "not-a-cursor"}); err != ErrInvalidInput {
+		t.Fatalf("expected ErrInvalidInput for bad cursor, got %v", err)
+	}
+	if _, err := env.School.List(repository.ListOptions{Sort: "teacher"}); err != ErrInvalidInput {
+		t.Fatalf("expected ErrInvalidInput for bad sort, got %v", err)
+	}
+
+	s := first.Items[0]
+	teacher, _ := env.Person.Create("T1", "teacher")
+	other, _ := env.Person.Create("T2", "teacher")
+	env.Class.Create("Maths", s.ID, teacher.ID)
+	class, _ := env.Class.Create("Music", s.ID, other.ID)
+	classes, err := env.School.ListClassesPage(s.ID, 0, repository.ListOptions{TeacherID: other.ID})
+	if err != nil || classes.Total != 1 || classes.Items[0].ID != class.ID || classes.Items[0].Teacher.Name != "T2" {
+		t.Fatalf("expected only T2's class, got %+v, %v", classes, err)
+	}
+
+	for _, name := range []string{"Ann", "Ben", "Bea"} {
+		st, _ := env.Person.Create(name, "student")
+		env.Class.Enroll(st.ID, class.ID)
+	}
+	students, err := env.Class.ListStudentsPage(class.ID, repository.ListOptions{NameContains: "b", Sort: "-id", Limit: 1})
+	if err != nil || students.Total != 2 || len(students.Items) != 1 || students.Items[0].Name != "Bea" {
+		t.Fatalf("unexpected roster page %+v, %v", students, err)
+	}
+}
+
+func TestSearch_RankedTypedAndScoped(t *testing.T) {
+	env := setup(t)
+
+	north, _ := env.School.Create("Northfield Academy")
+	south, _ := env.School.Create("Southfield High")
+	teacher, _ := env.Person.Create("Jo Northcott", "teacher")
+	class, _ := env.Class.Create("North American History", north.ID, teacher.ID)
+	anna, _ := env.Person.Create("Anna Smith <3", "student")
+	env.Class.Enroll(anna.ID, class.ID)
+	other, _ := env.Person.Create("Annabel Smithers", "student")
+	env.Person.Create("Hannah Smith", "student")
+
+	results, err := env.Search.Search("ann smi", 0, 0)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected the two people whose names start with ann and smi, got %+v", results)
+	}
+	for _, r := range results {
+		if r.Type != "person" || (r.ID != anna.ID && r.ID != other.ID) {
+			t.Fatalf("unexpected result %+v", r)
+		}
+		if r.ID == anna.ID && (r.Snippet != "<mark>Ann</mark>a <mark>Smi</mark>th &lt;3" || r.SchoolID == nil || *r.SchoolID != north.ID) {
+			t.Fatalf("unexpected result for Anna %+v", r)
+		}
+	}
+
+	results, _ = env.Search.Search("north", 0, 0)
+	kinds := map[string]int{}
+	for _, r := range results {
+		kinds[r.Type]++
+	}
+	if len(results) != 3 || kinds["school"] != 1 || kinds["class"] != 1 || kinds["person"] != 1 {
+		t.Fatalf("expected a school, a class and a person, got %+v", results)
+	}
+
+	if results, _ = env.Search.Search("anna", south.ID, 0); len(results) != 0 {
+		t.Fatalf("expected no results at the other school, got %+v", results)
+	}
+	if results, _ = env.Search.Search("jo", north.ID, 0); len(results) != 1 || results[0].ID != teacher.ID {
+		t.Fatalf("expected the school's teacher, got %+v", results)
+	}
+
+	env.Person.Update(other.ID, "Bel Jones", nil)
+	if results, _ = env.Search.Search("annabel", 0, 0); len(results) != 0 {
+		t.Fatalf("expected renamed person to drop out, got %+v", results)
+	}
+	env.School.Delete(south.ID, false)
+	if results, _ = env.Search.Search("southfield", 0, 0); len(results) != 0 {
+		t.Fatalf("expected deleted school to drop out, got %+v", results)
+	}
+
+	if _, err := env.Search.Search(" ?! ", 0, 0); err != ErrInvalidInput {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+	if _, err := env.Search.Search("anna", 999, 0); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestImport_DryRunReportsRowsAndRealRunIsAtomic(t *testing.T) {
+	env := setup(t)
+
+	bad := strings.Join([]string{
+		"type,ref,name,roles,school,teacher,class,student",
+		"school,s1,Northfield,,,,,",
+		"person,t1,Jo,teacher,,,,",
+		"person,st1,Ann,student,,,,",
+		"person,x,Nobody,wizard,,,,",
+		"class,c1,Maths,,s1,st1,,",
+		"class,c2,Music,,s1,t1,,",
+		"enrollment,,,,,,c2,st1",
+		"enrollment,,,,,,c9,st1",
+		"party,,Cake,,,,,",
+	}, "\n")
+
+	report, err := env.Import.Import(strings.NewReader(bad), true)
+	if err != nil {
+		t.Fatalf("expected dry run to succeed, got %v", err)
+	}
+	var lines []int
+	for _, e := range report.Errors {
+		lines = append(lines, e.Line)
+	}
+	if fmt.Sprint(lines) != "[5 6 9 10]" || report.Rows != 9 {
+		t.Fatalf("expected errors on lines 5, 6, 9 and 10, got %+v", report)
+	}
+	if report.Created[ImportClass] != 1 || report.Created[ImportEnrollment] != 1 {
+		t.Fatalf("expected the good rows to be counted, got %+v", report.Created)
+	}
+	if page, _ := env.School.List(repository.ListOptions{}); page.Total != 0 {
+		t.Fatalf("expected dry run to leave no schools, got %d", page.Total)
+	}
+
+	if report, err = env.Import.Import(strings.NewReader(bad), false); err != ErrImportFailed || len(report.Errors) != 4 {
+		t.Fatalf("expected ErrImportFailed with 4 row errors, got %+v, %v", report, err)
+	}
+	if page, _ := env.School.List(repository.ListOptions{}); page.Total != 0 {
+		t.Fatalf("expected failed import to leave no schools, got %d", page.Total)
+	}
+
+	good := "type,name,ref,roles,school,teacher,class,student\n" +
+		"school,Northfield,s1,,,,,\n" +
+		"person,Jo,t1,teacher,,,,\n" +
+		"person,Ann,st1,student;guardian,,,,\n" +
+		"class,Music,c1,,s1,t1,,\n" +
+		"enrollment,,,,,,c1,st1\n"
+	report, err = env.Import.Import(strings.NewReader(good), false)
+	if err != nil || len(report.Errors) != 0 || report.Created[ImportPerson] != 2 {
+		t.Fatalf("expected a clean import, got %+v, %v", report, err)
+	}
+	page, _ := env.School.List(repository.ListOptions{})
+	classes, _ := env.School.ListClasses(page.Items[0].ID)
+	students, _ := env.Class.ListStudents(classes[0].ID)
+	if len(classes) != 1 || len(students) != 1 || students[0].Name != "Ann" {
+		t.Fatalf("expected the imported class and student, got %+v %+v", classes, students)
+	}
+
+	// existing records are referred to by id
+	more := fmt.Sprintf("type,name,school,teacher\nclass,Art,%d,%d\n", page.Items[0].ID, classes[0].TeacherID)
+	if report, err = env.Import.Import(strings.NewReader(more), false); err != nil {
+		t.Fatalf("expected class on existing school, got %+v, %v", report, err)
+	}
+
+	if _, err := env.Import.Import(strings.NewReader(""), true); err != ErrInvalidInput {
+		t.Fatalf("expected ErrInvalidInput for empty input, got %v", err)
+	}
+	report, _ = env.Import.Import(strings.NewReader("name,colour\nx,y\n"), true)
+	if len(report.Errors) != 2 || report.Errors[0].Line != 1 {
+		t.Fatalf("expected header errors, got %+v", report)
+	}
+}
+
+func TestExport_FormatsColumnsAndFilters(t *testing.T) {
+	env := setup(t)
+
+	s1, _ := env.School.Create("S1")
+	s2, _ := env.School.Create("S2")
+	teacher, _ := env.Person.Create("Jo, Teacher", "teacher")
+	class, _ := env.Class.Create("Maths", s1.ID, teacher.ID)
+	other, _ := env.Class.Create("Art", s2.ID, teacher.ID)
+	ann, _ := env.Person.Create("Ann", "student")
+	env.Class.Enroll(ann.ID, class.ID)
+	bob, _ := env.Person.Create("Bob", "student")
+	env.Class.Enroll(bob.ID, other.ID)
+
+	var b strings.Builder
+	opts := ExportOptions{Tables: []string{ExportPeople}, Columns: []string{"name", "id"}, SchoolID: s1.ID}
+	if err := env.Export.Export(&b, ExportFormatCSV, opts); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	want := fmt.Sprintf("name,id\n\"Jo, Teacher\",%d\nAnn,%d\n", teacher.ID, ann.ID)
+	if b.String() != want {
+		t.Fatalf("expected %q, got %q", want, b.String())
+	}
+
+	b.Reset()
+	opts = ExportOptions{Columns: []string{"enrollments.student_name", "schools.name"}, SchoolID: s1.ID, Role: "student"}
+	if err := env.Export.Export(&b, ExportFormatNDJSON, opts); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 4 || lines[0] != `{"table":"schools","name":"S1"}` || lines[3] != `{"table":"enrollments","student_name":"Ann"}` {
+		t.Fatalf("unexpected ndjson %q", lines)
+	}
+	if !strings.HasPrefix(lines[1], fmt.Sprintf(`{"table":"classes","id":%d,"name":"Maths","school_id":%d`, class.ID, s1.ID)) ||
+		!strings.HasPrefix(lines[2], fmt.Sprintf(`{"table":"people","id":%d,"name":"Ann","roles":"student"`, ann.ID)) {
+		t.Fatalf("unexpected ndjson %q", lines)
+	}
+
+	var first, second bytes.Buffer
+	env.Export.Export(&first, ExportFormatXLSX, ExportOptions{})
+	env.Export.Export(&second, ExportFormatXLSX, ExportOptions{})
+	if first.Len() == 0 || !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Fatalf("expected identical workbooks for the same data")
+	}
+	zr, err := zip.NewReader(bytes.NewReader(first.Bytes()), int64(first.Len()))
+	if err != nil {
+		t.Fatalf("expected a zip, got %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if !slices.Contains(names, "xl/worksheets/sheet4.xml") || !slices.Contains(names, "[Content_Types].xml") {
+		t.Fatalf("expected a sheet per table, got %v", names)
+	}
+
+	for _, bad := range []struct {
+		format string
+		opts   ExportOptions
+		err    error
+	}{
+		{ExportFormatCSV, ExportOptions{}, ErrInvalidInput},
+		{"pdf", ExportOptions{}, ErrInvalidInput},
+		{ExportFormatNDJSON, ExportOptions{Tables: []string{"rooms"}}, ErrInvalidInput},
+		{ExportFormatNDJSON, ExportOptions{Columns: []string{"people.capacity"}}, ErrInvalidInput},
+		{ExportFormatNDJSON, ExportOptions{Role: "wizard"}, ErrInvalidInput},
+		{ExportFormatNDJSON, ExportOptions{SchoolID: 999}, ErrNotFound},
+	} {
+		if err := env.Export.Export(io.Discard, bad.format, bad.opts); err != bad.err {
+			t.Fatalf("%s %+v: expected %v, got %v", bad.format, bad.opts, bad.err, err)
+		}
+	}
+}
+
+func TestExport_CSVKeepsFormulasAsText(t *testing.T) {
+	env := setup(t)
+
+	var ids []uint
+	for _, name := range []string{"=1+1", "+1", "-1", "@SUM(A1)", "Ann"} {
+		p, _ := env.Person.Create(name, "student")
+		ids = append(ids, p.ID)
+	}
+
+	var b strings.Builder
+	if err := env.Export.Export(&b, ExportFormatCSV, ExportOptions{Tables: []string{ExportPeople}, Columns: []string{"id", "name"}}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	want := fmt.Sprintf("id,name\n%d,'=1+1\n%d,'+1\n%d,'-1\n%d,'@SUM(A1)\n%d,Ann\n", ids[0], ids[1], ids[2], ids[3], ids[4])
+	if b.String() != want {
+		t.Fatalf("expected %q, got %q", want, b.String())
+	}
+}
+
+func TestExport_MaxBytes(t *testing.T) {
+	env := setup(t)
+
+	env.School.Create("S1")
+	for _, format := range []string{ExportFormatCSV, ExportFormatNDJSON, ExportFormatXLSX} {
+		opts := ExportOptions{Tables: []string{ExportSchools}, MaxBytes: 16}
+		if err := env.Export.Export(io.Discard, format, opts); err != ErrExportTooLarge {
+			t.Fatalf("%s: expected ErrExportTooLarge, got %v", format, err)
+		}
+		opts.MaxBytes = 1 << 20
+		if err := env.Export.Export(io.Discard, format, opts); err != nil {
+			t.Fatalf("%s: expected nil, got %v", format, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import "OldSchool/internal/repository"
+
+// Services groups every service wired against one set of repositories.
+type Services struct {
+	School     *SchoolService
+	Person     *PersonService
+	Class      *ClassService
+	Auth       *AuthService
+	Transfer   *TransferService
+	Term       *TermService
+	Timetable  *TimetableService
+	Gradebook  *GradebookService
+	Attendance *AttendanceService
+	Assignment *AssignmentService
+	ReportCard *ReportCardService
+	Guardian   *GuardianService
+	Search     *SearchService
+	Import     *ImportService
+	Export     *ExportService
+
+	UnitOfWork UnitOfWork
+}
+
+func NewServices(r repository.Repos) *Services {
+	person := NewPersonService(r.Person, r.Class, r.Enrollment, r.UnitOfWork)
+	return &Services{
+		School:     NewSchoolService(r.School, r.Class, r.Term, r.UnitOfWork),
+		Person:     person,
+		Class:      NewClassService(r.Class, r.Person, r.UnitOfWork, r.Enrollment, r.Waitlist),
+		Auth:       NewAuthService(r.Person, r.Session, DefaultSessionTTL),
+		Transfer:   NewTransferService(r.Transfer, r.Person, r.UnitOfWork),
+		Term:       NewTermService(r.Term, r.School),
+		Timetable:  NewTimetableService(r.Room, r.Slot, r.Class, r.Enrollment, r.School, r.Person, r.UnitOfWork),
+		Gradebook:  NewGradebookService(r.Assessment, r.Score, r.Class, r.Enrollment, r.Person, r.UnitOfWork),
+		Attendance: NewAttendanceService(r.Attendance, r.Class, r.School, r.Person, r.UnitOfWork),
+		Assignment: NewAssignmentService(r.Assignment, r.Submission, r.Class, r.Enrollment, r.UnitOfWork),
+		ReportCard: NewReportCardService(person, r.School, r.Term, r.Class, r.Person, r.Assessment, r.Score, r.Attendance, r.Comment, r.UnitOfWork),
+		Guardian:   NewGuardianService(r.Guardian, r.Person, person, r.UnitOfWork),
+		Search:     NewSearchService(r.Search, r.School),
+		Import:     NewImportService(r.UnitOfWork),
+		Export:     NewExportService(r.Export, r.School),
+		UnitOfWork: r.UnitOfWork,
+	}
+}
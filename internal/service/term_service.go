@@ -0,0 +1,118 @@
+package service
+
+import (
+	"OldSchool/internal/repository"
+	"OldSchool/internal/repository/models"
+	"strings"
+	"time"
+)
+
+type TermRepo interface {
+	Create(schoolID uint, name string, startsAt, endsAt time.Time) (*models.Term, error)
+	GetByID(id uint) (*models.Term, error)
+	ListBySchoolID(schoolID uint) ([]models.Term, error)
+	Current(schoolID uint, at time.Time) (*models.Term, error)
+}
+
+type TermService struct {
+	termRepo   TermRepo
+	schoolRepo SchoolRepo
+	now        func() time.Time
+}
+
+func NewTermService(termRepo TermRepo, schoolRepo SchoolRepo) *TermService {
+	return &TermService{
+		termRepo:   termRepo,
+		schoolRepo: schoolRepo,
+		now:        time.Now,
+	}
+}
+
+func (ts *TermService) Create(schoolID uint, name string, startsAt, endsAt time.Time) (*models.Term, error) {
+	name = strings.TrimSpace(name)
+	if schoolID == 0 || name == "" || !startsAt.Before(endsAt) {
+		return nil, ErrInvalidInput
+	}
+
+	s, err := ts.schoolRepo.GetByID(schoolID)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, ErrNotFound
+	}
+
+	created, err := ts.termRepo.Create(schoolID, name, startsAt, endsAt)
+	if err != nil {
+		if isUniqueConstraintErr(err) {
+			return nil, ErrTermAlreadyExists
+		}
+		return nil, err
+	}
+	return created, nil
+}
+
+func (ts *TermService) List(schoolID uint) ([]models.Term, error) {
+	if schoolID == 0 {
+		return nil, ErrInvalidInput
+	}
+	s, err := ts.schoolRepo.GetByID(schoolID)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, ErrNotFound
+	}
+	return ts.termRepo.ListBySchoolID(schoolID)
+}
+
+// Current returns the school's term that is open now.
+func (ts *TermService) Current(schoolID uint) (*models.Term, error) {
+	if schoolID == 0 {
+		return nil, ErrInvalidInput
+	}
+	t, err := ts.termRepo.Current(schoolID, ts.now())
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrNoCurrentTerm
+	}
+	return t, nil
+}
+
+// resolveTerm picks the term a new class of the school goes into: termID
+// when given, otherwise the school's current term. A school that has no
+// terms at all gets its default term.
+func resolveTerm(r repository.Repos, schoolID, termID uint, at time.Time) (*models.Term, error) {
+	if termID != 0 {
+		t, err := r.Term.GetByID(termID)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return nil, ErrNotFound
+		}
+		if t.SchoolID != schoolID {
+			return nil, ErrInvalidInput
+		}
+		return t, nil
+	}
+
+	n, err := r.Term.CountBySchoolID(schoolID)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return r.Term.GetOrCreateDefault(schoolID)
+	}
+
+	t, err := r.Term.Current(schoolID, at)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrNoCurrentTerm
+	}
+	return t, nil
+}
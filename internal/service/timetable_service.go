@@ -0,0 +1,394 @@
+package service
+
+import (
+	"OldSchool/internal/repository"
+	"OldSchool/internal/repository/models"
+	"strings"
+	"time"
+)
+
+type RoomRepo interface {
+	ListBySchoolID(schoolID uint) ([]models.Room, error)
+}
+
+type SlotRepo interface {
+	ListByClassID(classID uint) ([]models.Slot, error)
+	ListByClassIDs(classIDs []uint) ([]models.Slot, error)
+}
+
+type ClassRepoForTimetable interface {
+	GetByID(id uint) (*models.Class, error)
+	ListIDsByTeacherID(teacherID uint) ([]uint, error)
+	FilterIDsByTermID(ids []uint, termID uint) ([]uint, error)
+	FilterIDsByOpenTerm(ids []uint, at time.Time) ([]uint, error)
+}
+
+type TimetableService struct {
+	roomRepo       RoomRepo
+	slotRepo       SlotRepo
+	classRepo      ClassRepoForTimetable
+	enrollmentRepo EnrollmentRepoForWhoAmI
+	schoolRepo     SchoolRepo
+	personRepo     PersonRepo
+	uow            UnitOfWork
+	now            func() time.Time
+}
+
+func NewTimetableService(roomRepo RoomRepo, slotRepo SlotRepo, classRepo ClassRepoForTimetable, enrollmentRepo EnrollmentRepoForWhoAmI, schoolRepo SchoolRepo, personRepo PersonRepo, uow UnitOfWork) *TimetableService {
+	return &TimetableService{
+		roomRepo:       roomRepo,
+		slotRepo:       slotRepo,
+		classRepo:      classRepo,
+		enrollmentRepo: enrollmentRepo,
+		schoolRepo:     schoolRepo,
+		personRepo:     personRepo,
+		uow:            uow,
+		now:            time.Now,
+	}
+}
+
+// TimetableEntry is one weekly meeting in a person's timetable.
+type TimetableEntry struct {
+	SlotID    uint   `json:"slot_id"`
+	ClassID   uint   `json:"class_id"`
+	ClassName string `json:"class_name"`
+	Role      string `json:"role"`
+	RoomID    uint   `json:"room_id"`
+	RoomName  string `json:"room_name"`
+	Weekday   int    `json:"weekday"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+// clockTime checks an "HH:MM" time and returns it zero padded.
+func clockTime(s string) (string, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return "", ErrInvalidInput
+	}
+	return t.Format("15:04"), nil
+}
+
+func validSlot(weekday int, start, end string) (string, string, error) {
+	if weekday < int(time.Sunday) || weekday > int(time.Saturday) {
+		return "", "", ErrInvalidInput
+	}
+	start, err := clockTime(start)
+	if err != nil {
+		return "", "", err
+	}
+	end, err = clockTime(end)
+	if err != nil {
+		return "", "", err
+	}
+	if start >= end {
+		return "", "", ErrInvalidInput
+	}
+	return start, end, nil
+}
+
+// checkPersonFree fails with conflict when any slot of cl clashes with
+// another class the person teaches or attends.
+func checkPersonFree(r repository.Repos, personID uint, cl *models.Class, conflict error) error {
+	term, err := r.Term.GetByID(cl.TermID)
+	if err != nil || term == nil {
+		return err
+	}
+	slots, err := r.Slot.ListByClassID(cl.ID)
+	if err != nil {
+		return err
+	}
+	for _, s := range slots {
+		busy, err := r.Slot.PersonBooked(personID, repository.Clash{
+			Weekday:        s.Weekday,
+			Start:          s.StartTime,
+			End:            s.EndTime,
+			TermStart:      term.StartsAt,
+			TermEnd:        term.EndsAt,
+			ExcludeClassID: cl.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if busy {
+			return conflict
+		}
+	}
+	return nil
+}
+
+// checkSlotFree fails when putting the class in the room at the given time
+// would double-book the room, the teacher or any enrolled student.
+func checkSlotFree(r repository.Repos, cl *models.Class, roomID uint, slotID uint, weekday int, start, end string) error {
+	term, err := r.Term.GetByID(cl.TermID)
+	if err != nil {
+		return err
+	}
+	if term == nil {
+		return ErrNotFound
+	}
+
+	clash := repository.Clash{
+		Weekday:       weekday,
+		Start:         start,
+		End:           end,
+		TermStart:     term.StartsAt,
+		TermEnd:       term.EndsAt,
+		ExcludeSlotID: slotID,
+	}
+	booked, err := r.Slot.RoomBooked(roomID, clash)
+	if err != nil {
+		return err
+	}
+	if booked {
+		return ErrRoomConflict
+	}
+
+	clash.ExcludeClassID = cl.ID
+	busy, err := r.Slot.PersonBooked(cl.TeacherID, clash)
+	if err != nil {
+		return err
+	}
+	if busy {
+		return ErrTeacherConflict
+	}
+
+	students, err := r.Enrollment.ListStudentsByClassID(cl.ID)
+	if err != nil {
+		return err
+	}
+	for _, st := range students {
+		busy, err := r.Slot.PersonBooked(st.ID, clash)
+		if err != nil {
+			return err
+		}
+		if busy {
+			return ErrStudentConflict
+		}
+	}
+	return nil
+}
+
+// slotTarget loads the class and room for a slot and checks they belong to
+// the same school.
+func slotTarget(r repository.Repos, classID, roomID uint) (*models.Class, error) {
+	cl, err := r.Class.GetByID(classID)
+	if err != nil {
+		return nil, err
+	}
+	if cl == nil {
+		return nil, ErrNotFound
+	}
+	room, err := r.Room.GetByID(roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, ErrNotFound
+	}
+	if room.SchoolID != cl.SchoolID {
+		return nil, ErrInvalidInput
+	}
+	return cl, nil
+}
+
+func (ts *TimetableService) CreateRoom(schoolID uint, name string) (*models.Room, error) {
+	name = strings.TrimSpace(name)
+	if schoolID == 0 || name == "" {
+		return nil, ErrInvalidInput
+	}
+
+	var created *models.Room
+	err := ts.uow.WithinTx(func(r repository.Repos) error {
+		s, err := r.School.GetByID(schoolID)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return ErrNotFound
+		}
+		created, err = r.Room.Create(schoolID, name)
+		if isUniqueConstraintErr(err) {
+			return ErrRoomAlreadyExists
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (ts *TimetableService) ListRooms(schoolID uint) ([]models.Room, error) {
+	if schoolID == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	s, err := ts.schoolRepo.GetByID(schoolID)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, ErrNotFound
+	}
+	return ts.roomRepo.ListBySchoolID(schoolID)
+}
+
+// AddSlot schedules a weekly meeting of the class in one of its school's
+// rooms.
+func (ts *TimetableService) AddSlot(classID, roomID uint, weekday int, start, end string) (*models.Slot, error) {
+	if classID == 0 || roomID == 0 {
+		return nil, ErrInvalidInput
+	}
+	start, end, err := validSlot(weekday, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var created *models.Slot
+	err = ts.uow.WithinTx(func(r repository.Repos) error {
+		cl, err := slotTarget(r, classID, roomID)
+		if err != nil {
+			return err
+		}
+		if err := checkSlotFree(r, cl, roomID, 0, weekday, start, end); err != nil {
+			return err
+		}
+		created, err = r.Slot.Create(classID, roomID, weekday, start, end)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateSlot moves a meeting to another room or time.
+func (ts *TimetableService) UpdateSlot(slotID, roomID uint, weekday int, start, end string) (*models.Slot, error) {
+	if slotID == 0 || roomID == 0 {
+		return nil, ErrInvalidInput
+	}
+	start, end, err := validSlot(weekday, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *models.Slot
+	err = ts.uow.WithinTx(func(r repository.Repos) error {
+		s, err := r.Slot.GetByID(slotID)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return ErrNotFound
+		}
+		cl, err := slotTarget(r, s.ClassID, roomID)
+		if err != nil {
+			return err
+		}
+		if err := checkSlotFree(r, cl, roomID, slotID, weekday, start, end); err != nil {
+			return err
+		}
+		if err := r.Slot.Update(slotID, roomID, weekday, start, end); err != nil {
+			return err
+		}
+		updated, err = r.Slot.GetByID(slotID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (ts *TimetableService) DeleteSlot(slotID uint) error {
+	if slotID == 0 {
+		return ErrInvalidInput
+	}
+
+	return ts.uow.WithinTx(func(r repository.Repos) error {
+		s, err := r.Slot.GetByID(slotID)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return ErrNotFound
+		}
+		return r.Slot.Delete(slotID)
+	})
+}
+
+func (ts *TimetableService) ClassSlots(classID uint) ([]models.Slot, error) {
+	if classID == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	cl, err := ts.classRepo.GetByID(classID)
+	if err != nil {
+		return nil, err
+	}
+	if cl == nil {
+		return nil, ErrNotFound
+	}
+	return ts.slotRepo.ListByClassID(classID)
+}
+
+// PersonTimetable returns the person's week in the given term: every meeting
+// of a class they teach or attend. A termID of 0 means the terms open now.
+func (ts *TimetableService) PersonTimetable(personID uint, termID uint) ([]TimetableEntry, error) {
+	if personID == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	p, err := ts.personRepo.GetByID(personID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrNotFound
+	}
+
+	taught, err := ts.classRepo.ListIDsByTeacherID(personID)
+	if err != nil {
+		return nil, err
+	}
+	enrolled, err := ts.enrollmentRepo.ListClassIDsByStudentID(personID)
+	if err != nil {
+		return nil, err
+	}
+
+	classIDs := append(taught, enrolled...)
+	if termID != 0 {
+		classIDs, err = ts.classRepo.FilterIDsByTermID(classIDs, termID)
+	} else {
+		classIDs, err = ts.classRepo.FilterIDsByOpenTerm(classIDs, ts.now())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	slots, err := ts.slotRepo.ListByClassIDs(classIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []TimetableEntry{}
+	for _, s := range slots {
+		role := models.RoleStudent
+		if s.Class.TeacherID == personID {
+			role = models.RoleTeacher
+		}
+		entries = append(entries, TimetableEntry{
+			SlotID:    s.ID,
+			ClassID:   s.ClassID,
+			ClassName: s.Class.Name,
+			Role:      role,
+			RoomID:    s.RoomID,
+			RoomName:  s.Room.Name,
+			Weekday:   s.Weekday,
+			StartTime: s.StartTime,
+			EndTime:   s.EndTime,
+		})
+	}
+	return entries, nil
+}
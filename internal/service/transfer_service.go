@@ -0,0 +1,122 @@
+package service
+
+import (
+	"OldSchool/internal/repository"
+	"OldSchool/internal/repository/models"
+	"strings"
+	"time"
+)
+
+type TransferRepo interface {
+	ListByStudentID(studentID uint) ([]models.Transfer, error)
+}
+
+type TransferService struct {
+	transferRepo TransferRepo
+	personRepo   PersonRepo
+	uow          UnitOfWork
+	now          func() time.Time
+}
+
+func NewTransferService(transferRepo TransferRepo, personRepo PersonRepo, uow UnitOfWork) *TransferService {
+	return &TransferService{
+		transferRepo: transferRepo,
+		personRepo:   personRepo,
+		uow:          uow,
+		now:          time.Now,
+	}
+}
+
+// Transfer moves a student to another school in one transaction: they leave
+// every class and waitlist at their old school, the move is recorded, and
+// they join each of classIDs, which must all belong to the new school. Full
+// classes put them on the waitlist instead. Any failure leaves the student
+// where they were.
+func (ts *TransferService) Transfer(studentID, toSchoolID uint, reason string, classIDs []uint) (*models.Transfer, error) {
+	reason = strings.TrimSpace(reason)
+	if studentID == 0 || toSchoolID == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	var transfer *models.Transfer
+	err := ts.uow.WithinTx(func(r repository.Repos) error {
+		st, err := r.Person.GetByID(studentID)
+		if err != nil {
+			return err
+		}
+		if st == nil {
+			return ErrNotFound
+		}
+		if !st.HasRole(models.RoleStudent) {
+			return ErrRoleMismatch
+		}
+
+		school, err := r.School.GetByID(toSchoolID)
+		if err != nil {
+			return err
+		}
+		if school == nil {
+			return ErrNotFound
+		}
+		if st.StudentSchoolID != nil && *st.StudentSchoolID == toSchoolID {
+			return ErrInvalidInput
+		}
+
+		left, err := r.Enrollment.ListClassIDsByStudentID(studentID)
+		if err != nil {
+			return err
+		}
+		if err := r.Enrollment.DeleteByStudentID(studentID); err != nil {
+			return err
+		}
+		if err := r.Waitlist.DeleteByStudentID(studentID); err != nil {
+			return err
+		}
+		for _, classID := range left {
+			if err := promoteWaitlist(r, classID, ts.now()); err != nil {
+				return err
+			}
+		}
+		if err := r.Person.UpdateStudentSchoolID(studentID, toSchoolID); err != nil {
+			return err
+		}
+		transfer, err = r.Transfer.Create(studentID, st.StudentSchoolID, toSchoolID, reason, ts.now())
+		if err != nil {
+			return err
+		}
+		st.StudentSchoolID = &toSchoolID
+
+		for _, classID := range classIDs {
+			cl, err := r.Class.GetByID(classID)
+			if err != nil {
+				return err
+			}
+			if cl == nil {
+				return ErrNotFound
+			}
+			if _, err := enrollOrWaitlist(r, st, cl, ts.now()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// History lists a student's transfers, oldest first.
+func (ts *TransferService) History(studentID uint) ([]models.Transfer, error) {
+	if studentID == 0 {
+		return nil, ErrInvalidInput
+	}
+	p, err := ts.personRepo.GetByID(studentID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrNotFound
+	}
+	return ts.transferRepo.ListByStudentID(studentID)
+}
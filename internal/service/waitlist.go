@@ -0,0 +1,233 @@
+package service
+
+import (
+	"OldSchool/internal/repository"
+	"OldSchool/internal/repository/models"
+	"errors"
+	"time"
+)
+
+// enrollOrWaitlist gives st a seat in cl, or queues them when the class is
+// full or others are already waiting. It returns the waitlist position, 0
+// for a seat. Either way the class's term must be open at the given time.
+func enrollOrWaitlist(r repository.Repos, st *models.Person, cl *models.Class, at time.Time) (int, error) {
+	// a teaching assistant may study, but not in a class they teach
+	if cl.TeacherID == st.ID {
+		return 0, ErrRoleMismatch
+	}
+
+	exists, err := r.Enrollment.Exists(cl.ID, st.ID)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, ErrDuplicateEnrollment
+	}
+
+	waiting, err := r.Waitlist.Position(cl.ID, st.ID)
+	if err != nil {
+		return 0, err
+	}
+	if waiting > 0 {
+		return 0, ErrAlreadyWaitlisted
+	}
+
+	t, err := r.Term.GetByID(cl.TermID)
+	if err != nil {
+		return 0, err
+	}
+	if t == nil || !t.IsOpen(at) {
+		return 0, ErrTermClosed
+	}
+	if err := checkPersonFree(r, st.ID, cl, ErrStudentConflict); err != nil {
+		return 0, err
+	}
+
+	if st.StudentSchoolID != nil && *st.StudentSchoolID != cl.SchoolID {
+		return 0, ErrDifferentSchool
+	}
+
+	// seats the queue could fill go to it first
+	if err := promoteWaitlist(r, cl.ID, at); err != nil {
+		return 0, err
+	}
+	open, err := hasOpenSeat(r, cl)
+	if err != nil {
+		return 0, err
+	}
+	if !open {
+		if _, err := r.Waitlist.Add(cl.ID, st.ID); err != nil {
+			return 0, err
+		}
+		return r.Waitlist.Position(cl.ID, st.ID)
+	}
+
+	if st.StudentSchoolID == nil {
+		if err := r.Person.UpdateStudentSchoolID(st.ID, cl.SchoolID); err != nil {
+			return 0, err
+		}
+	}
+	_, err = r.Enrollment.Add(cl.ID, st.ID)
+	return 0, err
+}
+
+// hasOpenSeat reports whether a newcomer may take a seat right away. It is
+// only asked after promoteWaitlist, so the students still waiting cannot
+// take a free seat and do not hold it.
+func hasOpenSeat(r repository.Repos, cl *models.Class) (bool, error) {
+	if cl.Capacity == nil {
+		return true, nil
+	}
+	enrolled, err := r.Enrollment.CountByClassID(cl.ID)
+	if err != nil {
+		return false, err
+	}
+	return enrolled < int64(*cl.Capacity), nil
+}
+
+// promoteWaitlist fills free seats in the class from the head of its
+// waitlist while its term is open. Students who have since been bound to
+// another school or whose timetable now clashes with the class cannot take
+// the seat; they are passed over but keep their place in the queue.
+func promoteWaitlist(r repository.Repos, classID uint, at time.Time) error {
+	cl, err := r.Class.GetByID(classID)
+	if err != nil || cl == nil {
+		return err
+	}
+	t, err := r.Term.GetByID(cl.TermID)
+	if err != nil || t == nil || !t.IsOpen(at) {
+		return err
+	}
+
+	waiting, err := r.Waitlist.ListByClassID(classID)
+	if err != nil {
+		return err
+	}
+	for _, next := range waiting {
+		if cl.Capacity != nil {
+			enrolled, err := r.Enrollment.CountByClassID(classID)
+			if err != nil {
+				return err
+			}
+			if enrolled >= int64(*cl.Capacity) {
+				return nil
+			}
+		}
+
+		st, err := r.Person.GetByID(next.StudentID)
+		if err != nil {
+			return err
+		}
+		if st == nil || (st.StudentSchoolID != nil && *st.StudentSchoolID != cl.SchoolID) {
+			continue
+		}
+		if err := checkPersonFree(r, st.ID, cl, ErrStudentConflict); errors.Is(err, ErrStudentConflict) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := r.Waitlist.Delete(classID, st.ID); err != nil {
+			return err
+		}
+		if st.StudentSchoolID == nil {
+			if err := r.Person.UpdateStudentSchoolID(st.ID, cl.SchoolID); err != nil {
+				return err
+			}
+		}
+		if _, err := r.Enrollment.Add(classID, st.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetCapacity limits the class to capacity students; 0 removes the limit.
+// Raising the limit promotes waitlisted students into the new seats. Students
+// already enrolled keep their seats when it is lowered.
+func (cs *ClassService) SetCapacity(classID uint, capacity uint) error {
+	if classID == 0 {
+		return ErrInvalidInput
+	}
+
+	return cs.uow.WithinTx(func(r repository.Repos) error {
+		cl, err := r.Class.GetByID(classID)
+		if err != nil {
+			return err
+		}
+		if cl == nil {
+			return ErrNotFound
+		}
+		return setCapacity(r, classID, capacity, cs.now())
+	})
+}
+
+func setCapacity(r repository.Repos, classID uint, capacity uint, at time.Time) error {
+	var limit *uint
+	if capacity > 0 {
+		limit = &capacity
+	}
+	if err := r.Class.SetCapacity(classID, limit); err != nil {
+		return err
+	}
+	return promoteWaitlist(r, classID, at)
+}
+
+// WaitlistPosition returns the student's 1-based place on the class
+// waitlist.
+func (cs *ClassService) WaitlistPosition(studentID, classID uint) (int, error) {
+	if studentID == 0 || classID == 0 {
+		return 0, ErrInvalidInput
+	}
+
+	cl, err := cs.classRepo.GetByID(classID)
+	if err != nil {
+		return 0, err
+	}
+	if cl == nil {
+		return 0, ErrNotFound
+	}
+
+	position, err := cs.waitlistRepo.Position(classID, studentID)
+	if err != nil {
+		return 0, err
+	}
+	if position == 0 {
+		return 0, ErrNotWaitlisted
+	}
+	return position, nil
+}
+
+// Waitlist lists the students waiting for the class, first in line first.
+func (cs *ClassService) Waitlist(classID uint) ([]models.Waitlist, error) {
+	if classID == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	cl, err := cs.classRepo.GetByID(classID)
+	if err != nil {
+		return nil, err
+	}
+	if cl == nil {
+		return nil, ErrNotFound
+	}
+	return cs.waitlistRepo.ListByClassID(classID)
+}
+
+// LeaveWaitlist takes the student off the class waitlist.
+func (cs *ClassService) LeaveWaitlist(studentID, classID uint) error {
+	if studentID == 0 || classID == 0 {
+		return ErrInvalidInput
+	}
+
+	return cs.uow.WithinTx(func(r repository.Repos) error {
+		n, err := r.Waitlist.Delete(classID, studentID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotWaitlisted
+		}
+		return nil
+	})
+}
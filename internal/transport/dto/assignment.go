@@ -0,0 +1,24 @@
+package dto
+
+import "time"
+
+type CreateAssignmentDTO struct {
+	ClassID     uint      `json:"class_id,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	DueAt       time.Time `json:"due_at"`
+}
+
+type AssignmentListDTO struct {
+	ClassID uint `json:"class_id,omitempty"`
+}
+
+type SubmitDTO struct {
+	AssignmentID uint   `json:"assignment_id,omitempty"`
+	Text         string `json:"text,omitempty"`
+	FileRef      string `json:"file_ref,omitempty"`
+}
+
+type SubmissionsDTO struct {
+	AssignmentID uint `json:"assignment_id,omitempty"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+type AttendanceRecordDTO struct {
+	StudentID uint   `json:"student_id,omitempty"`
+	Status    string `json:"status,omitempty"`
+}
+
+type RecordAttendanceDTO struct {
+	ClassID uint                  `json:"class_id,omitempty"`
+	Date    string                `json:"date,omitempty"`
+	Records []AttendanceRecordDTO `json:"records,omitempty"`
+}
+
+type AttendanceSessionDTO struct {
+	ClassID uint   `json:"class_id,omitempty"`
+	Date    string `json:"date,omitempty"`
+}
+
+type AttendanceRateDTO struct {
+	ClassID   uint `json:"class_id,omitempty"`
+	StudentID uint `json:"student_id,omitempty"`
+}
+
+type LowAttendanceDTO struct {
+	SchoolID  uint    `json:"school_id,omitempty"`
+	TermID    uint    `json:"term_id,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+}
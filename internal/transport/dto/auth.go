@@ -0,0 +1,15 @@
+package dto
+
+type LoginDTO struct {
+	ID       uint   `json:"id,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type ChangePasswordDTO struct {
+	OldPassword string `json:"old_password,omitempty"`
+	NewPassword string `json:"new_password,omitempty"`
+}
+
+type RevokeSessionsDTO struct {
+	PersonID uint `json:"person_id,omitempty"`
+}
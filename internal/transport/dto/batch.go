@@ -0,0 +1,8 @@
+package dto
+
+import "OldSchool/internal/transport/protocol"
+
+type BatchDTO struct {
+	Atomic   bool               `json:"atomic,omitempty"`
+	Requests []protocol.Request `json:"requests,omitempty"`
+}
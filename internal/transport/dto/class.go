@@ -4,9 +4,34 @@ type CreateClassDTO struct {
 	Name      string `json:"name,omitempty"`
 	SchoolID  uint   `json:"school_id,omitempty"`
 	TeacherID uint   `json:"teacher_id,omitempty"`
+	Capacity  uint   `json:"capacity,omitempty"`
+	TermID    uint   `json:"term_id,omitempty"`
 }
 
 type AddStudentToClassDTO struct {
 	StudentID uint `json:"student_id,omitempty"`
 	ClassID   uint `json:"class_id,omitempty"`
 }
+
+type RemoveStudentFromClassDTO struct {
+	StudentID uint `json:"student_id,omitempty"`
+	ClassID   uint `json:"class_id,omitempty"`
+}
+
+type UpdateClassDTO struct {
+	ClassID   uint   `json:"class_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	TeacherID uint   `json:"teacher_id,omitempty"`
+	// Capacity 0 removes the limit; leaving it out keeps the current one.
+	Capacity *uint `json:"capacity,omitempty"`
+}
+
+type DeleteClassDTO struct {
+	ClassID uint `json:"class_id,omitempty"`
+	Cascade bool `json:"cascade,omitempty"`
+}
+
+type WaitlistDTO struct {
+	ClassID   uint `json:"class_id,omitempty"`
+	StudentID uint `json:"student_id,omitempty"`
+}
@@ -0,0 +1,12 @@
+package dto
+
+// ExportDTO takes tables and columns as comma-separated lists, so the same
+// fields work as URL query parameters.
+type ExportDTO struct {
+	Format   string `json:"format,omitempty"`
+	Tables   string `json:"tables,omitempty"`
+	Columns  string `json:"columns,omitempty"`
+	SchoolID uint   `json:"school_id,omitempty"`
+	TermID   uint   `json:"term_id,omitempty"`
+	Role     string `json:"role,omitempty"`
+}
@@ -0,0 +1,23 @@
+package dto
+
+type CreateAssessmentDTO struct {
+	ClassID   uint    `json:"class_id,omitempty"`
+	Name      string  `json:"name,omitempty"`
+	Weight    float64 `json:"weight,omitempty"`
+	MaxPoints float64 `json:"max_points,omitempty"`
+}
+
+type ScoreDTO struct {
+	StudentID uint    `json:"student_id,omitempty"`
+	Points    float64 `json:"points"`
+}
+
+type RecordScoresDTO struct {
+	AssessmentID uint       `json:"assessment_id,omitempty"`
+	Scores       []ScoreDTO `json:"scores,omitempty"`
+}
+
+type GradesDTO struct {
+	ID     uint `json:"id,omitempty"`
+	TermID uint `json:"term_id,omitempty"`
+}
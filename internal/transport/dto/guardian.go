@@ -0,0 +1,14 @@
+package dto
+
+type LinkGuardianDTO struct {
+	GuardianID       uint   `json:"guardian_id,omitempty"`
+	StudentID        uint   `json:"student_id,omitempty"`
+	Relationship     string `json:"relationship,omitempty"`
+	PreferredContact string `json:"preferred_contact,omitempty"`
+	EmergencyContact bool   `json:"emergency_contact,omitempty"`
+	ReceivesReports  bool   `json:"receives_reports,omitempty"`
+}
+
+type GuardiansDTO struct {
+	ID uint `json:"id,omitempty"`
+}
@@ -0,0 +1,6 @@
+package dto
+
+type ImportDTO struct {
+	CSV    string `json:"csv,omitempty"`
+	DryRun bool   `json:"dry_run,omitempty"`
+}
@@ -1,9 +1,29 @@
 package dto
 
+// PageDTO holds the paging and sorting fields every list method accepts.
+type PageDTO struct {
+	Limit int    `json:"limit,omitempty"`
+	After string `json:"after,omitempty"`
+	Sort  string `json:"sort,omitempty"`
+}
+
+type SchoolListDTO struct {
+	PageDTO
+	NameContains string `json:"name_contains,omitempty"`
+}
+
 type SchoolClassesDTO struct {
-	SchoolID uint `json:"school_id,omitempty"`
+	PageDTO
+	SchoolID     uint   `json:"school_id,omitempty"`
+	TermID       uint   `json:"term_id,omitempty"`
+	NameContains string `json:"name_contains,omitempty"`
+	TeacherID    uint   `json:"teacher_id,omitempty"`
 }
 
 type ClassStudentsDTO struct {
-	ClassID uint `json:"class_id,omitempty"`
+	PageDTO
+	ClassID      uint   `json:"class_id,omitempty"`
+	NameContains string `json:"name_contains,omitempty"`
+	Role         string `json:"role,omitempty"`
+	SchoolID     uint   `json:"school_id,omitempty"`
 }
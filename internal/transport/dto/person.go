@@ -1,10 +1,24 @@
 package dto
 
 type CreatePersonDTO struct {
-	Name string `json:"name,omitempty"`
-	Role string `json:"role,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Role     string   `json:"role,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	Password string   `json:"password,omitempty"`
 }
 
 type WhoAmIDTO struct {
-	ID uint `json:"id,omitempty"`
+	ID     uint `json:"id,omitempty"`
+	TermID uint `json:"term_id,omitempty"`
+}
+
+type UpdatePersonDTO struct {
+	ID    uint     `json:"id,omitempty"`
+	Name  string   `json:"name,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+type DeletePersonDTO struct {
+	ID      uint `json:"id,omitempty"`
+	Cascade bool `json:"cascade,omitempty"`
 }
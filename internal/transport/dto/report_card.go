@@ -0,0 +1,13 @@
+package dto
+
+type ReportCardDTO struct {
+	ID     uint   `json:"id,omitempty"`
+	TermID uint   `json:"term_id,omitempty"`
+	Format string `json:"format,omitempty"`
+}
+
+type ReportCommentDTO struct {
+	ClassID   uint   `json:"class_id,omitempty"`
+	StudentID uint   `json:"student_id,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+}
@@ -3,3 +3,13 @@ package dto
 type CreateSchoolDTO struct {
 	Name string `json:"name,omitempty"`
 }
+
+type UpdateSchoolDTO struct {
+	SchoolID uint   `json:"school_id,omitempty"`
+	Name     string `json:"name,omitempty"`
+}
+
+type DeleteSchoolDTO struct {
+	SchoolID uint `json:"school_id,omitempty"`
+	Cascade  bool `json:"cascade,omitempty"`
+}
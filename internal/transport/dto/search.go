@@ -0,0 +1,7 @@
+package dto
+
+type SearchDTO struct {
+	Query    string `json:"query,omitempty"`
+	SchoolID uint   `json:"school_id,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+type CreateTermDTO struct {
+	SchoolID uint      `json:"school_id,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+type TermListDTO struct {
+	SchoolID uint `json:"school_id,omitempty"`
+}
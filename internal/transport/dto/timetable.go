@@ -0,0 +1,28 @@
+package dto
+
+type CreateRoomDTO struct {
+	SchoolID uint   `json:"school_id,omitempty"`
+	Name     string `json:"name,omitempty"`
+}
+
+type RoomListDTO struct {
+	SchoolID uint `json:"school_id,omitempty"`
+}
+
+type SlotDTO struct {
+	SlotID    uint   `json:"slot_id,omitempty"`
+	ClassID   uint   `json:"class_id,omitempty"`
+	RoomID    uint   `json:"room_id,omitempty"`
+	Weekday   int    `json:"weekday"`
+	StartTime string `json:"start_time,omitempty"`
+	EndTime   string `json:"end_time,omitempty"`
+}
+
+type ClassSlotsDTO struct {
+	ClassID uint `json:"class_id,omitempty"`
+}
+
+type TimetableDTO struct {
+	ID     uint `json:"id,omitempty"`
+	TermID uint `json:"term_id,omitempty"`
+}
@@ -0,0 +1,12 @@
+package dto
+
+type TransferDTO struct {
+	StudentID uint   `json:"student_id,omitempty"`
+	SchoolID  uint   `json:"school_id,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	ClassIDs  []uint `json:"class_ids,omitempty"`
+}
+
+type TransferHistoryDTO struct {
+	ID uint `json:"id,omitempty"`
+}
@@ -0,0 +1,103 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	CodecJSON    = "json"
+	CodecMsgPack = "msgpack"
+	CodecCBOR    = "cbor"
+)
+
+// Codec converts messages between a wire encoding and JSON. The server works
+// on JSON internally, so a codec only has to translate at the connection edge
+// and every message shape (requests, JSON-RPC, batches) keeps working.
+type Codec interface {
+	ToJSON(msg []byte) ([]byte, error)
+	FromJSON(msg []byte) ([]byte, error)
+}
+
+func LookupCodec(name string) (Codec, bool) {
+	switch name {
+	case CodecJSON:
+		return jsonCodec{}, true
+	case CodecMsgPack:
+		return genericCodec{marshal: msgpack.Marshal, unmarshal: msgpack.Unmarshal}, true
+	case CodecCBOR:
+		return genericCodec{marshal: cborEnc.Marshal, unmarshal: cborDec.Unmarshal}, true
+	default:
+		return nil, false
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ToJSON(msg []byte) ([]byte, error)   { return msg, nil }
+func (jsonCodec) FromJSON(msg []byte) ([]byte, error) { return msg, nil }
+
+var (
+	cborEnc, _ = cbor.EncOptions{Sort: cbor.SortCanonical}.EncMode()
+	cborDec, _ = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]any(nil))}.DecMode()
+)
+
+// genericCodec goes through plain Go values (maps, slices, numbers, strings)
+// on the way to and from JSON.
+type genericCodec struct {
+	marshal   func(v any) ([]byte, error)
+	unmarshal func(data []byte, v any) error
+}
+
+func (c genericCodec) ToJSON(msg []byte) ([]byte, error) {
+	var v any
+	if err := c.unmarshal(msg, &v); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUndecodable, err)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUndecodable, err)
+	}
+	return b, nil
+}
+
+func (c genericCodec) FromJSON(msg []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(msg))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return c.marshal(numbersToNative(v))
+}
+
+// numbersToNative turns json.Number values into integers where possible so
+// ids and counts are not encoded as floats.
+func numbersToNative(v any) any {
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+		f, _ := x.Float64()
+		return f
+	case map[string]any:
+		for k, e := range x {
+			x[k] = numbersToNative(e)
+		}
+		return x
+	case []any:
+		for i, e := range x {
+			x[i] = numbersToNative(e)
+		}
+		return x
+	default:
+		return v
+	}
+}
@@ -0,0 +1,100 @@
+package protocol
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// NegotiateMethod may be sent as the very first request on a connection to
+// switch framing and codec. The reply still uses JSON lines; everything after
+// it uses the negotiated format in both directions.
+const NegotiateMethod = "/protocol/negotiate"
+
+const (
+	FramingLines  = "lines"
+	FramingLength = "length-prefixed"
+)
+
+// MaxFrameBytes caps a length-prefixed frame. It is larger than MaxLineBytes
+// because binary framing is meant for bulk transfers.
+const MaxFrameBytes = 16 << 20
+
+// ErrUndecodable is returned when a frame cannot be decoded with the
+// negotiated codec. The connection stays usable.
+var ErrUndecodable = errors.New("cannot decode message")
+
+type Negotiation struct {
+	Framing string `json:"framing,omitempty"`
+	Codec   string `json:"codec,omitempty"`
+}
+
+// ParseNegotiation returns the requested format when req is a negotiation.
+// Empty fields fall back to JSON lines.
+func ParseNegotiation(req *Request) (*Negotiation, error) {
+	var n Negotiation
+	if len(req.Data) > 0 {
+		if err := json.Unmarshal(req.Data, &n); err != nil {
+			return nil, err
+		}
+	}
+	if n.Framing == "" {
+		n.Framing = FramingLines
+	}
+	if n.Codec == "" {
+		n.Codec = CodecJSON
+	}
+	if n.Framing != FramingLines && n.Framing != FramingLength {
+		return nil, errors.New("unsupported framing")
+	}
+	if _, ok := LookupCodec(n.Codec); !ok {
+		return nil, errors.New("unsupported codec")
+	}
+	if n.Framing == FramingLines && n.Codec != CodecJSON {
+		// binary payloads may contain newlines
+		return nil, errors.New("codec requires length-prefixed framing")
+	}
+	return &n, nil
+}
+
+// ReadFrame reads one frame made of a 4-byte big-endian length followed by
+// that many bytes. An oversized frame is skipped and ErrMessageTooBig is
+// returned, so the stream stays in sync.
+func ReadFrame(r *bufio.Reader) ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n > MaxFrameBytes {
+		if _, err := io.CopyN(io.Discard, r, int64(n)); err != nil {
+			return nil, err
+		}
+		return nil, ErrMessageTooBig
+	}
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func WriteFrame(w *bufio.Writer, msg []byte) error {
+	if len(msg) > MaxFrameBytes {
+		return ErrMessageTooBig
+	}
+
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(len(msg)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,87 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+const JSONRPCVersion = "2.0"
+
+// Error codes reserved by the JSON-RPC 2.0 specification.
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+)
+
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// IsNotification reports whether the request has no id member, in which
+// case the server must not reply to it.
+func (r *RPCRequest) IsNotification() bool {
+	return r.ID == nil
+}
+
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+type RPCResponse struct {
+	ID     json.RawMessage
+	Result interface{}
+	Error  *RPCError
+}
+
+// MarshalJSON always emits "id", and exactly one of "result" or "error",
+// as the specification requires.
+func (r RPCResponse) MarshalJSON() ([]byte, error) {
+	id := r.ID
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string          `json:"jsonrpc"`
+			ID      json.RawMessage `json:"id"`
+			Error   *RPCError       `json:"error"`
+		}{JSONRPCVersion, id, r.Error})
+	}
+
+	return json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Result  interface{}     `json:"result"`
+	}{JSONRPCVersion, id, r.Result})
+}
+
+// IsJSONRPC reports whether msg should be answered in JSON-RPC form: a batch
+// array, or an object carrying a "jsonrpc" member. Anything else is handled
+// as a regular Request.
+func IsJSONRPC(msg []byte) bool {
+	msg = bytes.TrimSpace(msg)
+	if len(msg) == 0 {
+		return false
+	}
+	if msg[0] == '[' {
+		return true
+	}
+
+	var probe struct {
+		JSONRPC *string `json:"jsonrpc"`
+	}
+	if err := json.Unmarshal(msg, &probe); err != nil {
+		// malformed JSON is still answered as JSON-RPC if it looks like it
+		return bytes.Contains(msg, []byte(`"jsonrpc"`))
+	}
+	return probe.JSONRPC != nil
+}
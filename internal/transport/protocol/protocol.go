@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"sync"
 )
 
 var (
@@ -14,14 +15,64 @@ var (
 
 const MaxLineBytes = 1 << 20
 
+// Request is one call sent by a client. ID is optional and opaque to the
+// server; when present it is echoed back on the matching Response so that
+// clients can pipeline several requests and match replies out of order.
 type Request struct {
+	ID     json.RawMessage `json:"id,omitempty"`
 	Method string          `json:"method,omitempty"`
 	Data   json.RawMessage `json:"data,omitempty"`
+
+	// Peer describes the connection the request arrived on. It is set by
+	// the transport and never read from the wire.
+	Peer *Peer `json:"-"`
+}
+
+// Peer is what a transport knows about the other end of a connection. It is
+// shared by every request on that connection, which is how a login on one
+// request authenticates the ones that follow.
+type Peer struct {
+	Addr string
+
+	// CommonName and Subject come from a client certificate verified
+	// during a mutual TLS handshake; both are empty otherwise.
+	CommonName string
+	Subject    string
+
+	mu    sync.Mutex
+	token string
 }
+
+// SessionToken returns the session token bound to this connection, if any.
+func (p *Peer) SessionToken() string {
+	if p == nil {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.token
+}
+
+// BindSession makes later requests on this connection run as the session's
+// owner. An empty token unbinds it. A nil peer has nothing to bind.
+func (p *Peer) BindSession(token string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.token = token
+}
+
 type Response struct {
-	Status  bool        `json:"status,omitempty"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Status  bool            `json:"status,omitempty"`
+	Message string          `json:"message,omitempty"`
+	Data    interface{}     `json:"data,omitempty"`
+
+	// Err is the error behind a failed response. It never goes on the wire;
+	// transports use it to pick their own status codes.
+	Err error `json:"-"`
 }
 
 func readLineLimited(r *bufio.Reader, max int) ([]byte, error) {
@@ -50,17 +101,28 @@ func readLineLimited(r *bufio.Reader, max int) ([]byte, error) {
 	return buf, nil
 }
 
+// ReadLine reads one newline-terminated message of at most MaxLineBytes.
+func ReadLine(r *bufio.Reader) ([]byte, error) {
+	return readLineLimited(r, MaxLineBytes)
+}
+
 func ReadRequest(r *bufio.Reader) (*Request, error) {
-	line, err := readLineLimited(r, MaxLineBytes)
+	line, err := ReadLine(r)
 	if err != nil {
 		return nil, err
 	}
-	if len(line) == 0 {
+	return ParseRequest(line)
+}
+
+// ParseRequest decodes a single message that has already been framed by the
+// transport, such as a line or a WebSocket text frame.
+func ParseRequest(msg []byte) (*Request, error) {
+	if len(msg) == 0 {
 		return nil, ErrEmptyLine
 	}
 
 	var req Request
-	if err := json.Unmarshal(line, &req); err != nil {
+	if err := json.Unmarshal(msg, &req); err != nil {
 		return nil, err
 	}
 	return &req, nil
@@ -71,7 +133,12 @@ func WriteResponse(w *bufio.Writer, resp Response) error {
 	if err != nil {
 		return nil
 	}
-	if _, err := w.Write(b); err != nil {
+	return WriteLine(w, b)
+}
+
+// WriteLine writes one already encoded message followed by a newline.
+func WriteLine(w *bufio.Writer, msg []byte) error {
+	if _, err := w.Write(msg); err != nil {
 		return err
 	}
 
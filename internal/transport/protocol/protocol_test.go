@@ -66,3 +66,100 @@ func TestReadRequest_ReadsNDJSON(t *testing.T) {
 		t.Fatalf("expected name MIT, got %v", payload["name"])
 	}
 }
+
+func TestReadRequest_KeepsID(t *testing.T) {
+	in := `{"id":"req-7","method":"/school/list"}` + "\n"
+	r := bufio.NewReader(bytes.NewBufferString(in))
+
+	req, err := protocol.ReadRequest(r)
+	if err != nil {
+		t.Fatalf("ReadRequest error: %v", err)
+	}
+	if string(req.ID) != `"req-7"` {
+		t.Fatalf("expected id \"req-7\", got %s", req.ID)
+	}
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	if err := protocol.WriteResponse(w, protocol.Response{ID: req.ID, Status: true}); err != nil {
+		t.Fatalf("WriteResponse error: %v", err)
+	}
+	if got := buf.String(); got != `{"id":"req-7","status":true}`+"\n" {
+		t.Fatalf("unexpected response line: %q", got)
+	}
+}
+
+func TestFrame_RoundTripKeepsNewlines(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+
+	msg := []byte("{\"name\":\"line one\\nline two\"}")
+	if err := protocol.WriteFrame(w, msg); err != nil {
+		t.Fatalf("WriteFrame error: %v", err)
+	}
+	if err := protocol.WriteFrame(w, []byte(`{}`)); err != nil {
+		t.Fatalf("WriteFrame error: %v", err)
+	}
+
+	r := bufio.NewReader(&buf)
+	got, err := protocol.ReadFrame(r)
+	if err != nil {
+		t.Fatalf("ReadFrame error: %v", err)
+	}
+	if !bytes.Equal(got, msg) {
+		t.Fatalf("expected %q, got %q", msg, got)
+	}
+	if got, _ := protocol.ReadFrame(r); string(got) != `{}` {
+		t.Fatalf("second frame out of sync: %q", got)
+	}
+}
+
+func TestCodecs_RoundTripThroughJSON(t *testing.T) {
+	in := []byte(`{"data":{"ids":[1,2,3],"name":"S1","ratio":0.5},"id":7,"status":true}`)
+
+	for _, name := range []string{protocol.CodecJSON, protocol.CodecMsgPack, protocol.CodecCBOR} {
+		codec, ok := protocol.LookupCodec(name)
+		if !ok {
+			t.Fatalf("codec %s not found", name)
+		}
+
+		wire, err := codec.FromJSON(in)
+		if err != nil {
+			t.Fatalf("%s: FromJSON error: %v", name, err)
+		}
+		out, err := codec.ToJSON(wire)
+		if err != nil {
+			t.Fatalf("%s: ToJSON error: %v", name, err)
+		}
+
+		var want, got map[string]any
+		_ = json.Unmarshal(in, &want)
+		if err := json.Unmarshal(out, &got); err != nil {
+			t.Fatalf("%s: output is not JSON: %v", name, err)
+		}
+		if !bytes.Equal(mustMarshal(t, want), mustMarshal(t, got)) {
+			t.Fatalf("%s: expected %s, got %s", name, in, out)
+		}
+	}
+
+	if _, ok := protocol.LookupCodec("xml"); ok {
+		t.Fatalf("expected unknown codec")
+	}
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	return b
+}
+
+func TestPeer_NilIsSafe(t *testing.T) {
+	var p *protocol.Peer
+	p.BindSession("token")
+	if p.SessionToken() != "" {
+		t.Fatalf("expected no token on a nil peer")
+	}
+}
@@ -0,0 +1,316 @@
+package rest
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"math"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"OldSchool/internal/service"
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"OldSchool/internal/transport/router"
+	"OldSchool/internal/transport/server"
+)
+
+// route maps one REST resource onto a router method. When param is set the
+// {id} path value is copied into the request data under that JSON field.
+// Query parameters are read only for routes with a query DTO, which gives
+// each parameter its JSON type.
+type route struct {
+	pattern string
+	method  string
+	param   string
+	query   any
+	created bool
+}
+
+var routes = []route{
+	{pattern: "GET /schools", method: router.SchoolListMethod, query: dto.SchoolListDTO{}},
+	{pattern: "POST /schools", method: router.CreateSchoolMethod, created: true},
+	{pattern: "PUT /schools/{id}", method: router.UpdateSchoolMethod, param: "school_id"},
+	{pattern: "DELETE /schools/{id}", method: router.DeleteSchoolMethod, param: "school_id"},
+	{pattern: "GET /schools/{id}/classes", method: router.SchoolClassesMethod, param: "school_id", query: dto.SchoolClassesDTO{}},
+	{pattern: "GET /schools/{id}/terms", method: router.TermListMethod, param: "school_id", query: dto.TermListDTO{}},
+	{pattern: "POST /schools/{id}/terms", method: router.CreateTermMethod, param: "school_id", created: true},
+	{pattern: "GET /schools/{id}/terms/current", method: router.CurrentTermMethod, param: "school_id", query: dto.TermListDTO{}},
+	{pattern: "GET /schools/{id}/rooms", method: router.RoomListMethod, param: "school_id", query: dto.RoomListDTO{}},
+	{pattern: "POST /schools/{id}/rooms", method: router.CreateRoomMethod, param: "school_id", created: true},
+	{pattern: "GET /schools/{id}/attendance/low", method: router.LowAttendanceMethod, param: "school_id", query: dto.LowAttendanceDTO{}},
+	{pattern: "POST /people", method: router.CreatePersonMethod, created: true},
+	{pattern: "GET /people/{id}", method: router.WhoAmIMethod, param: "id", query: dto.WhoAmIDTO{}},
+	{pattern: "PUT /people/{id}", method: router.UpdatePersonMethod, param: "id"},
+	{pattern: "DELETE /people/{id}", method: router.DeletePersonMethod, param: "id"},
+	{pattern: "POST /people/{id}/transfers", method: router.TransferMethod, param: "student_id", created: true},
+	{pattern: "GET /people/{id}/transfers", method: router.TransferHistoryMethod, param: "id", query: dto.TransferHistoryDTO{}},
+	{pattern: "GET /people/{id}/timetable", method: router.TimetableMethod, param: "id", query: dto.TimetableDTO{}},
+	{pattern: "GET /people/{id}/grades", method: router.GradesMethod, param: "id", query: dto.GradesDTO{}},
+	{pattern: "GET /people/{id}/report-card", method: router.ReportCardMethod, param: "id", query: dto.ReportCardDTO{}},
+	{pattern: "GET /people/{id}/guardians", method: router.GuardiansMethod, param: "id", query: dto.GuardiansDTO{}},
+	{pattern: "POST /people/{id}/guardians", method: router.LinkGuardianMethod, param: "student_id", created: true},
+	{pattern: "DELETE /people/{id}/guardians", method: router.UnlinkGuardianMethod, param: "student_id"},
+	{pattern: "PUT /people/{id}/report-card/comment", method: router.ReportCommentMethod, param: "student_id"},
+	{pattern: "POST /classes", method: router.CreateClassMethod, created: true},
+	{pattern: "PUT /classes/{id}", method: router.UpdateClassMethod, param: "class_id"},
+	{pattern: "DELETE /classes/{id}", method: router.DeleteClassMethod, param: "class_id"},
+	{pattern: "GET /classes/{id}/slots", method: router.ClassSlotsMethod, param: "class_id", query: dto.ClassSlotsDTO{}},
+	{pattern: "POST /classes/{id}/slots", method: router.AddSlotMethod, param: "class_id", created: true},
+	{pattern: "PUT /slots/{id}", method: router.UpdateSlotMethod, param: "slot_id"},
+	{pattern: "DELETE /slots/{id}", method: router.DeleteSlotMethod, param: "slot_id"},
+	{pattern: "POST /classes/{id}/assessments", method: router.CreateAssessmentMethod, param: "class_id", created: true},
+	{pattern: "PUT /assessments/{id}/scores", method: router.RecordScoresMethod, param: "assessment_id"},
+	{pattern: "PUT /classes/{id}/attendance", method: router.RecordAttendanceMethod, param: "class_id"},
+	{pattern: "GET /classes/{id}/attendance", method: router.AttendanceSessionMethod, param: "class_id", query: dto.AttendanceSessionDTO{}},
+	{pattern: "GET /classes/{id}/attendance/rate", method: router.AttendanceRateMethod, param: "class_id", query: dto.AttendanceRateDTO{}},
+	{pattern: "GET /classes/{id}/assignments", method: router.AssignmentListMethod, param: "class_id", query: dto.AssignmentListDTO{}},
+	{pattern: "POST /classes/{id}/assignments", method: router.CreateAssignmentMethod, param: "class_id", created: true},
+	{pattern: "POST /assignments/{id}/submission", method: router.SubmitMethod, param: "assignment_id", created: true},
+	{pattern: "PUT /assignments/{id}/submission", method: router.ResubmitMethod, param: "assignment_id"},
+	{pattern: "GET /assignments/{id}/submissions", method: router.SubmissionsMethod, param: "assignment_id", query: dto.SubmissionsDTO{}},
+	{pattern: "GET /classes/{id}/students", method: router.ClassStudentsMethod, param: "class_id", query: dto.ClassStudentsDTO{}},
+	{pattern: "POST /classes/{id}/students", method: router.AddStudentToClassMethod, param: "class_id", created: true},
+	{pattern: "DELETE /classes/{id}/students", method: router.RemoveStudentFromClassMethod, param: "class_id"},
+	{pattern: "GET /classes/{id}/waitlist", method: router.ClassWaitlistMethod, param: "class_id", query: dto.WaitlistDTO{}},
+	{pattern: "GET /classes/{id}/waitlist/position", method: router.WaitlistPositionMethod, param: "class_id", query: dto.WaitlistDTO{}},
+	{pattern: "DELETE /classes/{id}/waitlist", method: router.LeaveWaitlistMethod, param: "class_id"},
+	{pattern: "PUT /classes/{id}/teacher", method: router.AssignTeacherToClassMethod, param: "class_id"},
+	{pattern: "POST /batch", method: router.BatchMethod},
+	{pattern: "POST /auth/login", method: router.LoginMethod, created: true},
+	{pattern: "POST /auth/logout", method: router.LogoutMethod},
+	{pattern: "GET /me", method: router.WhoAmIMethod, query: dto.WhoAmIDTO{}},
+	{pattern: "GET /search", method: router.SearchMethod, query: dto.SearchDTO{}},
+	{pattern: "POST /import", method: router.ImportMethod},
+	{pattern: "GET /export", method: router.ExportMethod, query: dto.ExportDTO{}},
+	{pattern: "GET /health", method: router.HealthMethod},
+}
+
+// NewHandler exposes the router's operations as REST resources. Every call is
+// translated into a protocol.Request and dispatched through router.Handle.
+func NewHandler(r *router.Router) http.Handler {
+	mux := http.NewServeMux()
+	for _, rt := range routes {
+		mux.HandleFunc(rt.pattern, handle(r, rt))
+	}
+	return mux
+}
+
+func handle(r *router.Router, rt route) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		data, err := requestData(req, rt)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, protocol.Response{Status: false, Message: err.Error()})
+			return
+		}
+
+		// HTTP is stateless: each call carries its session as a bearer token.
+		peer := &protocol.Peer{Addr: req.RemoteAddr}
+		if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+			peer.BindSession(strings.TrimSpace(token))
+		}
+
+		resp := r.Handle(&protocol.Request{Method: rt.method, Data: data, Peer: peer})
+
+		code := statusFor(resp.Err)
+		if resp.Status && rt.created {
+			code = http.StatusCreated
+		}
+		writeJSON(w, code, resp)
+	}
+}
+
+// requestData reads the JSON body, if any, and merges the query parameters and
+// the path id into it.
+func requestData(req *http.Request, rt route) (json.RawMessage, error) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, protocol.MaxLineBytes+1))
+	if err != nil {
+		return nil, errors.New("cannot read body")
+	}
+	if len(body) > protocol.MaxLineBytes {
+		return nil, protocol.ErrMessageTooBig
+	}
+
+	query := req.URL.Query()
+	if rt.query == nil {
+		query = nil
+	}
+	if rt.param == "" && len(query) == 0 {
+		return body, nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return nil, errors.New("invalid json body")
+		}
+	}
+	if len(query) > 0 {
+		kinds := queryKinds(reflect.TypeOf(rt.query))
+		for key := range query {
+			value, err := queryValue(kinds, key, query.Get(key))
+			if err != nil {
+				return nil, err
+			}
+			fields[key] = value
+		}
+	}
+
+	if rt.param != "" {
+		id, err := strconv.ParseUint(req.PathValue("id"), 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid id in path")
+		}
+		fields[rt.param] = json.RawMessage(strconv.FormatUint(id, 10))
+	}
+
+	return json.Marshal(fields)
+}
+
+// queryKinds maps the JSON name of every field of a DTO, including those of
+// embedded structs, to its kind.
+func queryKinds(t reflect.Type) map[string]reflect.Kind {
+	kinds := map[string]reflect.Kind{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			maps.Copy(kinds, queryKinds(f.Type))
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			kinds[name] = f.Type.Kind()
+		}
+	}
+	return kinds
+}
+
+// queryValue turns one query parameter into JSON of the type its DTO field
+// has. Parameters the DTO does not know are refused.
+func queryValue(kinds map[string]reflect.Kind, key, value string) (json.RawMessage, error) {
+	kind, known := kinds[key]
+	if !known {
+		return nil, errors.New("unknown query parameter " + key)
+	}
+
+	var parsed any
+	var err error
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err = strconv.ParseUint(value, 10, 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err = strconv.ParseInt(value, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(value, 64); err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			err = strconv.ErrSyntax
+		}
+		parsed = f
+	case reflect.Bool:
+		parsed, err = strconv.ParseBool(value)
+	default:
+		parsed = value
+	}
+	if err != nil {
+		return nil, errors.New("invalid " + key + " in query")
+	}
+	return json.Marshal(parsed)
+}
+
+func statusFor(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, router.ErrBadRequest), errors.Is(err, service.ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrUnauthenticated), errors.Is(err, service.ErrInvalidCredentials):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, router.ErrUnknownMethod), errors.Is(err, service.ErrNotFound),
+		errors.Is(err, service.ErrNotEnrolled), errors.Is(err, service.ErrNotWaitlisted),
+		errors.Is(err, service.ErrNotSubmitted):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrRoleMismatch), errors.Is(err, service.ErrImportFailed),
+		errors.Is(err, service.ErrExportTooLarge):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrDuplicateEnrollment),
+		errors.Is(err, service.ErrDifferentSchool),
+		errors.Is(err, service.ErrSchoolAlreadyExists),
+		errors.Is(err, service.ErrSchoolHasClasses),
+		errors.Is(err, service.ErrClassHasStudents),
+		errors.Is(err, service.ErrPersonHasClasses),
+		errors.Is(err, service.ErrAlreadyWaitlisted),
+		errors.Is(err, service.ErrTermClosed),
+		errors.Is(err, service.ErrNoCurrentTerm),
+		errors.Is(err, service.ErrTermAlreadyExists),
+		errors.Is(err, service.ErrRoomAlreadyExists),
+		errors.Is(err, service.ErrRoomConflict),
+		errors.Is(err, service.ErrTeacherConflict),
+		errors.Is(err, service.ErrStudentConflict),
+		errors.Is(err, service.ErrAssessmentAlreadyExists),
+		errors.Is(err, service.ErrAlreadySubmitted):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, resp protocol.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+type httpServer struct {
+	srv *http.Server
+	cfg server.Config
+	wg  sync.WaitGroup
+}
+
+// New returns a server that serves the REST gateway next to the TCP one. It
+// uses the same TLS settings as the other listeners.
+func New(r *router.Router, cfg server.Config) server.Server {
+	return &httpServer{
+		cfg: cfg,
+		srv: &http.Server{
+			Handler:           NewHandler(r),
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+func (s *httpServer) Start(port string) error {
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return err
+	}
+	if s.cfg.TLS != nil {
+		ln = tls.NewListener(ln, s.cfg.TLS)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		_ = s.srv.Serve(ln)
+	}()
+
+	return nil
+}
+
+func (s *httpServer) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.srv.Shutdown(ctx)
+	s.wg.Wait()
+	return err
+}
@@ -0,0 +1,360 @@
+package rest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"OldSchool/internal/repository"
+	"OldSchool/internal/service"
+	"OldSchool/internal/transport/rest"
+	"OldSchool/internal/transport/router"
+	"OldSchool/internal/transport/server"
+)
+
+type apiResponse struct {
+	Status  bool            `json:"status"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+const adminPassword = "admin-password"
+
+var bearer string
+
+func setupGateway(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db.DB failed: %v", err)
+	}
+
+	services := service.NewServices(repository.NewRepos(db))
+	admin, err := services.Auth.Bootstrap("admin", adminPassword)
+	if err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+
+	ts := httptest.NewServer(rest.NewHandler(router.NewRouter(services)))
+	t.Cleanup(func() {
+		ts.Close()
+		_ = sqlDB.Close()
+	})
+
+	bearer = ""
+	code, resp := call(t, ts, "POST", "/auth/login", map[string]any{"id": admin.ID, "password": adminPassword})
+	if code != http.StatusCreated {
+		t.Fatalf("admin login failed: %d %s", code, resp.Message)
+	}
+	var session struct {
+		Token string `json:"token"`
+	}
+	_ = json.Unmarshal(resp.Data, &session)
+	bearer = session.Token
+
+	return ts
+}
+
+func call(t *testing.T, ts *httptest.Server, method, path string, body any) (int, apiResponse) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, ts.URL+path, &buf)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer res.Body.Close()
+
+	var out apiResponse
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return res.StatusCode, out
+}
+
+func id(t *testing.T, resp apiResponse) uint {
+	t.Helper()
+	var x struct{ ID uint }
+	if err := json.Unmarshal(resp.Data, &x); err != nil || x.ID == 0 {
+		t.Fatalf("cannot read ID from %s", resp.Data)
+	}
+	return x.ID
+}
+
+func TestGateway_ResourcesAndStatusCodes(t *testing.T) {
+	ts := setupGateway(t)
+
+	code, resp := call(t, ts, "POST", "/schools", map[string]any{"name": "S1"})
+	if code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (%s)", code, resp.Message)
+	}
+	schoolID := id(t, resp)
+
+	code, _ = call(t, ts, "POST", "/schools", map[string]any{"name": "S1"})
+	if code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate school, got %d", code)
+	}
+
+	_, resp = call(t, ts, "POST", "/people", map[string]any{"name": "T1", "role": "teacher"})
+	teacherID := id(t, resp)
+	_, resp = call(t, ts, "POST", "/people", map[string]any{"name": "Stu", "role": "student"})
+	studentID := id(t, resp)
+
+	_, resp = call(t, ts, "POST", "/classes", map[string]any{"name": "C1", "school_id": schoolID, "teacher_id": teacherID})
+	classID := id(t, resp)
+
+	code, resp = call(t, ts, "POST", "/classes/"+itoa(classID)+"/students", map[string]any{"student_id": studentID})
+	if code != http.StatusCreated {
+		t.Fatalf("expected 201 for enrollment, got %d (%s)", code, resp.Message)
+	}
+
+	code, resp = call(t, ts, "GET", "/classes/"+itoa(classID)+"/students", nil)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", code, resp.Message)
+	}
+	var students struct {
+		Items []struct{ ID uint }
+		Total int64
+	}
+	_ = json.Unmarshal(resp.Data, &students)
+	if len(students.Items) != 1 || students.Items[0].ID != studentID || students.Total != 1 {
+		t.Fatalf("unexpected roster: %s", resp.Data)
+	}
+
+	code, resp = call(t, ts, "GET", "/classes/"+itoa(classID)+"/students?limit=1&name_contains=nobody", nil)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 for filtered roster, got %d (%s)", code, resp.Message)
+	}
+	_ = json.Unmarshal(resp.Data, &students)
+	if len(students.Items) != 0 || students.Total != 0 {
+		t.Fatalf("expected empty filtered roster: %s", resp.Data)
+	}
+
+	code, _ = call(t, ts, "GET", "/schools?limit=abc", nil)
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad limit, got %d", code)
+	}
+
+	code, _ = call(t, ts, "GET", "/schools/999/classes", nil)
+	if code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown school, got %d", code)
+	}
+
+	code, _ = call(t, ts, "PUT", "/classes/"+itoa(classID)+"/teacher", map[string]any{"teacher_id": studentID})
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for student as teacher, got %d", code)
+	}
+
+	code, resp = call(t, ts, "GET", "/export?format=csv&tables=enrollments&columns=student_name&school_id="+itoa(schoolID), nil)
+	var export struct{ Document string }
+	_ = json.Unmarshal(resp.Data, &export)
+	if code != http.StatusOK || export.Document != "student_name\nStu\n" {
+		t.Fatalf("expected csv roster export, got %d %s", code, resp.Data)
+	}
+
+	code, _ = call(t, ts, "GET", "/classes/abc/students", nil)
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad path id, got %d", code)
+	}
+}
+
+func itoa(n uint) string {
+	return strconv.FormatUint(uint64(n), 10)
+}
+
+func TestGateway_RequiresBearerToken(t *testing.T) {
+	ts := setupGateway(t)
+
+	token := bearer
+	bearer = ""
+	code, _ := call(t, ts, "GET", "/schools", nil)
+	if code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", code)
+	}
+
+	code, _ = call(t, ts, "GET", "/health", nil)
+	if code != http.StatusOK {
+		t.Fatalf("expected health check to be public, got %d", code)
+	}
+
+	bearer = token
+	code, resp := call(t, ts, "GET", "/me", nil)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 for /me, got %d (%s)", code, resp.Message)
+	}
+}
+
+func TestGateway_ServesTLSWhenConfigured(t *testing.T) {
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	// borrow httptest's certificate and a client that trusts it
+	certs := httptest.NewTLSServer(http.NotFoundHandler())
+	tlsCfg, client := certs.TLS, certs.Client()
+	certs.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+	_ = ln.Close()
+
+	gateway := rest.New(router.NewRouter(service.NewServices(repository.NewRepos(db))), server.Config{TLS: tlsCfg})
+	if err := gateway.Start(port); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { _ = gateway.Stop() })
+
+	resp, err := client.Get("https://127.0.0.1:" + port + "/health")
+	if err != nil {
+		t.Fatalf("https request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 over https, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get("http://127.0.0.1:" + port + "/health")
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Fatal("expected plain http to be refused")
+		}
+	}
+}
+
+func TestGateway_QueryParametersTakeTheirDTOTypes(t *testing.T) {
+	ts := setupGateway(t)
+
+	call(t, ts, "POST", "/schools", map[string]any{"name": "School 123"})
+	call(t, ts, "POST", "/schools", map[string]any{"name": "Other"})
+
+	// name_contains is a string field even when its value looks like a number
+	code, resp := call(t, ts, "GET", "/schools?name_contains=123&limit=5", nil)
+	var page struct{ Total int64 }
+	_ = json.Unmarshal(resp.Data, &page)
+	if code != http.StatusOK || page.Total != 1 {
+		t.Fatalf("expected one matching school, got %d %s", code, resp.Data)
+	}
+
+	code, resp = call(t, ts, "GET", "/search?query=123", nil)
+	if code != http.StatusOK {
+		t.Fatalf("expected numeric-looking query to search, got %d (%s)", code, resp.Message)
+	}
+
+	for _, path := range []string{"/schools?colour=red", "/schools?limit=-1", "/schools?limit=1.5"} {
+		if code, _ := call(t, ts, "GET", path, nil); code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", path, code)
+		}
+	}
+}
+
+func TestGateway_AttendanceQueryParameters(t *testing.T) {
+	ts := setupGateway(t)
+
+	_, resp := call(t, ts, "POST", "/schools", map[string]any{"name": "S1"})
+	schoolID := id(t, resp)
+	_, resp = call(t, ts, "POST", "/people", map[string]any{"name": "T1", "role": "teacher"})
+	teacherID := id(t, resp)
+	_, resp = call(t, ts, "POST", "/classes", map[string]any{"name": "C1", "school_id": schoolID, "teacher_id": teacherID})
+	classID := id(t, resp)
+	var students []uint
+	for _, name := range []string{"Ann", "Bob"} {
+		_, resp = call(t, ts, "POST", "/people", map[string]any{"name": name, "role": "student"})
+		students = append(students, id(t, resp))
+		call(t, ts, "POST", "/classes/"+itoa(classID)+"/students", map[string]any{"student_id": students[len(students)-1]})
+	}
+
+	today := time.Now()
+	for i, bobStatus := range []string{"present", "absent"} {
+		date := today.AddDate(0, 0, -i).Format("2006-01-02")
+		code, resp := call(t, ts, "PUT", "/classes/"+itoa(classID)+"/attendance", map[string]any{"date": date, "records": []map[string]any{
+			{"student_id": students[0], "status": "present"},
+			{"student_id": students[1], "status": bobStatus},
+		}})
+		if code != http.StatusOK {
+			t.Fatalf("expected attendance recorded, got %d (%s)", code, resp.Message)
+		}
+	}
+
+	code, resp := call(t, ts, "GET", "/classes/"+itoa(classID)+"/attendance/rate?student_id="+itoa(students[1]), nil)
+	var rate struct {
+		StudentID uint     `json:"student_id"`
+		Rate      *float64 `json:"rate"`
+	}
+	_ = json.Unmarshal(resp.Data, &rate)
+	if code != http.StatusOK || rate.StudentID != students[1] || rate.Rate == nil || *rate.Rate != 0.5 {
+		t.Fatalf("expected Bob's rate of 0.5, got %d %s", code, resp.Data)
+	}
+
+	var low []struct {
+		StudentID uint `json:"student_id"`
+	}
+	code, resp = call(t, ts, "GET", "/schools/"+itoa(schoolID)+"/attendance/low?threshold=0.8", nil)
+	_ = json.Unmarshal(resp.Data, &low)
+	if code != http.StatusOK || len(low) != 1 || low[0].StudentID != students[1] {
+		t.Fatalf("expected Bob below 0.8, got %d %s", code, resp.Data)
+	}
+	code, resp = call(t, ts, "GET", "/schools/"+itoa(schoolID)+"/attendance/low?threshold=0.4", nil)
+	low = nil
+	_ = json.Unmarshal(resp.Data, &low)
+	if code != http.StatusOK || len(low) != 0 {
+		t.Fatalf("expected nobody below 0.4, got %d %s", code, resp.Data)
+	}
+}
+
+func TestGateway_WaitlistPositionOfAnotherStudent(t *testing.T) {
+	ts := setupGateway(t)
+
+	_, resp := call(t, ts, "POST", "/schools", map[string]any{"name": "S1"})
+	schoolID := id(t, resp)
+	_, resp = call(t, ts, "POST", "/people", map[string]any{"name": "T1", "role": "teacher"})
+	teacherID := id(t, resp)
+	_, resp = call(t, ts, "POST", "/classes", map[string]any{"name": "C1", "school_id": schoolID, "teacher_id": teacherID, "capacity": 1})
+	classID := id(t, resp)
+	var students []uint
+	for _, name := range []string{"Ann", "Bob", "Cat"} {
+		_, resp = call(t, ts, "POST", "/people", map[string]any{"name": name, "role": "student"})
+		students = append(students, id(t, resp))
+		call(t, ts, "POST", "/classes/"+itoa(classID)+"/students", map[string]any{"student_id": students[len(students)-1]})
+	}
+
+	code, resp := call(t, ts, "GET", "/classes/"+itoa(classID)+"/waitlist/position?student_id="+itoa(students[2]), nil)
+	var pos struct {
+		StudentID uint `json:"student_id"`
+		Position  int  `json:"position"`
+	}
+	_ = json.Unmarshal(resp.Data, &pos)
+	if code != http.StatusOK || pos.StudentID != students[2] || pos.Position != 2 {
+		t.Fatalf("expected Cat second on the waitlist, got %d %s", code, resp.Data)
+	}
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"OldSchool/internal/repository/models"
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+)
+
+func (r *Router) handleCreateAssignmentMethod(req *protocol.Request) protocol.Response {
+	var caDTO dto.CreateAssignmentDTO
+	if err := json.Unmarshal(req.Data, &caDTO); err != nil {
+		return badRequest("invalid json for assignment.create")
+	}
+	created, err := r.assignment.Create(caDTO.ClassID, caDTO.Title, caDTO.Description, caDTO.DueAt)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(created)
+}
+
+func (r *Router) handleAssignmentListMethod(req *protocol.Request) protocol.Response {
+	var alDTO dto.AssignmentListDTO
+	if err := json.Unmarshal(req.Data, &alDTO); err != nil {
+		return badRequest("invalid json for assignment.list")
+	}
+	assignments, err := r.assignment.List(alDTO.ClassID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(assignments)
+}
+
+func (r *Router) handleSubmitMethod(req *protocol.Request, caller *models.Person) protocol.Response {
+	var sDTO dto.SubmitDTO
+	if err := json.Unmarshal(req.Data, &sDTO); err != nil {
+		return badRequest("invalid json for assignment.submit")
+	}
+	submitted, err := r.assignment.Submit(caller.ID, sDTO.AssignmentID, sDTO.Text, sDTO.FileRef)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(submitted)
+}
+
+func (r *Router) handleResubmitMethod(req *protocol.Request, caller *models.Person) protocol.Response {
+	var sDTO dto.SubmitDTO
+	if err := json.Unmarshal(req.Data, &sDTO); err != nil {
+		return badRequest("invalid json for assignment.resubmit")
+	}
+	submitted, err := r.assignment.Resubmit(caller.ID, sDTO.AssignmentID, sDTO.Text, sDTO.FileRef)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(submitted)
+}
+
+func (r *Router) handleSubmissionsMethod(req *protocol.Request) protocol.Response {
+	var sDTO dto.SubmissionsDTO
+	if err := json.Unmarshal(req.Data, &sDTO); err != nil {
+		return badRequest("invalid json for assignment.submissions")
+	}
+	statuses, err := r.assignment.Submissions(sDTO.AssignmentID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(statuses)
+}
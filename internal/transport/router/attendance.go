@@ -0,0 +1,63 @@
+package router
+
+import (
+	"OldSchool/internal/repository/models"
+	"OldSchool/internal/service"
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+)
+
+func (r *Router) handleRecordAttendanceMethod(req *protocol.Request) protocol.Response {
+	var raDTO dto.RecordAttendanceDTO
+	if err := json.Unmarshal(req.Data, &raDTO); err != nil {
+		return badRequest("invalid json for attendance.record")
+	}
+	records := make([]service.AttendanceInput, 0, len(raDTO.Records))
+	for _, rec := range raDTO.Records {
+		records = append(records, service.AttendanceInput{StudentID: rec.StudentID, Status: rec.Status})
+	}
+	if err := r.attendance.Record(raDTO.ClassID, raDTO.Date, records); err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{"status": "recorded", "count": len(records)})
+}
+
+func (r *Router) handleAttendanceSessionMethod(req *protocol.Request) protocol.Response {
+	var asDTO dto.AttendanceSessionDTO
+	if err := json.Unmarshal(req.Data, &asDTO); err != nil {
+		return badRequest("invalid json for attendance.session")
+	}
+	records, err := r.attendance.Session(asDTO.ClassID, asDTO.Date)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(records)
+}
+
+func (r *Router) handleAttendanceRateMethod(req *protocol.Request, caller *models.Person) protocol.Response {
+	var arDTO dto.AttendanceRateDTO
+	if err := json.Unmarshal(req.Data, &arDTO); err != nil {
+		return badRequest("invalid json for attendance.rate")
+	}
+	if arDTO.StudentID == 0 {
+		arDTO.StudentID = caller.ID
+	}
+	rate, err := r.attendance.Rate(arDTO.ClassID, arDTO.StudentID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(rate)
+}
+
+func (r *Router) handleLowAttendanceMethod(req *protocol.Request) protocol.Response {
+	var laDTO dto.LowAttendanceDTO
+	if err := json.Unmarshal(req.Data, &laDTO); err != nil {
+		return badRequest("invalid json for attendance.low")
+	}
+	rates, err := r.attendance.BelowThreshold(laDTO.SchoolID, laDTO.TermID, laDTO.Threshold)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(rates)
+}
@@ -0,0 +1,70 @@
+package router
+
+import (
+	"OldSchool/internal/repository/models"
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+)
+
+func (r *Router) handleLoginMethod(req *protocol.Request) protocol.Response {
+	var lDTO dto.LoginDTO
+	if err := json.Unmarshal(req.Data, &lDTO); err != nil {
+		return badRequest("invalid json for auth.login")
+	}
+
+	token, session, err := r.auth.Login(lDTO.ID, lDTO.Password)
+	if err != nil {
+		return fromServiceError(err)
+	}
+
+	// Connection-oriented transports keep the session for later requests;
+	// stateless ones (HTTP) send the token back on every call instead.
+	if req.Peer != nil {
+		req.Peer.BindSession(token)
+	}
+
+	return ok(map[string]any{
+		"token":      token,
+		"person_id":  session.PersonID,
+		"expires_at": session.ExpiresAt,
+	})
+}
+
+func (r *Router) handleLogoutMethod(req *protocol.Request) protocol.Response {
+	if err := r.auth.Logout(req.Peer.SessionToken()); err != nil {
+		return fromServiceError(err)
+	}
+	req.Peer.BindSession("")
+	return ok(map[string]any{"status": "logged out"})
+}
+
+// handleRevokeSessionsMethod revokes every session of the given person, or of
+// the caller when no person is named.
+func (r *Router) handleRevokeSessionsMethod(req *protocol.Request, caller *models.Person) protocol.Response {
+	var rsDTO dto.RevokeSessionsDTO
+	if len(req.Data) > 0 {
+		if err := json.Unmarshal(req.Data, &rsDTO); err != nil {
+			return badRequest("invalid json for auth.revoke")
+		}
+	}
+	if rsDTO.PersonID == 0 {
+		rsDTO.PersonID = caller.ID
+	}
+	n, err := r.auth.RevokeSessions(rsDTO.PersonID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{"revoked": n})
+}
+
+func (r *Router) handleChangePasswordMethod(req *protocol.Request, caller *models.Person) protocol.Response {
+	var cpDTO dto.ChangePasswordDTO
+	if err := json.Unmarshal(req.Data, &cpDTO); err != nil {
+		return badRequest("invalid json for auth.password")
+	}
+	if err := r.auth.ChangePassword(caller.ID, cpDTO.OldPassword, cpDTO.NewPassword); err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{"status": "password changed"})
+}
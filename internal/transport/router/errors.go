@@ -6,21 +6,69 @@ import (
 	"errors"
 )
 
+var (
+	ErrBadRequest    = errors.New("bad request")
+	ErrUnknownMethod = errors.New("unknown method")
+)
+
+// serviceErrors maps each service error to the message sent to clients and
+// the numeric code used by JSON-RPC. Codes live in the -32000..-32099 range
+// the JSON-RPC specification reserves for server errors.
+var serviceErrors = []struct {
+	err     error
+	message string
+	code    int
+}{
+	{service.ErrInvalidInput, "invalid input", -32001},
+	{service.ErrNotFound, "not found", -32002},
+	{service.ErrRoleMismatch, "role mismatch", -32003},
+	{service.ErrDuplicateEnrollment, "duplicate enrollment", -32004},
+	{service.ErrDifferentSchool, "different school not allowed", -32005},
+	{service.ErrSchoolAlreadyExists, "school already exists", -32006},
+	{service.ErrUnauthenticated, "authentication required", -32007},
+	{service.ErrInvalidCredentials, "invalid credentials", -32008},
+	{service.ErrPermissionDenied, "permission denied", -32009},
+	{service.ErrSchoolHasClasses, "school still has classes", -32010},
+	{service.ErrClassHasStudents, "class still has students", -32011},
+	{service.ErrPersonHasClasses, "person still teaches or attends classes", -32012},
+	{service.ErrNotEnrolled, "student not enrolled", -32013},
+	{service.ErrAlreadyWaitlisted, "already waitlisted", -32014},
+	{service.ErrNotWaitlisted, "not waitlisted", -32015},
+	{service.ErrTermClosed, "term closed", -32016},
+	{service.ErrNoCurrentTerm, "no current term", -32017},
+	{service.ErrTermAlreadyExists, "term already exists", -32018},
+	{service.ErrRoomAlreadyExists, "room already exists", -32019},
+	{service.ErrRoomConflict, "room conflict", -32020},
+	{service.ErrTeacherConflict, "teacher conflict", -32021},
+	{service.ErrStudentConflict, "student conflict", -32022},
+	{service.ErrAssessmentAlreadyExists, "assessment already exists", -32023},
+	{service.ErrAlreadySubmitted, "already submitted", -32024},
+	{service.ErrNotSubmitted, "not submitted", -32025},
+	{service.ErrImportFailed, "import failed", -32026},
+	{service.ErrExportTooLarge, "export too large, use the export command", -32027},
+}
+
 func fromServiceError(err error) protocol.Response {
+	for _, se := range serviceErrors {
+		if errors.Is(err, se.err) {
+			return protocol.Response{Status: false, Message: se.message, Data: nil, Err: err}
+		}
+	}
+	return protocol.Response{Status: false, Message: "internal error", Data: nil, Err: err}
+}
+
+// ErrorCode returns the numeric code for the error behind a failed response.
+func ErrorCode(err error) int {
 	switch {
-	case errors.Is(err, service.ErrInvalidInput):
-		return protocol.Response{Status: false, Message: "invalid input", Data: nil}
-	case errors.Is(err, service.ErrNotFound):
-		return protocol.Response{Status: false, Message: "not found", Data: nil}
-	case errors.Is(err, service.ErrRoleMismatch):
-		return protocol.Response{Status: false, Message: "role mismatch", Data: nil}
-	case errors.Is(err, service.ErrDuplicateEnrollment):
-		return protocol.Response{Status: false, Message: "duplicate enrollment", Data: nil}
-	case errors.Is(err, service.ErrDifferentSchool):
-		return protocol.Response{Status: false, Message: "different school not allowed", Data: nil}
-	case errors.Is(err, service.ErrSchoolAlreadyExists):
-		return protocol.Response{Status: false, Message: "school already exists", Data: nil}
-	default:
-		return protocol.Response{Status: false, Message: "internal error", Data: nil}
+	case errors.Is(err, ErrBadRequest):
+		return protocol.RPCInvalidParams
+	case errors.Is(err, ErrUnknownMethod):
+		return protocol.RPCMethodNotFound
+	}
+	for _, se := range serviceErrors {
+		if errors.Is(err, se.err) {
+			return se.code
+		}
 	}
+	return protocol.RPCInternalError
 }
@@ -0,0 +1,73 @@
+package router
+
+import (
+	"OldSchool/internal/service"
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+var exportContentTypes = map[string]string{
+	service.ExportFormatCSV:    "text/csv; charset=utf-8",
+	service.ExportFormatNDJSON: "application/x-ndjson",
+	service.ExportFormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// maxExportBytes caps an export sent as a response. Half of MaxFrameBytes
+// leaves room for base64 and JSON escaping; bigger exports are for the
+// export command, which streams to a file.
+const maxExportBytes = protocol.MaxFrameBytes / 2
+
+// handleExportMethod answers with the whole export as its document. The
+// xlsx workbook is binary, so it is sent base64 encoded.
+func (r *Router) handleExportMethod(req *protocol.Request) protocol.Response {
+	var eDTO dto.ExportDTO
+	if len(req.Data) > 0 {
+		if err := json.Unmarshal(req.Data, &eDTO); err != nil {
+			return badRequest("invalid json for export")
+		}
+	}
+	if eDTO.Format == "" {
+		eDTO.Format = service.DefaultExportFormat
+	}
+	contentType, known := exportContentTypes[eDTO.Format]
+	if !known {
+		return badRequest("format must be csv, ndjson or xlsx")
+	}
+
+	var b bytes.Buffer
+	err := r.export.Export(&b, eDTO.Format, service.ExportOptions{
+		Tables:   splitList(eDTO.Tables),
+		Columns:  splitList(eDTO.Columns),
+		SchoolID: eDTO.SchoolID,
+		TermID:   eDTO.TermID,
+		Role:     eDTO.Role,
+		MaxBytes: maxExportBytes,
+	})
+	if err != nil {
+		return fromServiceError(err)
+	}
+
+	var doc any = b.String()
+	if eDTO.Format == service.ExportFormatXLSX {
+		doc = b.Bytes()
+	}
+	return ok(map[string]any{
+		"format":       eDTO.Format,
+		"content_type": contentType,
+		"document":     doc,
+	})
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
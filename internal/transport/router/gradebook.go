@@ -0,0 +1,53 @@
+package router
+
+import (
+	"OldSchool/internal/repository/models"
+	"OldSchool/internal/service"
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+)
+
+func (r *Router) handleCreateAssessmentMethod(req *protocol.Request) protocol.Response {
+	var caDTO dto.CreateAssessmentDTO
+	if err := json.Unmarshal(req.Data, &caDTO); err != nil {
+		return badRequest("invalid json for assessment.create")
+	}
+	created, err := r.gradebook.CreateAssessment(caDTO.ClassID, caDTO.Name, caDTO.Weight, caDTO.MaxPoints)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(created)
+}
+
+func (r *Router) handleRecordScoresMethod(req *protocol.Request, caller *models.Person) protocol.Response {
+	var rsDTO dto.RecordScoresDTO
+	if err := json.Unmarshal(req.Data, &rsDTO); err != nil {
+		return badRequest("invalid json for assessment.scores")
+	}
+	scores := make([]service.ScoreInput, 0, len(rsDTO.Scores))
+	for _, s := range rsDTO.Scores {
+		scores = append(scores, service.ScoreInput{StudentID: s.StudentID, Points: s.Points})
+	}
+	if err := r.gradebook.RecordScores(caller.ID, rsDTO.AssessmentID, scores); err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{"status": "recorded", "count": len(rsDTO.Scores)})
+}
+
+func (r *Router) handleGradesMethod(req *protocol.Request, caller *models.Person) protocol.Response {
+	var gDTO dto.GradesDTO
+	if len(req.Data) > 0 {
+		if err := json.Unmarshal(req.Data, &gDTO); err != nil {
+			return badRequest("invalid json for person.grades")
+		}
+	}
+	if gDTO.ID == 0 {
+		gDTO.ID = caller.ID
+	}
+	grades, err := r.gradebook.StudentGrades(gDTO.ID, gDTO.TermID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(grades)
+}
@@ -0,0 +1,75 @@
+package router
+
+import (
+	"OldSchool/internal/repository/models"
+	"OldSchool/internal/service"
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+)
+
+func (r *Router) handleLinkGuardianMethod(req *protocol.Request) protocol.Response {
+	var lgDTO dto.LinkGuardianDTO
+	if err := json.Unmarshal(req.Data, &lgDTO); err != nil {
+		return badRequest("invalid json for guardian.link")
+	}
+	linked, err := r.guardian.Link(lgDTO.GuardianID, lgDTO.StudentID, lgDTO.Relationship, lgDTO.PreferredContact, lgDTO.EmergencyContact, lgDTO.ReceivesReports)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(linked)
+}
+
+func (r *Router) handleUnlinkGuardianMethod(req *protocol.Request) protocol.Response {
+	var lgDTO dto.LinkGuardianDTO
+	if err := json.Unmarshal(req.Data, &lgDTO); err != nil {
+		return badRequest("invalid json for guardian.unlink")
+	}
+	if err := r.guardian.Unlink(lgDTO.GuardianID, lgDTO.StudentID); err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{"status": "unlinked"})
+}
+
+func (r *Router) handleGuardiansMethod(req *protocol.Request) protocol.Response {
+	var gDTO dto.GuardiansDTO
+	if err := json.Unmarshal(req.Data, &gDTO); err != nil {
+		return badRequest("invalid json for person.guardians")
+	}
+	links, err := r.guardian.Guardians(gDTO.ID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(links)
+}
+
+// guardianOf rejects requests naming anyone but the caller or, for a
+// guardian, one of their children.
+func guardianOf(r *Router, caller *models.Person, req *protocol.Request) error {
+	err := selfOnly(r, caller, req)
+	if err == nil || !caller.HasRole(models.RoleGuardian) {
+		return err
+	}
+
+	var target struct {
+		ID        uint `json:"id"`
+		PersonID  uint `json:"person_id"`
+		StudentID uint `json:"student_id"`
+	}
+	if err := json.Unmarshal(req.Data, &target); err != nil {
+		return nil
+	}
+	for _, id := range []uint{target.ID, target.PersonID, target.StudentID} {
+		if id == 0 || id == caller.ID {
+			continue
+		}
+		linked, err := r.guardian.IsGuardianOf(caller.ID, id)
+		if err != nil {
+			return err
+		}
+		if !linked {
+			return service.ErrPermissionDenied
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package router
+
+import (
+	"OldSchool/internal/service"
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// handleImportMethod answers with the import report. When a real run is
+// rolled back the report still comes along so the client sees every row
+// error.
+func (r *Router) handleImportMethod(req *protocol.Request) protocol.Response {
+	var iDTO dto.ImportDTO
+	if err := json.Unmarshal(req.Data, &iDTO); err != nil {
+		return badRequest("invalid json for import")
+	}
+	report, err := r.importer.Import(strings.NewReader(iDTO.CSV), iDTO.DryRun)
+	if errors.Is(err, service.ErrImportFailed) {
+		resp := fromServiceError(err)
+		resp.Message = fmt.Sprintf("import rolled back: %d row errors", len(report.Errors))
+		resp.Data = report
+		return resp
+	}
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(report)
+}
@@ -0,0 +1,76 @@
+package router
+
+import (
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+)
+
+func (r *Router) handleUpdateSchoolMethod(req *protocol.Request) protocol.Response {
+	var usDTO dto.UpdateSchoolDTO
+	if err := json.Unmarshal(req.Data, &usDTO); err != nil {
+		return badRequest("invalid json for school.update")
+	}
+	updated, err := r.school.Update(usDTO.SchoolID, usDTO.Name)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(updated)
+}
+
+func (r *Router) handleDeleteSchoolMethod(req *protocol.Request) protocol.Response {
+	var dsDTO dto.DeleteSchoolDTO
+	if err := json.Unmarshal(req.Data, &dsDTO); err != nil {
+		return badRequest("invalid json for school.delete")
+	}
+	if err := r.school.Delete(dsDTO.SchoolID, dsDTO.Cascade); err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{"status": "deleted"})
+}
+
+func (r *Router) handleUpdatePersonMethod(req *protocol.Request) protocol.Response {
+	var upDTO dto.UpdatePersonDTO
+	if err := json.Unmarshal(req.Data, &upDTO); err != nil {
+		return badRequest("invalid json for person.update")
+	}
+	updated, err := r.person.Update(upDTO.ID, upDTO.Name, upDTO.Roles)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(updated)
+}
+
+func (r *Router) handleDeletePersonMethod(req *protocol.Request) protocol.Response {
+	var dpDTO dto.DeletePersonDTO
+	if err := json.Unmarshal(req.Data, &dpDTO); err != nil {
+		return badRequest("invalid json for person.delete")
+	}
+	if err := r.person.Delete(dpDTO.ID, dpDTO.Cascade); err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{"status": "deleted"})
+}
+
+func (r *Router) handleUpdateClassMethod(req *protocol.Request) protocol.Response {
+	var ucDTO dto.UpdateClassDTO
+	if err := json.Unmarshal(req.Data, &ucDTO); err != nil {
+		return badRequest("invalid json for class.update")
+	}
+	updated, err := r.class.Update(ucDTO.ClassID, ucDTO.Name, ucDTO.TeacherID, ucDTO.Capacity)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(updated)
+}
+
+func (r *Router) handleDeleteClassMethod(req *protocol.Request) protocol.Response {
+	var dcDTO dto.DeleteClassDTO
+	if err := json.Unmarshal(req.Data, &dcDTO); err != nil {
+		return badRequest("invalid json for class.delete")
+	}
+	if err := r.class.Delete(dcDTO.ClassID, dcDTO.Cascade); err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{"status": "deleted"})
+}
@@ -0,0 +1,254 @@
+package router
+
+import (
+	"OldSchool/internal/repository/models"
+	"OldSchool/internal/service"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+)
+
+// permission says who may call a method. Public methods skip authentication
+// altogether. Otherwise the caller must hold at least one of roles, and if
+// check is set it runs for every caller except admins to decide on the
+// request itself.
+type permission struct {
+	public bool
+	roles  []string
+	check  func(r *Router, caller *models.Person, req *protocol.Request) error
+}
+
+// Guardians only hold everyone, so members is what most read methods use to
+// keep them to their own account and the few methods that admit them.
+var (
+	adminOnly = []string{models.RoleAdmin}
+	teaching  = []string{models.RoleAdmin, models.RoleTeacher}
+	faculty   = []string{models.RoleAdmin, models.RoleStaff, models.RoleTeacher}
+	members   = []string{models.RoleAdmin, models.RoleStaff, models.RoleTeacher, models.RoleStudent}
+	everyone  = models.ValidRoles
+)
+
+// permissions lists every method the router answers. A method missing from
+// this table is unknown, so a new handler cannot be reached until it has a
+// rule here.
+var permissions = map[string]permission{
+	CreateSchoolMethod:           {roles: adminOnly},
+	CreatePersonMethod:           {roles: adminOnly},
+	CreateClassMethod:            {roles: adminOnly},
+	AssignTeacherToClassMethod:   {roles: adminOnly},
+	UpdateSchoolMethod:           {roles: adminOnly},
+	DeleteSchoolMethod:           {roles: adminOnly},
+	UpdatePersonMethod:           {roles: adminOnly},
+	DeletePersonMethod:           {roles: adminOnly},
+	UpdateClassMethod:            {roles: adminOnly},
+	DeleteClassMethod:            {roles: adminOnly},
+	TransferMethod:               {roles: adminOnly},
+	TransferHistoryMethod:        {roles: members, check: studentSelfOnly},
+	ClassWaitlistMethod:          {roles: teaching, check: teachesClass},
+	WaitlistPositionMethod:       {roles: members, check: studentSelfOnly},
+	LeaveWaitlistMethod:          {roles: members, check: selfOnly},
+	CreateTermMethod:             {roles: adminOnly},
+	TermListMethod:               {roles: members},
+	CurrentTermMethod:            {roles: members},
+	CreateRoomMethod:             {roles: adminOnly},
+	RoomListMethod:               {roles: members},
+	AddSlotMethod:                {roles: adminOnly},
+	UpdateSlotMethod:             {roles: adminOnly},
+	DeleteSlotMethod:             {roles: adminOnly},
+	ClassSlotsMethod:             {roles: members},
+	TimetableMethod:              {roles: members, check: studentSelfOnly},
+	CreateAssessmentMethod:       {roles: teaching, check: teachesClass},
+	RecordScoresMethod:           {roles: []string{models.RoleTeacher}},
+	GradesMethod:                 {roles: everyone, check: gradesOf},
+	RecordAttendanceMethod:       {roles: teaching, check: teachesClass},
+	AttendanceSessionMethod:      {roles: teaching, check: teachesClass},
+	AttendanceRateMethod:         {roles: everyone, check: attendanceOf},
+	LowAttendanceMethod:          {roles: faculty},
+	CreateAssignmentMethod:       {roles: teaching, check: teachesClass},
+	AssignmentListMethod:         {roles: members, check: inClass},
+	SubmitMethod:                 {roles: []string{models.RoleStudent}},
+	ResubmitMethod:               {roles: []string{models.RoleStudent}},
+	SubmissionsMethod:            {roles: teaching, check: teachesAssignmentClass},
+	ReportCardMethod:             {roles: members, check: studentSelfOnly},
+	ReportCommentMethod:          {roles: teaching, check: teachesClass},
+	LinkGuardianMethod:           {roles: []string{models.RoleAdmin, models.RoleStaff}},
+	UnlinkGuardianMethod:         {roles: []string{models.RoleAdmin, models.RoleStaff}},
+	GuardiansMethod:              {roles: faculty},
+	SearchMethod:                 {roles: faculty},
+	ImportMethod:                 {roles: adminOnly},
+	ExportMethod:                 {roles: []string{models.RoleAdmin, models.RoleStaff}},
+	AddStudentToClassMethod:      {roles: teaching, check: teachesClass},
+	RemoveStudentFromClassMethod: {roles: teaching, check: teachesClass},
+	ClassStudentsMethod:          {roles: teaching, check: teachesClass},
+	SchoolListMethod:             {roles: members},
+	SchoolClassesMethod:          {roles: members},
+	WhoAmIMethod:                 {roles: everyone, check: studentSelfOnly},
+	BatchMethod:                  {roles: everyone},
+	LogoutMethod:                 {roles: everyone},
+	RevokeSessionsMethod:         {roles: everyone, check: selfOnly},
+	ChangePasswordMethod:         {roles: everyone},
+	LoginMethod:                  {public: true},
+	HealthMethod:                 {public: true},
+}
+
+// authorize authenticates the request's peer and checks it against the
+// permission table. The caller is nil for public methods.
+func (r *Router) authorize(req *protocol.Request) (*models.Person, error) {
+	perm, known := permissions[req.Method]
+	if !known {
+		return nil, ErrUnknownMethod
+	}
+	if perm.public {
+		return nil, nil
+	}
+
+	caller, _, err := r.auth.Authenticate(req.Peer.SessionToken())
+	if err != nil {
+		return nil, err
+	}
+
+	if !caller.HasAnyRole(perm.roles...) {
+		return nil, service.ErrPermissionDenied
+	}
+	if perm.check != nil && !caller.HasRole(models.RoleAdmin) {
+		if err := perm.check(r, caller, req); err != nil {
+			return nil, err
+		}
+	}
+	return caller, nil
+}
+
+// teachesClass lets a teacher act only on classes they teach. Malformed data
+// is left for the handler to reject.
+func teachesClass(r *Router, caller *models.Person, req *protocol.Request) error {
+	var target struct {
+		ClassID uint `json:"class_id"`
+	}
+	if err := json.Unmarshal(req.Data, &target); err != nil {
+		return nil
+	}
+	teaches, err := r.class.IsTeacherOf(caller.ID, target.ClassID)
+	if err != nil {
+		return err
+	}
+	if !teaches {
+		return service.ErrPermissionDenied
+	}
+	return nil
+}
+
+// inClass lets teachers and students act only on classes they teach or are
+// enrolled in. Staff are not restricted.
+func inClass(r *Router, caller *models.Person, req *protocol.Request) error {
+	if caller.HasRole(models.RoleStaff) {
+		return nil
+	}
+	var target struct {
+		ClassID uint `json:"class_id"`
+	}
+	if err := json.Unmarshal(req.Data, &target); err != nil {
+		return nil
+	}
+	teaches, err := r.class.IsTeacherOf(caller.ID, target.ClassID)
+	if err != nil || teaches {
+		return err
+	}
+	enrolled, err := r.class.IsEnrolledIn(caller.ID, target.ClassID)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		return service.ErrPermissionDenied
+	}
+	return nil
+}
+
+// teachesAssignmentClass is teachesClass for requests that name an
+// assignment instead of its class.
+func teachesAssignmentClass(r *Router, caller *models.Person, req *protocol.Request) error {
+	var target struct {
+		AssignmentID uint `json:"assignment_id"`
+	}
+	if err := json.Unmarshal(req.Data, &target); err != nil {
+		return nil
+	}
+	classID, err := r.assignment.ClassIDOf(target.AssignmentID)
+	if err != nil {
+		return err
+	}
+	teaches, err := r.class.IsTeacherOf(caller.ID, classID)
+	if err != nil {
+		return err
+	}
+	if !teaches {
+		return service.ErrPermissionDenied
+	}
+	return nil
+}
+
+// gradesOf lets staff read anyone's grades and teachers those of students
+// in their classes. Everyone else goes through guardianOf.
+func gradesOf(r *Router, caller *models.Person, req *protocol.Request) error {
+	if caller.HasRole(models.RoleStaff) {
+		return nil
+	}
+	if caller.HasRole(models.RoleTeacher) {
+		var target struct {
+			ID uint `json:"id"`
+		}
+		if err := json.Unmarshal(req.Data, &target); err != nil {
+			return nil
+		}
+		if target.ID == 0 || target.ID == caller.ID {
+			return nil
+		}
+		teaches, err := r.class.TeachesStudent(caller.ID, target.ID)
+		if err != nil || teaches {
+			return err
+		}
+	}
+	return guardianOf(r, caller, req)
+}
+
+// attendanceOf lets staff read any attendance rate and teachers the rates in
+// classes they teach. Everyone else goes through guardianOf.
+func attendanceOf(r *Router, caller *models.Person, req *protocol.Request) error {
+	if caller.HasRole(models.RoleStaff) {
+		return nil
+	}
+	if caller.HasRole(models.RoleTeacher) && teachesClass(r, caller, req) == nil {
+		return nil
+	}
+	return guardianOf(r, caller, req)
+}
+
+// studentSelfOnly keeps students from looking up anyone but themselves. A
+// student who also teaches or works for the school is not restricted.
+func studentSelfOnly(r *Router, caller *models.Person, req *protocol.Request) error {
+	if caller.HasAnyRole(models.RoleTeacher, models.RoleStaff) {
+		return nil
+	}
+	return selfOnly(r, caller, req)
+}
+
+// selfOnly rejects requests naming a person other than the caller. The id
+// field may be called "id", "person_id" or "student_id"; leaving it out means
+// the caller.
+func selfOnly(_ *Router, caller *models.Person, req *protocol.Request) error {
+	if len(req.Data) == 0 {
+		return nil
+	}
+	var target struct {
+		ID        uint `json:"id"`
+		PersonID  uint `json:"person_id"`
+		StudentID uint `json:"student_id"`
+	}
+	if err := json.Unmarshal(req.Data, &target); err != nil {
+		return nil
+	}
+	for _, id := range []uint{target.ID, target.PersonID, target.StudentID} {
+		if id != 0 && id != caller.ID {
+			return service.ErrPermissionDenied
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package router
+
+import (
+	"OldSchool/internal/repository/models"
+	"OldSchool/internal/service"
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+)
+
+var reportContentTypes = map[string]string{
+	service.ReportFormatText: "text/plain; charset=utf-8",
+	service.ReportFormatHTML: "text/html; charset=utf-8",
+}
+
+// handleReportCardMethod answers with the report card itself, or for the
+// text and html formats with the rendered document and its content type.
+func (r *Router) handleReportCardMethod(req *protocol.Request, caller *models.Person) protocol.Response {
+	var rcDTO dto.ReportCardDTO
+	if len(req.Data) > 0 {
+		if err := json.Unmarshal(req.Data, &rcDTO); err != nil {
+			return badRequest("invalid json for person.report-card")
+		}
+	}
+	if rcDTO.ID == 0 {
+		rcDTO.ID = caller.ID
+	}
+	if rcDTO.Format == "" {
+		rcDTO.Format = service.ReportFormatJSON
+	}
+	contentType, known := reportContentTypes[rcDTO.Format]
+	if !known && rcDTO.Format != service.ReportFormatJSON {
+		return badRequest("format must be json, text or html")
+	}
+
+	card, err := r.reportCard.Build(rcDTO.ID, rcDTO.TermID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	if rcDTO.Format == service.ReportFormatJSON {
+		return ok(card)
+	}
+
+	doc, err := card.Render(rcDTO.Format)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{
+		"format":       rcDTO.Format,
+		"content_type": contentType,
+		"document":     doc,
+	})
+}
+
+func (r *Router) handleReportCommentMethod(req *protocol.Request, caller *models.Person) protocol.Response {
+	var rcDTO dto.ReportCommentDTO
+	if err := json.Unmarshal(req.Data, &rcDTO); err != nil {
+		return badRequest("invalid json for person.report-card.comment")
+	}
+	if err := r.reportCard.Comment(caller.ID, rcDTO.ClassID, rcDTO.StudentID, rcDTO.Comment); err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{"status": "saved"})
+}
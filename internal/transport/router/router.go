@@ -1,44 +1,125 @@
 package router
 
 import (
+	"OldSchool/internal/repository"
+	"OldSchool/internal/repository/models"
 	"OldSchool/internal/service"
 	"OldSchool/internal/transport/dto"
 	"OldSchool/internal/transport/protocol"
 	"encoding/json"
+	"errors"
+	"fmt"
 )
 
 const (
-	CreateSchoolMethod         = "/school/create"
-	CreateClassMethod          = "/class/create"
-	CreatePersonMethod         = "/person/create"
-	AddStudentToClassMethod    = "/class/add/student"
-	WhoAmIMethod               = "/who/am/i"
-	SchoolListMethod           = "/school/list"
-	SchoolClassesMethod        = "/school/classes"
-	ClassStudentsMethod        = "/class/students"
-	AssignTeacherToClassMethod = "/class/assign/teacher"
+	CreateSchoolMethod           = "/school/create"
+	CreateClassMethod            = "/class/create"
+	CreatePersonMethod           = "/person/create"
+	AddStudentToClassMethod      = "/class/add/student"
+	RemoveStudentFromClassMethod = "/class/remove/student"
+	WhoAmIMethod                 = "/who/am/i"
+	SchoolListMethod             = "/school/list"
+	SchoolClassesMethod          = "/school/classes"
+	ClassStudentsMethod          = "/class/students"
+	AssignTeacherToClassMethod   = "/class/assign/teacher"
+	BatchMethod                  = "/batch"
+	LoginMethod                  = "/auth/login"
+	LogoutMethod                 = "/auth/logout"
+	RevokeSessionsMethod         = "/auth/revoke"
+	ChangePasswordMethod         = "/auth/password"
+	HealthMethod                 = "/health"
+	UpdateSchoolMethod           = "/school/update"
+	DeleteSchoolMethod           = "/school/delete"
+	UpdatePersonMethod           = "/person/update"
+	DeletePersonMethod           = "/person/delete"
+	UpdateClassMethod            = "/class/update"
+	DeleteClassMethod            = "/class/delete"
+	TransferMethod               = "/person/transfer"
+	TransferHistoryMethod        = "/person/transfers"
+	ClassWaitlistMethod          = "/class/waitlist"
+	WaitlistPositionMethod       = "/class/waitlist/position"
+	LeaveWaitlistMethod          = "/class/waitlist/leave"
+	CreateTermMethod             = "/term/create"
+	TermListMethod               = "/term/list"
+	CurrentTermMethod            = "/term/current"
+	CreateRoomMethod             = "/room/create"
+	RoomListMethod               = "/room/list"
+	AddSlotMethod                = "/class/slot/add"
+	UpdateSlotMethod             = "/class/slot/update"
+	DeleteSlotMethod             = "/class/slot/delete"
+	ClassSlotsMethod             = "/class/slots"
+	TimetableMethod              = "/person/timetable"
+	CreateAssessmentMethod       = "/assessment/create"
+	RecordScoresMethod           = "/assessment/scores"
+	GradesMethod                 = "/person/grades"
+	RecordAttendanceMethod       = "/attendance/record"
+	AttendanceSessionMethod      = "/attendance/session"
+	AttendanceRateMethod         = "/attendance/rate"
+	LowAttendanceMethod          = "/attendance/low"
+	CreateAssignmentMethod       = "/assignment/create"
+	AssignmentListMethod         = "/assignment/list"
+	SubmitMethod                 = "/assignment/submit"
+	ResubmitMethod               = "/assignment/resubmit"
+	SubmissionsMethod            = "/assignment/submissions"
+	ReportCardMethod             = "/person/report-card"
+	ReportCommentMethod          = "/person/report-card/comment"
+	LinkGuardianMethod           = "/guardian/link"
+	UnlinkGuardianMethod         = "/guardian/unlink"
+	GuardiansMethod              = "/person/guardians"
+	SearchMethod                 = "/search"
+	ImportMethod                 = "/import"
+	ExportMethod                 = "/export"
 )
 
 type Router struct {
-	school *service.SchoolService
-	person *service.PersonService
-	class  *service.ClassService
+	school     *service.SchoolService
+	person     *service.PersonService
+	class      *service.ClassService
+	auth       *service.AuthService
+	transfer   *service.TransferService
+	term       *service.TermService
+	timetable  *service.TimetableService
+	gradebook  *service.GradebookService
+	attendance *service.AttendanceService
+	assignment *service.AssignmentService
+	reportCard *service.ReportCardService
+	guardian   *service.GuardianService
+	search     *service.SearchService
+	importer   *service.ImportService
+	export     *service.ExportService
+	uow        service.UnitOfWork
 }
 
-func NewRouter(school *service.SchoolService, person *service.PersonService, class *service.ClassService) *Router {
+func NewRouter(s *service.Services) *Router {
 	return &Router{
-		school: school,
-		person: person,
-		class:  class,
+		school:     s.School,
+		person:     s.Person,
+		class:      s.Class,
+		auth:       s.Auth,
+		transfer:   s.Transfer,
+		term:       s.Term,
+		timetable:  s.Timetable,
+		gradebook:  s.Gradebook,
+		attendance: s.Attendance,
+		assignment: s.Assignment,
+		reportCard: s.ReportCard,
+		guardian:   s.Guardian,
+		search:     s.Search,
+		importer:   s.Import,
+		export:     s.Export,
+		uow:        s.UnitOfWork,
 	}
 }
 
+// errBatchAborted stops an atomic batch so its transaction is rolled back.
+var errBatchAborted = errors.New("batch aborted")
+
 func ok(data any) protocol.Response {
 	return protocol.Response{Status: true, Message: "ok", Data: data}
 }
 
 func badRequest(msg string) protocol.Response {
-	return protocol.Response{Status: false, Message: msg, Data: nil}
+	return protocol.Response{Status: false, Message: msg, Data: nil, Err: ErrBadRequest}
 }
 
 func (r *Router) handleCreateSchoolMethod(req *protocol.Request) protocol.Response {
@@ -58,7 +139,17 @@ func (r *Router) handleCreatePersonMethod(req *protocol.Request) protocol.Respon
 	if err := json.Unmarshal(req.Data, &cpDTO); err != nil {
 		return badRequest("inavlid json for person.create")
 	}
-	created, err := r.person.Create(cpDTO.Name, cpDTO.Role)
+	roles := cpDTO.Roles
+	if cpDTO.Role != "" {
+		roles = append(roles, cpDTO.Role)
+	}
+	var created *models.Person
+	var err error
+	if cpDTO.Password != "" {
+		created, err = r.person.CreateWithPassword(cpDTO.Name, cpDTO.Password, roles...)
+	} else {
+		created, err = r.person.Create(cpDTO.Name, roles...)
+	}
 	if err != nil {
 		return fromServiceError(err)
 	}
@@ -71,7 +162,7 @@ func (r *Router) handleCreateClassMethod(req *protocol.Request) protocol.Respons
 		return badRequest("invalid json for class.create")
 	}
 
-	created, err := r.class.Create(ccDTo.Name, ccDTo.SchoolID, ccDTo.TeacherID)
+	created, err := r.class.CreateInTerm(ccDTo.Name, ccDTo.SchoolID, ccDTo.TeacherID, ccDTo.TermID, ccDTo.Capacity)
 	if err != nil {
 		return fromServiceError(err)
 	}
@@ -83,30 +174,71 @@ func (r *Router) handleAddStudentToClassMethod(req *protocol.Request) protocol.R
 	if err := json.Unmarshal(req.Data, &astcDTO); err != nil {
 		return badRequest("invalid json for class.add.student")
 	}
-	if err := r.class.AddStudentToClass(astcDTO.StudentID, astcDTO.ClassID); err != nil {
+	position, err := r.class.Enroll(astcDTO.StudentID, astcDTO.ClassID)
+	if err != nil {
 		return fromServiceError(err)
 	}
+	if position > 0 {
+		return ok(map[string]any{"status": "waitlisted", "position": position})
+	}
 	return ok(map[string]any{"status": "enrolled"})
 }
 
-func (r *Router) handleWhoAmIMethod(req *protocol.Request) protocol.Response {
+func (r *Router) handleRemoveStudentFromClassMethod(req *protocol.Request) protocol.Response {
+	var rsDTO dto.RemoveStudentFromClassDTO
+	if err := json.Unmarshal(req.Data, &rsDTO); err != nil {
+		return badRequest("invalid json for class.remove.student")
+	}
+	if err := r.class.RemoveStudentFromClass(rsDTO.StudentID, rsDTO.ClassID); err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{"status": "unenrolled"})
+}
+
+func (r *Router) handleWhoAmIMethod(req *protocol.Request, caller *models.Person) protocol.Response {
 	var wai dto.WhoAmIDTO
-	if err := json.Unmarshal(req.Data, &wai); err != nil {
-		return badRequest("inavlid json for who.am.i")
+	if len(req.Data) > 0 {
+		if err := json.Unmarshal(req.Data, &wai); err != nil {
+			return badRequest("inavlid json for who.am.i")
+		}
 	}
-	person, classIDs, err := r.person.WhoAmI(wai.ID)
+	if wai.ID == 0 {
+		wai.ID = caller.ID
+	}
+	person, memberships, err := r.person.WhoAmIInTerm(wai.ID, wai.TermID)
 	if err != nil {
 		return fromServiceError(err)
 	}
-	return ok(map[string]any{
-		"person":    person,
-		"class_ids": classIDs,
-	})
+	resp := map[string]any{
+		"person":      person,
+		"class_ids":   memberships.ClassIDs(),
+		"memberships": memberships,
+	}
+	if person.HasRole(models.RoleGuardian) {
+		children, err := r.guardian.Children(person.ID, wai.TermID)
+		if err != nil {
+			return fromServiceError(err)
+		}
+		resp["children"] = children
+	}
+	return ok(resp)
+
+}
 
+func listOptions(p dto.PageDTO) repository.ListOptions {
+	return repository.ListOptions{Limit: p.Limit, After: p.After, Sort: p.Sort}
 }
 
-func (r *Router) handleSchoolListMethod() protocol.Response {
-	schools, err := r.school.List()
+func (r *Router) handleSchoolListMethod(req *protocol.Request) protocol.Response {
+	var slDTO dto.SchoolListDTO
+	if len(req.Data) > 0 {
+		if err := json.Unmarshal(req.Data, &slDTO); err != nil {
+			return badRequest("invalid input for school.list")
+		}
+	}
+	opts := listOptions(slDTO.PageDTO)
+	opts.NameContains = slDTO.NameContains
+	schools, err := r.school.List(opts)
 	if err != nil {
 		return fromServiceError(err)
 	}
@@ -118,7 +250,10 @@ func (r *Router) handleSchoolClassesMethod(req *protocol.Request) protocol.Respo
 	if err := json.Unmarshal(req.Data, &scDTO); err != nil {
 		return badRequest("invalid input for school.classes")
 	}
-	classes, err := r.school.ListClasses(scDTO.SchoolID)
+	opts := listOptions(scDTO.PageDTO)
+	opts.NameContains = scDTO.NameContains
+	opts.TeacherID = scDTO.TeacherID
+	classes, err := r.school.ListClassesPage(scDTO.SchoolID, scDTO.TermID, opts)
 	if err != nil {
 		return fromServiceError(err)
 	}
@@ -130,7 +265,11 @@ func (r *Router) handleClassStudentsMethod(req *protocol.Request) protocol.Respo
 	if err := json.Unmarshal(req.Data, &csDTO); err != nil {
 		return badRequest("invalid input for class.students")
 	}
-	students, err := r.class.ListStudents(csDTO.ClassID)
+	opts := listOptions(csDTO.PageDTO)
+	opts.NameContains = csDTO.NameContains
+	opts.Role = csDTO.Role
+	opts.SchoolID = csDTO.SchoolID
+	students, err := r.class.ListStudentsPage(csDTO.ClassID, opts)
 	if err != nil {
 		return fromServiceError(err)
 	}
@@ -150,7 +289,97 @@ func (r *Router) handleAssignTeacherToClassMethod(req *protocol.Request) protoco
 	return ok(map[string]any{"status": "teacher assigned"})
 }
 
+func (r *Router) handleBatchMethod(req *protocol.Request) protocol.Response {
+	var bDTO dto.BatchDTO
+	if err := json.Unmarshal(req.Data, &bDTO); err != nil {
+		return badRequest("invalid json for batch")
+	}
+	if len(bDTO.Requests) == 0 {
+		return badRequest("empty batch")
+	}
+
+	if !bDTO.Atomic {
+		return ok(r.runBatch(req.Peer, bDTO.Requests, false))
+	}
+
+	var results []protocol.Response
+	err := r.uow.WithinTx(func(repos repository.Repos) error {
+		results = NewRouter(service.NewServices(repos)).runBatch(req.Peer, bDTO.Requests, true)
+		if last := results[len(results)-1]; !last.Status {
+			return errBatchAborted
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchAborted) {
+		last := results[len(results)-1]
+		return protocol.Response{
+			Status:  false,
+			Message: fmt.Sprintf("batch rolled back at step %d: %s", len(results)-1, last.Message),
+			Data:    results,
+			Err:     last.Err,
+		}
+	}
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(results)
+}
+
+// runBatch handles each sub-request in order, on behalf of the peer that sent
+// the batch. When stopOnError is set it returns right after the first failed
+// step.
+func (r *Router) runBatch(peer *protocol.Peer, reqs []protocol.Request, stopOnError bool) []protocol.Response {
+	results := make([]protocol.Response, 0, len(reqs))
+	for i := range reqs {
+		sub := &reqs[i]
+		sub.Peer = peer
+
+		var resp protocol.Response
+		if sub.Method == BatchMethod {
+			resp = badRequest("nested batch not allowed")
+			resp.ID = sub.ID
+		} else {
+			resp = r.Handle(sub)
+		}
+
+		results = append(results, resp)
+		if stopOnError && !resp.Status {
+			break
+		}
+	}
+	return results
+}
+
+// Handle runs a single request and returns its response. The request ID, if
+// any, is copied onto the response so pipelined callers can match them up.
+//
+// Every request is checked against the permission table before it reaches
+// its handler.
 func (r *Router) Handle(req *protocol.Request) protocol.Response {
+	var resp protocol.Response
+	caller, err := r.authorize(req)
+	switch {
+	case errors.Is(err, ErrUnknownMethod):
+		resp = unknownMethod()
+	case err != nil:
+		resp = fromServiceError(err)
+	default:
+		resp = r.dispatch(req, caller)
+	}
+	resp.ID = req.ID
+	return resp
+}
+
+func unknownMethod() protocol.Response {
+	return protocol.Response{
+		Status:  false,
+		Message: "unknown method",
+		Data:    nil,
+		Err:     ErrUnknownMethod,
+	}
+}
+
+func (r *Router) dispatch(req *protocol.Request, caller *models.Person) protocol.Response {
 	switch req.Method {
 	case CreateSchoolMethod:
 		return r.handleCreateSchoolMethod(req)
@@ -160,21 +389,113 @@ func (r *Router) Handle(req *protocol.Request) protocol.Response {
 		return r.handleCreateClassMethod(req)
 	case AddStudentToClassMethod:
 		return r.handleAddStudentToClassMethod(req)
+	case RemoveStudentFromClassMethod:
+		return r.handleRemoveStudentFromClassMethod(req)
 	case WhoAmIMethod:
-		return r.handleWhoAmIMethod(req)
+		return r.handleWhoAmIMethod(req, caller)
 	case SchoolListMethod:
-		return r.handleSchoolListMethod()
+		return r.handleSchoolListMethod(req)
 	case SchoolClassesMethod:
 		return r.handleSchoolClassesMethod(req)
 	case ClassStudentsMethod:
 		return r.handleClassStudentsMethod(req)
 	case AssignTeacherToClassMethod:
 		return r.handleAssignTeacherToClassMethod(req)
+	case BatchMethod:
+		return r.handleBatchMethod(req)
+	case LoginMethod:
+		return r.handleLoginMethod(req)
+	case LogoutMethod:
+		return r.handleLogoutMethod(req)
+	case RevokeSessionsMethod:
+		return r.handleRevokeSessionsMethod(req, caller)
+	case ChangePasswordMethod:
+		return r.handleChangePasswordMethod(req, caller)
+	case HealthMethod:
+		return ok(map[string]any{"status": "up"})
+	case UpdateSchoolMethod:
+		return r.handleUpdateSchoolMethod(req)
+	case DeleteSchoolMethod:
+		return r.handleDeleteSchoolMethod(req)
+	case UpdatePersonMethod:
+		return r.handleUpdatePersonMethod(req)
+	case DeletePersonMethod:
+		return r.handleDeletePersonMethod(req)
+	case UpdateClassMethod:
+		return r.handleUpdateClassMethod(req)
+	case DeleteClassMethod:
+		return r.handleDeleteClassMethod(req)
+	case TransferMethod:
+		return r.handleTransferMethod(req)
+	case TransferHistoryMethod:
+		return r.handleTransferHistoryMethod(req, caller)
+	case ClassWaitlistMethod:
+		return r.handleClassWaitlistMethod(req)
+	case WaitlistPositionMethod:
+		return r.handleWaitlistPositionMethod(req, caller)
+	case LeaveWaitlistMethod:
+		return r.handleLeaveWaitlistMethod(req, caller)
+	case CreateTermMethod:
+		return r.handleCreateTermMethod(req)
+	case TermListMethod:
+		return r.handleTermListMethod(req)
+	case CurrentTermMethod:
+		return r.handleCurrentTermMethod(req)
+	case CreateRoomMethod:
+		return r.handleCreateRoomMethod(req)
+	case RoomListMethod:
+		return r.handleRoomListMethod(req)
+	case AddSlotMethod:
+		return r.handleAddSlotMethod(req)
+	case UpdateSlotMethod:
+		return r.handleUpdateSlotMethod(req)
+	case DeleteSlotMethod:
+		return r.handleDeleteSlotMethod(req)
+	case ClassSlotsMethod:
+		return r.handleClassSlotsMethod(req)
+	case TimetableMethod:
+		return r.handleTimetableMethod(req, caller)
+	case CreateAssessmentMethod:
+		return r.handleCreateAssessmentMethod(req)
+	case RecordScoresMethod:
+		return r.handleRecordScoresMethod(req, caller)
+	case GradesMethod:
+		return r.handleGradesMethod(req, caller)
+	case RecordAttendanceMethod:
+		return r.handleRecordAttendanceMethod(req)
+	case AttendanceSessionMethod:
+		return r.handleAttendanceSessionMethod(req)
+	case AttendanceRateMethod:
+		return r.handleAttendanceRateMethod(req, caller)
+	case LowAttendanceMethod:
+		return r.handleLowAttendanceMethod(req)
+	case CreateAssignmentMethod:
+		return r.handleCreateAssignmentMethod(req)
+	case AssignmentListMethod:
+		return r.handleAssignmentListMethod(req)
+	case SubmitMethod:
+		return r.handleSubmitMethod(req, caller)
+	case ResubmitMethod:
+		return r.handleResubmitMethod(req, caller)
+	case SubmissionsMethod:
+		return r.handleSubmissionsMethod(req)
+	case ReportCardMethod:
+		return r.handleReportCardMethod(req, caller)
+	case ReportCommentMethod:
+		return r.handleReportCommentMethod(req, caller)
+	case LinkGuardianMethod:
+		return r.handleLinkGuardianMethod(req)
+	case UnlinkGuardianMethod:
+		return r.handleUnlinkGuardianMethod(req)
+	case GuardiansMethod:
+		return r.handleGuardiansMethod(req)
+	case SearchMethod:
+		return r.handleSearchMethod(req)
+	case ImportMethod:
+		return r.handleImportMethod(req)
+	case ExportMethod:
+		return r.handleExportMethod(req)
 	default:
-		return protocol.Response{
-			Status:  false,
-			Message: "unknown method",
-			Data:    nil,
-		}
+		return unknownMethod()
 	}
 }
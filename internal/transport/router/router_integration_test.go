@@ -2,7 +2,9 @@ package router_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"OldSchool/internal/repository"
@@ -12,7 +14,23 @@ import (
 	"OldSchool/internal/transport/router"
 )
 
-func setupRouter(t *testing.T) *router.Router {
+const adminPassword = "admin-password"
+
+// testRouter sends every request as the admin created by setupRouter unless
+// the request already carries a peer.
+type testRouter struct {
+	*router.Router
+	admin *protocol.Peer
+}
+
+func (tr testRouter) Handle(req *protocol.Request) protocol.Response {
+	if req.Peer == nil {
+		req.Peer = tr.admin
+	}
+	return tr.Router.Handle(req)
+}
+
+func setupRouter(t *testing.T) testRouter {
 	t.Helper()
 
 	dbPath := filepath.Join(t.TempDir(), "test.db")
@@ -27,17 +45,24 @@ func setupRouter(t *testing.T) *router.Router {
 	}
 	t.Cleanup(func() { _ = sqlDB.Close() })
 
-	schoolRepo := repository.NewSchoolRepository(db)
-	personRepo := repository.NewPersonRepositrory(db)
-	classRepo := repository.NewClassRepository(db)
-	enrollRepo := repository.NewEnrollmentRepository(db)
-	uow := repository.NewUnitOfWork(db)
+	services := service.NewServices(repository.NewRepos(db))
+	admin, err := services.Auth.Bootstrap("admin", adminPassword)
+	if err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
 
-	schoolSvc := service.NewSchoolService(schoolRepo , classRepo)
-	personSvc := service.NewPersonService(personRepo, classRepo, enrollRepo)
-	classSvc := service.NewClassService(classRepo, personRepo, uow, enrollRepo)
+	r := router.NewRouter(services)
+	peer := &protocol.Peer{}
+	resp := r.Handle(&protocol.Request{
+		Method: router.LoginMethod,
+		Data:   mustJSON(t, map[string]any{"id": admin.ID, "password": adminPassword}),
+		Peer:   peer,
+	})
+	if !resp.Status {
+		t.Fatalf("admin login failed: %q", resp.Message)
+	}
 
-	return router.NewRouter(schoolSvc, personSvc, classSvc)
+	return testRouter{Router: r, admin: peer}
 }
 
 func mustJSON(t *testing.T, v any) json.RawMessage {
@@ -162,3 +187,751 @@ func TestRouter_WhoAmI_TeacherAndStudent(t *testing.T) {
 		t.Fatalf("unexpected student classes: %v", classIDsStudent)
 	}
 }
+
+func TestRouter_Batch_NonAtomicKeepsSuccessfulSteps(t *testing.T) {
+	r := setupRouter(t)
+
+	resp := r.Handle(&protocol.Request{
+		Method: router.BatchMethod,
+		Data: mustJSON(t, map[string]any{
+			"requests": []map[string]any{
+				{"id": 1, "method": router.CreateSchoolMethod, "data": map[string]any{"name": "S1"}},
+				{"id": 2, "method": router.CreateSchoolMethod, "data": map[string]any{"name": "S1"}},
+				{"id": 3, "method": router.CreateSchoolMethod, "data": map[string]any{"name": "S2"}},
+			},
+		}),
+	})
+	if !resp.Status {
+		t.Fatalf("expected status=true, got %q", resp.Message)
+	}
+
+	results := resp.Data.([]protocol.Response)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if !results[0].Status || results[1].Status || !results[2].Status {
+		t.Fatalf("unexpected step statuses: %+v", results)
+	}
+	if string(results[1].ID) != "2" {
+		t.Fatalf("expected step id 2, got %s", results[1].ID)
+	}
+
+	list := r.Handle(&protocol.Request{Method: router.SchoolListMethod})
+	if schools := list.Data.(repository.Page[models.School]); len(schools.Items) != 2 {
+		t.Fatalf("expected 2 schools, got %d", len(schools.Items))
+	}
+}
+
+func TestRouter_Batch_AtomicRollsBack(t *testing.T) {
+	r := setupRouter(t)
+
+	resp := r.Handle(&protocol.Request{
+		Method: router.BatchMethod,
+		Data: mustJSON(t, map[string]any{
+			"atomic": true,
+			"requests": []map[string]any{
+				{"method": router.CreateSchoolMethod, "data": map[string]any{"name": "S1"}},
+				{"method": router.CreatePersonMethod, "data": map[string]any{"name": "T1", "role": "teacher"}},
+				{"method": router.CreateClassMethod, "data": map[string]any{"name": "C1", "school_id": 1, "teacher_id": 999}},
+				{"method": router.CreateSchoolMethod, "data": map[string]any{"name": "S2"}},
+			},
+		}),
+	})
+	if resp.Status {
+		t.Fatalf("expected atomic batch to fail")
+	}
+	if results := resp.Data.([]protocol.Response); len(results) != 3 {
+		t.Fatalf("expected batch to stop after 3 steps, got %d", len(results))
+	}
+
+	list := r.Handle(&protocol.Request{Method: router.SchoolListMethod})
+	if schools := list.Data.(repository.Page[models.School]); len(schools.Items) != 0 {
+		t.Fatalf("expected rollback to leave no schools, got %d", len(schools.Items))
+	}
+
+	// person 1 is the admin, so T1 would have been person 2
+	resp = r.Handle(&protocol.Request{
+		Method: router.WhoAmIMethod,
+		Data:   mustJSON(t, map[string]any{"id": 2}),
+	})
+	if resp.Status || resp.Message != "not found" {
+		t.Fatalf("expected person created in rolled back batch to be gone, got %q", resp.Message)
+	}
+}
+
+func TestRouter_RejectsUnauthenticatedCalls(t *testing.T) {
+	r := setupRouter(t)
+
+	anon := &protocol.Peer{}
+	resp := r.Handle(&protocol.Request{
+		Method: router.CreateSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"name": "S1"}),
+		Peer:   anon,
+	})
+	if resp.Status || resp.Message != "authentication required" {
+		t.Fatalf("expected authentication required, got %q", resp.Message)
+	}
+
+	resp = r.Handle(&protocol.Request{Method: router.HealthMethod, Peer: anon})
+	if !resp.Status {
+		t.Fatalf("expected health check to be public, got %q", resp.Message)
+	}
+}
+
+func TestRouter_LoginBindsSessionToPeer(t *testing.T) {
+	r := setupRouter(t)
+
+	resp := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "T1", "role": "teacher", "password": "teacher-password"}),
+	})
+	teacher := resp.Data.(*models.Person)
+
+	peer := &protocol.Peer{}
+	resp = r.Handle(&protocol.Request{
+		Method: router.LoginMethod,
+		Data:   mustJSON(t, map[string]any{"id": teacher.ID, "password": "wrong-password"}),
+		Peer:   peer,
+	})
+	if resp.Status || resp.Message != "invalid credentials" {
+		t.Fatalf("expected invalid credentials, got %q", resp.Message)
+	}
+
+	resp = r.Handle(&protocol.Request{
+		Method: router.LoginMethod,
+		Data:   mustJSON(t, map[string]any{"id": teacher.ID, "password": "teacher-password"}),
+		Peer:   peer,
+	})
+	if !resp.Status || peer.SessionToken() == "" {
+		t.Fatalf("expected login to bind a session, got %q", resp.Message)
+	}
+
+	// who/am/i without an id answers for the session owner
+	resp = r.Handle(&protocol.Request{Method: router.WhoAmIMethod, Peer: peer})
+	if !resp.Status {
+		t.Fatalf("who/am/i failed: %q", resp.Message)
+	}
+	if p := resp.Data.(map[string]any)["person"].(*models.Person); p.ID != teacher.ID {
+		t.Fatalf("expected person %d, got %d", teacher.ID, p.ID)
+	}
+
+	resp = r.Handle(&protocol.Request{Method: router.LogoutMethod, Peer: peer})
+	if !resp.Status {
+		t.Fatalf("logout failed: %q", resp.Message)
+	}
+	resp = r.Handle(&protocol.Request{Method: router.WhoAmIMethod, Peer: peer})
+	if resp.Status {
+		t.Fatalf("expected calls after logout to be rejected")
+	}
+}
+
+func loginAs(t *testing.T, r testRouter, id uint, password string) *protocol.Peer {
+	t.Helper()
+
+	peer := &protocol.Peer{}
+	resp := r.Handle(&protocol.Request{
+		Method: router.LoginMethod,
+		Data:   mustJSON(t, map[string]any{"id": id, "password": password}),
+		Peer:   peer,
+	})
+	if !resp.Status {
+		t.Fatalf("login as %d failed: %q", id, resp.Message)
+	}
+	return peer
+}
+
+func TestRouter_EnforcesPermissions(t *testing.T) {
+	r := setupRouter(t)
+
+	school := r.Handle(&protocol.Request{
+		Method: router.CreateSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"name": "S1"}),
+	}).Data.(*models.School)
+	t1 := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "T1", "role": "teacher", "password": "teacher-password"}),
+	}).Data.(*models.Person)
+	t2 := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "T2", "role": "teacher"}),
+	}).Data.(*models.Person)
+	stu := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "Stu", "role": "student", "password": "student-password"}),
+	}).Data.(*models.Person)
+	own := r.Handle(&protocol.Request{
+		Method: router.CreateClassMethod,
+		Data:   mustJSON(t, map[string]any{"name": "C1", "school_id": school.ID, "teacher_id": t1.ID}),
+	}).Data.(*models.Class)
+	other := r.Handle(&protocol.Request{
+		Method: router.CreateClassMethod,
+		Data:   mustJSON(t, map[string]any{"name": "C2", "school_id": school.ID, "teacher_id": t2.ID}),
+	}).Data.(*models.Class)
+
+	teacher := loginAs(t, r, t1.ID, "teacher-password")
+	student := loginAs(t, r, stu.ID, "student-password")
+
+	cases := []struct {
+		name   string
+		peer   *protocol.Peer
+		method string
+		data   any
+		allow  bool
+	}{
+		{"teacher creates school", teacher, router.CreateSchoolMethod, map[string]any{"name": "S2"}, false},
+		{"teacher lists own class", teacher, router.ClassStudentsMethod, map[string]any{"class_id": own.ID}, true},
+		{"teacher lists other class", teacher, router.ClassStudentsMethod, map[string]any{"class_id": other.ID}, false},
+		{"student lists class", student, router.ClassStudentsMethod, map[string]any{"class_id": own.ID}, false},
+		{"student asks for self", student, router.WhoAmIMethod, map[string]any{"id": stu.ID}, true},
+		{"student asks for teacher", student, router.WhoAmIMethod, map[string]any{"id": t1.ID}, false},
+		{"teacher asks for student", teacher, router.WhoAmIMethod, map[string]any{"id": stu.ID}, true},
+		{"student revokes teacher", student, router.RevokeSessionsMethod, map[string]any{"person_id": t1.ID}, false},
+	}
+	for _, tc := range cases {
+		resp := r.Handle(&protocol.Request{Method: tc.method, Data: mustJSON(t, tc.data), Peer: tc.peer})
+		if tc.allow && !resp.Status {
+			t.Errorf("%s: expected success, got %q", tc.name, resp.Message)
+		}
+		if !tc.allow && resp.Message != "permission denied" {
+			t.Errorf("%s: expected permission denied, got %q", tc.name, resp.Message)
+		}
+	}
+
+	// a denied step inside a batch fails on its own
+	resp := r.Handle(&protocol.Request{
+		Method: router.BatchMethod,
+		Data: mustJSON(t, map[string]any{
+			"requests": []map[string]any{
+				{"method": router.SchoolListMethod},
+				{"method": router.CreateSchoolMethod, "data": map[string]any{"name": "S3"}},
+			},
+		}),
+		Peer: student,
+	})
+	results := resp.Data.([]protocol.Response)
+	if !results[0].Status || results[1].Message != "permission denied" {
+		t.Fatalf("unexpected batch results: %+v", results)
+	}
+}
+
+func TestRouter_UpdateAndDelete(t *testing.T) {
+	r := setupRouter(t)
+
+	school := r.Handle(&protocol.Request{
+		Method: router.CreateSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"name": "S1"}),
+	}).Data.(*models.School)
+	teacher := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "T1", "role": "teacher"}),
+	}).Data.(*models.Person)
+	r.Handle(&protocol.Request{
+		Method: router.CreateClassMethod,
+		Data:   mustJSON(t, map[string]any{"name": "C1", "school_id": school.ID, "teacher_id": teacher.ID}),
+	})
+
+	resp := r.Handle(&protocol.Request{
+		Method: router.UpdateSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"school_id": school.ID, "name": "S1 renamed"}),
+	})
+	if !resp.Status || resp.Data.(*models.School).Name != "S1 renamed" {
+		t.Fatalf("expected rename, got %q", resp.Message)
+	}
+
+	resp = r.Handle(&protocol.Request{
+		Method: router.DeleteSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"school_id": school.ID}),
+	})
+	if resp.Status || resp.Message != "school still has classes" {
+		t.Fatalf("expected school still has classes, got %q", resp.Message)
+	}
+	if code := router.ErrorCode(resp.Err); code != -32010 {
+		t.Fatalf("expected code -32010, got %d", code)
+	}
+
+	resp = r.Handle(&protocol.Request{
+		Method: router.DeleteSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"school_id": school.ID, "cascade": true}),
+	})
+	if !resp.Status {
+		t.Fatalf("expected cascade delete to succeed, got %q", resp.Message)
+	}
+
+	list := r.Handle(&protocol.Request{Method: router.SchoolListMethod})
+	if schools := list.Data.(repository.Page[models.School]); len(schools.Items) != 0 {
+		t.Fatalf("expected no schools, got %d", len(schools.Items))
+	}
+}
+
+func TestRouter_CreateClassRejectsBadCapacity(t *testing.T) {
+	r := setupRouter(t)
+
+	school := r.Handle(&protocol.Request{
+		Method: router.CreateSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"name": "S1"}),
+	}).Data.(*models.School)
+	teacher := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "T1", "role": "teacher"}),
+	}).Data.(*models.Person)
+
+	resp := r.Handle(&protocol.Request{
+		Method: router.CreateClassMethod,
+		Data:   mustJSON(t, map[string]any{"name": "C1", "school_id": school.ID, "teacher_id": teacher.ID, "capacity": -1}),
+	})
+	if resp.Status {
+		t.Fatalf("expected a negative capacity to be refused")
+	}
+
+	// nothing was created on the way to the error
+	list := r.Handle(&protocol.Request{
+		Method: router.SchoolClassesMethod,
+		Data:   mustJSON(t, map[string]any{"school_id": school.ID}),
+	})
+	if classes := list.Data.(repository.Page[models.Class]); len(classes.Items) != 0 {
+		t.Fatalf("expected no classes, got %d", len(classes.Items))
+	}
+}
+
+func TestRouter_WaitlistWhenClassIsFull(t *testing.T) {
+	r := setupRouter(t)
+
+	school := r.Handle(&protocol.Request{
+		Method: router.CreateSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"name": "S1"}),
+	}).Data.(*models.School)
+	teacher := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "T1", "role": "teacher"}),
+	}).Data.(*models.Person)
+	class := r.Handle(&protocol.Request{
+		Method: router.CreateClassMethod,
+		Data:   mustJSON(t, map[string]any{"name": "C1", "school_id": school.ID, "teacher_id": teacher.ID, "capacity": 1}),
+	}).Data.(*models.Class)
+	if class.Capacity == nil || *class.Capacity != 1 {
+		t.Fatalf("expected capacity 1, got %v", class.Capacity)
+	}
+
+	var students []*models.Person
+	for _, name := range []string{"A", "B"} {
+		st := r.Handle(&protocol.Request{
+			Method: router.CreatePersonMethod,
+			Data:   mustJSON(t, map[string]any{"name": name, "role": "student", "password": "student-password"}),
+		}).Data.(*models.Person)
+		students = append(students, st)
+	}
+
+	r.Handle(&protocol.Request{
+		Method: router.AddStudentToClassMethod,
+		Data:   mustJSON(t, map[string]any{"student_id": students[0].ID, "class_id": class.ID}),
+	})
+	resp := r.Handle(&protocol.Request{
+		Method: router.AddStudentToClassMethod,
+		Data:   mustJSON(t, map[string]any{"student_id": students[1].ID, "class_id": class.ID}),
+	})
+	if got := resp.Data.(map[string]any); got["status"] != "waitlisted" || got["position"] != 1 {
+		t.Fatalf("expected waitlisted at 1, got %v", got)
+	}
+
+	// the waitlisted student asks for their own position
+	peer := loginAs(t, r, students[1].ID, "student-password")
+	resp = r.Handle(&protocol.Request{
+		Method: router.WaitlistPositionMethod,
+		Data:   mustJSON(t, map[string]any{"class_id": class.ID}),
+		Peer:   peer,
+	})
+	if !resp.Status || resp.Data.(map[string]any)["position"] != 1 {
+		t.Fatalf("expected position 1, got %q %v", resp.Message, resp.Data)
+	}
+
+	resp = r.Handle(&protocol.Request{
+		Method: router.LeaveWaitlistMethod,
+		Data:   mustJSON(t, map[string]any{"class_id": class.ID, "student_id": students[0].ID}),
+		Peer:   peer,
+	})
+	if resp.Message != "permission denied" {
+		t.Fatalf("expected permission denied leaving for someone else, got %q", resp.Message)
+	}
+}
+
+func TestRouter_GradebookOnlyTeacherRecordsScores(t *testing.T) {
+	r := setupRouter(t)
+
+	school := r.Handle(&protocol.Request{
+		Method: router.CreateSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"name": "S1"}),
+	}).Data.(*models.School)
+	teacher := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "T1", "role": "teacher", "password": "teacher-password"}),
+	}).Data.(*models.Person)
+	student := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "A", "role": "student", "password": "student-password"}),
+	}).Data.(*models.Person)
+	class := r.Handle(&protocol.Request{
+		Method: router.CreateClassMethod,
+		Data:   mustJSON(t, map[string]any{"name": "C1", "school_id": school.ID, "teacher_id": teacher.ID}),
+	}).Data.(*models.Class)
+	r.Handle(&protocol.Request{
+		Method: router.AddStudentToClassMethod,
+		Data:   mustJSON(t, map[string]any{"student_id": student.ID, "class_id": class.ID}),
+	})
+
+	teacherPeer := loginAs(t, r, teacher.ID, "teacher-password")
+	resp := r.Handle(&protocol.Request{
+		Method: router.CreateAssessmentMethod,
+		Data:   mustJSON(t, map[string]any{"class_id": class.ID, "name": "Quiz", "weight": 1, "max_points": 20}),
+		Peer:   teacherPeer,
+	})
+	if !resp.Status {
+		t.Fatalf("expected assessment to be created, got %q", resp.Message)
+	}
+	quiz := resp.Data.(*models.Assessment)
+
+	scores := mustJSON(t, map[string]any{
+		"assessment_id": quiz.ID,
+		"scores":        []map[string]any{{"student_id": student.ID, "points": 15}},
+	})
+	studentPeer := loginAs(t, r, student.ID, "student-password")
+	resp = r.Handle(&protocol.Request{Method: router.RecordScoresMethod, Data: scores, Peer: studentPeer})
+	if resp.Message != "permission denied" {
+		t.Fatalf("expected permission denied for student, got %q", resp.Message)
+	}
+	resp = r.Handle(&protocol.Request{Method: router.RecordScoresMethod, Data: scores, Peer: teacherPeer})
+	if !resp.Status {
+		t.Fatalf("expected scores to be recorded, got %q", resp.Message)
+	}
+
+	resp = r.Handle(&protocol.Request{Method: router.GradesMethod, Peer: studentPeer})
+	grades, _ := resp.Data.([]service.ClassGrade)
+	if len(grades) != 1 || grades[0].FinalGrade == nil || *grades[0].FinalGrade != 75 {
+		t.Fatalf("expected final grade 75, got %q %+v", resp.Message, resp.Data)
+	}
+}
+
+func TestRouter_AssignmentsFollowClassMembership(t *testing.T) {
+	r := setupRouter(t)
+
+	school := r.Handle(&protocol.Request{
+		Method: router.CreateSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"name": "S1"}),
+	}).Data.(*models.School)
+	var teachers, students []*models.Person
+	for _, name := range []string{"T1", "T2"} {
+		teachers = append(teachers, r.Handle(&protocol.Request{
+			Method: router.CreatePersonMethod,
+			Data:   mustJSON(t, map[string]any{"name": name, "role": "teacher", "password": "teacher-password"}),
+		}).Data.(*models.Person))
+	}
+	for _, name := range []string{"A", "B"} {
+		students = append(students, r.Handle(&protocol.Request{
+			Method: router.CreatePersonMethod,
+			Data:   mustJSON(t, map[string]any{"name": name, "role": "student", "password": "student-password"}),
+		}).Data.(*models.Person))
+	}
+	class := r.Handle(&protocol.Request{
+		Method: router.CreateClassMethod,
+		Data:   mustJSON(t, map[string]any{"name": "C1", "school_id": school.ID, "teacher_id": teachers[0].ID}),
+	}).Data.(*models.Class)
+	r.Handle(&protocol.Request{
+		Method: router.AddStudentToClassMethod,
+		Data:   mustJSON(t, map[string]any{"student_id": students[0].ID, "class_id": class.ID}),
+	})
+
+	teacherPeer := loginAs(t, r, teachers[0].ID, "teacher-password")
+	resp := r.Handle(&protocol.Request{
+		Method: router.CreateAssignmentMethod,
+		Data:   mustJSON(t, map[string]any{"class_id": class.ID, "title": "Essay", "due_at": "2099-01-01T00:00:00Z"}),
+		Peer:   teacherPeer,
+	})
+	if !resp.Status {
+		t.Fatalf("expected assignment to be created, got %q", resp.Message)
+	}
+	hw := resp.Data.(*models.Assignment)
+
+	list := mustJSON(t, map[string]any{"class_id": class.ID})
+	enrolledPeer := loginAs(t, r, students[0].ID, "student-password")
+	if resp := r.Handle(&protocol.Request{Method: router.AssignmentListMethod, Data: list, Peer: enrolledPeer}); !resp.Status {
+		t.Fatalf("expected enrolled student to list assignments, got %q", resp.Message)
+	}
+	outsiderPeer := loginAs(t, r, students[1].ID, "student-password")
+	if resp := r.Handle(&protocol.Request{Method: router.AssignmentListMethod, Data: list, Peer: outsiderPeer}); resp.Message != "permission denied" {
+		t.Fatalf("expected permission denied for outsider, got %q", resp.Message)
+	}
+
+	resp = r.Handle(&protocol.Request{
+		Method: router.SubmitMethod,
+		Data:   mustJSON(t, map[string]any{"assignment_id": hw.ID, "text": "my essay"}),
+		Peer:   enrolledPeer,
+	})
+	if !resp.Status || resp.Data.(*models.Submission).Late {
+		t.Fatalf("expected on-time submission, got %q %v", resp.Message, resp.Data)
+	}
+
+	view := mustJSON(t, map[string]any{"assignment_id": hw.ID})
+	otherTeacher := loginAs(t, r, teachers[1].ID, "teacher-password")
+	if resp := r.Handle(&protocol.Request{Method: router.SubmissionsMethod, Data: view, Peer: otherTeacher}); resp.Message != "permission denied" {
+		t.Fatalf("expected permission denied for another teacher, got %q", resp.Message)
+	}
+	resp = r.Handle(&protocol.Request{Method: router.SubmissionsMethod, Data: view, Peer: teacherPeer})
+	statuses, _ := resp.Data.([]service.SubmissionStatus)
+	if len(statuses) != 1 || !statuses[0].Submitted {
+		t.Fatalf("expected one submitted student, got %q %+v", resp.Message, resp.Data)
+	}
+}
+
+func TestRouter_GuardianSeesOnlyOwnChildren(t *testing.T) {
+	r := setupRouter(t)
+
+	school := r.Handle(&protocol.Request{
+		Method: router.CreateSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"name": "S1"}),
+	}).Data.(*models.School)
+	teacher := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "T1", "role": "teacher"}),
+	}).Data.(*models.Person)
+	class := r.Handle(&protocol.Request{
+		Method: router.CreateClassMethod,
+		Data:   mustJSON(t, map[string]any{"name": "C1", "school_id": school.ID, "teacher_id": teacher.ID}),
+	}).Data.(*models.Class)
+	var kids []*models.Person
+	for _, name := range []string{"Kid", "Other"} {
+		kid := r.Handle(&protocol.Request{
+			Method: router.CreatePersonMethod,
+			Data:   mustJSON(t, map[string]any{"name": name, "role": "student"}),
+		}).Data.(*models.Person)
+		r.Handle(&protocol.Request{
+			Method: router.AddStudentToClassMethod,
+			Data:   mustJSON(t, map[string]any{"student_id": kid.ID, "class_id": class.ID}),
+		})
+		kids = append(kids, kid)
+	}
+	parent := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "Mum", "role": "guardian", "password": "guardian-password"}),
+	}).Data.(*models.Person)
+	resp := r.Handle(&protocol.Request{
+		Method: router.LinkGuardianMethod,
+		Data:   mustJSON(t, map[string]any{"guardian_id": parent.ID, "student_id": kids[0].ID, "relationship": "parent"}),
+	})
+	if !resp.Status {
+		t.Fatalf("expected link, got %q", resp.Message)
+	}
+
+	peer := loginAs(t, r, parent.ID, "guardian-password")
+	resp = r.Handle(&protocol.Request{Method: router.WhoAmIMethod, Peer: peer})
+	children, _ := resp.Data.(map[string]any)["children"].([]service.Child)
+	if len(children) != 1 || children[0].Person.ID != kids[0].ID || len(children[0].ClassIDs) != 1 {
+		t.Fatalf("expected one child in one class, got %q %+v", resp.Message, resp.Data)
+	}
+
+	for _, step := range []struct {
+		method string
+		data   map[string]any
+		denied bool
+	}{
+		{router.GradesMethod, map[string]any{"id": kids[0].ID}, false},
+		{router.AttendanceRateMethod, map[string]any{"class_id": class.ID, "student_id": kids[0].ID}, false},
+		{router.GradesMethod, map[string]any{"id": kids[1].ID}, true},
+		{router.AttendanceRateMethod, map[string]any{"class_id": class.ID, "student_id": kids[1].ID}, true},
+		{router.ReportCardMethod, map[string]any{"id": kids[0].ID}, true},
+		{router.SchoolListMethod, nil, true},
+	} {
+		resp := r.Handle(&protocol.Request{Method: step.method, Data: mustJSON(t, step.data), Peer: peer})
+		if denied := resp.Message == "permission denied"; denied != step.denied {
+			t.Fatalf("%s %v: expected denied=%v, got %q", step.method, step.data, step.denied, resp.Message)
+		}
+	}
+}
+
+func TestRouter_SearchFindsByPartialName(t *testing.T) {
+	r := setupRouter(t)
+
+	school := r.Handle(&protocol.Request{
+		Method: router.CreateSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"name": "S1"}),
+	}).Data.(*models.School)
+	teacher := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "T1", "role": "teacher"}),
+	}).Data.(*models.Person)
+	class := r.Handle(&protocol.Request{
+		Method: router.CreateClassMethod,
+		Data:   mustJSON(t, map[string]any{"name": "C1", "school_id": school.ID, "teacher_id": teacher.ID}),
+	}).Data.(*models.Class)
+	student := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "Margaret Thatcher", "role": "student", "password": "student-password"}),
+	}).Data.(*models.Person)
+	r.Handle(&protocol.Request{
+		Method: router.AddStudentToClassMethod,
+		Data:   mustJSON(t, map[string]any{"student_id": student.ID, "class_id": class.ID}),
+	})
+
+	resp := r.Handle(&protocol.Request{
+		Method: router.SearchMethod,
+		Data:   mustJSON(t, map[string]any{"query": "marg", "school_id": school.ID}),
+	})
+	results, _ := resp.Data.([]models.SearchResult)
+	if len(results) != 1 || results[0].Type != models.SearchPerson || results[0].ID != student.ID {
+		t.Fatalf("expected the student, got %q %+v", resp.Message, resp.Data)
+	}
+
+	if resp := r.Handle(&protocol.Request{Method: router.SearchMethod, Data: mustJSON(t, map[string]any{"query": ""})}); resp.Status {
+		t.Fatalf("expected an empty query to fail")
+	}
+
+	peer := loginAs(t, r, student.ID, "student-password")
+	resp = r.Handle(&protocol.Request{Method: router.SearchMethod, Data: mustJSON(t, map[string]any{"query": "marg"}), Peer: peer})
+	if resp.Message != "permission denied" {
+		t.Fatalf("expected students to be denied, got %q", resp.Message)
+	}
+}
+
+func TestRouter_ImportReportsRowErrors(t *testing.T) {
+	r := setupRouter(t)
+
+	csv := "type,ref,name,roles,school,teacher\n" +
+		"school,s1,S1,,,\n" +
+		"person,t1,T1,teacher,,\n" +
+		"class,,C1,,s1,t9\n"
+	resp := r.Handle(&protocol.Request{Method: router.ImportMethod, Data: mustJSON(t, map[string]any{"csv": csv})})
+	report, _ := resp.Data.(*service.ImportReport)
+	if resp.Status || resp.Message != "import rolled back: 1 row errors" || report == nil || report.Errors[0].Line != 4 {
+		t.Fatalf("expected rollback with the bad line, got %q %+v", resp.Message, resp.Data)
+	}
+	if router.ErrorCode(resp.Err) != -32026 {
+		t.Fatalf("expected import failed code, got %d", router.ErrorCode(resp.Err))
+	}
+
+	csv = strings.Replace(csv, "t9", "t1", 1)
+	resp = r.Handle(&protocol.Request{Method: router.ImportMethod, Data: mustJSON(t, map[string]any{"csv": csv})})
+	if report, _ = resp.Data.(*service.ImportReport); !resp.Status || report.Created["class"] != 1 {
+		t.Fatalf("expected import, got %q %+v", resp.Message, resp.Data)
+	}
+	list := r.Handle(&protocol.Request{Method: router.SchoolListMethod})
+	if schools := list.Data.(repository.Page[models.School]); schools.Total != 1 {
+		t.Fatalf("expected the imported school, got %d", schools.Total)
+	}
+}
+
+func TestRouter_ExportReturnsDocument(t *testing.T) {
+	r := setupRouter(t)
+
+	school := r.Handle(&protocol.Request{
+		Method: router.CreateSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"name": "S1"}),
+	}).Data.(*models.School)
+
+	resp := r.Handle(&protocol.Request{
+		Method: router.ExportMethod,
+		Data:   mustJSON(t, map[string]any{"format": "csv", "tables": "schools", "columns": "id, name"}),
+	})
+	doc, _ := resp.Data.(map[string]any)
+	if !resp.Status || doc["content_type"] != "text/csv; charset=utf-8" || doc["document"] != fmt.Sprintf("id,name\n%d,S1\n", school.ID) {
+		t.Fatalf("expected csv export, got %q %+v", resp.Message, resp.Data)
+	}
+
+	resp = r.Handle(&protocol.Request{Method: router.ExportMethod, Data: mustJSON(t, map[string]any{"format": "xlsx"})})
+	if doc, _ = resp.Data.(map[string]any); !resp.Status || len(doc["document"].([]byte)) == 0 {
+		t.Fatalf("expected xlsx export, got %q", resp.Message)
+	}
+
+	if resp = r.Handle(&protocol.Request{Method: router.ExportMethod, Data: mustJSON(t, map[string]any{"format": "pdf"})}); resp.Status {
+		t.Fatalf("expected unknown format to fail")
+	}
+}
+
+func TestRouter_TeacherSeesGradesOnlyOfOwnStudents(t *testing.T) {
+	r := setupRouter(t)
+
+	school := r.Handle(&protocol.Request{
+		Method: router.CreateSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"name": "S1"}),
+	}).Data.(*models.School)
+	create := func(name, role string) *models.Person {
+		return r.Handle(&protocol.Request{
+			Method: router.CreatePersonMethod,
+			Data:   mustJSON(t, map[string]any{"name": name, "role": role, "password": role + "-password"}),
+		}).Data.(*models.Person)
+	}
+	var teachers, students []*models.Person
+	for _, name := range []string{"T1", "T2"} {
+		teacher := create(name, "teacher")
+		teachers = append(teachers, teacher)
+		class := r.Handle(&protocol.Request{
+			Method: router.CreateClassMethod,
+			Data:   mustJSON(t, map[string]any{"name": "C" + name, "school_id": school.ID, "teacher_id": teacher.ID}),
+		}).Data.(*models.Class)
+		student := create("Kid of "+name, "student")
+		r.Handle(&protocol.Request{
+			Method: router.AddStudentToClassMethod,
+			Data:   mustJSON(t, map[string]any{"student_id": student.ID, "class_id": class.ID}),
+		})
+		students = append(students, student)
+	}
+	teacher := loginAs(t, r, teachers[0].ID, "teacher-password")
+	staff := loginAs(t, r, create("Office", "staff").ID, "staff-password")
+	student := loginAs(t, r, students[0].ID, "student-password")
+
+	for _, step := range []struct {
+		peer   *protocol.Peer
+		id     uint
+		denied bool
+	}{
+		{teacher, students[0].ID, false},
+		{teacher, students[1].ID, true},
+		{teacher, 0, false},
+		{staff, students[1].ID, false},
+		{student, students[0].ID, false},
+		{student, students[1].ID, true},
+	} {
+		resp := r.Handle(&protocol.Request{Method: router.GradesMethod, Data: mustJSON(t, map[string]any{"id": step.id}), Peer: step.peer})
+		if denied := resp.Message == "permission denied"; denied != step.denied {
+			t.Fatalf("grades of %d: expected denied=%v, got %q", step.id, step.denied, resp.Message)
+		}
+	}
+}
+
+func TestRouter_TeacherSeesAttendanceOnlyInOwnClasses(t *testing.T) {
+	r := setupRouter(t)
+
+	school := r.Handle(&protocol.Request{
+		Method: router.CreateSchoolMethod,
+		Data:   mustJSON(t, map[string]any{"name": "S1"}),
+	}).Data.(*models.School)
+	var teachers []*models.Person
+	var classes []*models.Class
+	for _, name := range []string{"T1", "T2"} {
+		teacher := r.Handle(&protocol.Request{
+			Method: router.CreatePersonMethod,
+			Data:   mustJSON(t, map[string]any{"name": name, "role": "teacher", "password": "teacher-password"}),
+		}).Data.(*models.Person)
+		class := r.Handle(&protocol.Request{
+			Method: router.CreateClassMethod,
+			Data:   mustJSON(t, map[string]any{"name": "C" + name, "school_id": school.ID, "teacher_id": teacher.ID}),
+		}).Data.(*models.Class)
+		teachers = append(teachers, teacher)
+		classes = append(classes, class)
+	}
+	student := r.Handle(&protocol.Request{
+		Method: router.CreatePersonMethod,
+		Data:   mustJSON(t, map[string]any{"name": "Kid", "role": "student"}),
+	}).Data.(*models.Person)
+	r.Handle(&protocol.Request{
+		Method: router.AddStudentToClassMethod,
+		Data:   mustJSON(t, map[string]any{"student_id": student.ID, "class_id": classes[0].ID}),
+	})
+
+	rate := mustJSON(t, map[string]any{"class_id": classes[0].ID, "student_id": student.ID})
+	own := loginAs(t, r, teachers[0].ID, "teacher-password")
+	if resp := r.Handle(&protocol.Request{Method: router.AttendanceRateMethod, Data: rate, Peer: own}); !resp.Status {
+		t.Fatalf("expected the class teacher to read the rate, got %q", resp.Message)
+	}
+	other := loginAs(t, r, teachers[1].ID, "teacher-password")
+	if resp := r.Handle(&protocol.Request{Method: router.AttendanceRateMethod, Data: rate, Peer: other}); resp.Message != "permission denied" {
+		t.Fatalf("expected another teacher to be denied, got %q", resp.Message)
+	}
+}
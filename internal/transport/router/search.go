@@ -0,0 +1,19 @@
+package router
+
+import (
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+)
+
+func (r *Router) handleSearchMethod(req *protocol.Request) protocol.Response {
+	var sDTO dto.SearchDTO
+	if err := json.Unmarshal(req.Data, &sDTO); err != nil {
+		return badRequest("invalid json for search")
+	}
+	results, err := r.search.Search(sDTO.Query, sDTO.SchoolID, sDTO.Limit)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(results)
+}
@@ -0,0 +1,43 @@
+package router
+
+import (
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+)
+
+func (r *Router) handleCreateTermMethod(req *protocol.Request) protocol.Response {
+	var ctDTO dto.CreateTermDTO
+	if err := json.Unmarshal(req.Data, &ctDTO); err != nil {
+		return badRequest("invalid json for term.create")
+	}
+	created, err := r.term.Create(ctDTO.SchoolID, ctDTO.Name, ctDTO.StartsAt, ctDTO.EndsAt)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(created)
+}
+
+func (r *Router) handleTermListMethod(req *protocol.Request) protocol.Response {
+	var tlDTO dto.TermListDTO
+	if err := json.Unmarshal(req.Data, &tlDTO); err != nil {
+		return badRequest("invalid json for term.list")
+	}
+	terms, err := r.term.List(tlDTO.SchoolID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(terms)
+}
+
+func (r *Router) handleCurrentTermMethod(req *protocol.Request) protocol.Response {
+	var tlDTO dto.TermListDTO
+	if err := json.Unmarshal(req.Data, &tlDTO); err != nil {
+		return badRequest("invalid json for term.current")
+	}
+	term, err := r.term.Current(tlDTO.SchoolID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(term)
+}
@@ -0,0 +1,96 @@
+package router
+
+import (
+	"OldSchool/internal/repository/models"
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+)
+
+func (r *Router) handleCreateRoomMethod(req *protocol.Request) protocol.Response {
+	var crDTO dto.CreateRoomDTO
+	if err := json.Unmarshal(req.Data, &crDTO); err != nil {
+		return badRequest("invalid json for room.create")
+	}
+	created, err := r.timetable.CreateRoom(crDTO.SchoolID, crDTO.Name)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(created)
+}
+
+func (r *Router) handleRoomListMethod(req *protocol.Request) protocol.Response {
+	var rlDTO dto.RoomListDTO
+	if err := json.Unmarshal(req.Data, &rlDTO); err != nil {
+		return badRequest("invalid json for room.list")
+	}
+	rooms, err := r.timetable.ListRooms(rlDTO.SchoolID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(rooms)
+}
+
+func (r *Router) handleAddSlotMethod(req *protocol.Request) protocol.Response {
+	var sDTO dto.SlotDTO
+	if err := json.Unmarshal(req.Data, &sDTO); err != nil {
+		return badRequest("invalid json for class.slot.add")
+	}
+	created, err := r.timetable.AddSlot(sDTO.ClassID, sDTO.RoomID, sDTO.Weekday, sDTO.StartTime, sDTO.EndTime)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(created)
+}
+
+func (r *Router) handleUpdateSlotMethod(req *protocol.Request) protocol.Response {
+	var sDTO dto.SlotDTO
+	if err := json.Unmarshal(req.Data, &sDTO); err != nil {
+		return badRequest("invalid json for class.slot.update")
+	}
+	updated, err := r.timetable.UpdateSlot(sDTO.SlotID, sDTO.RoomID, sDTO.Weekday, sDTO.StartTime, sDTO.EndTime)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(updated)
+}
+
+func (r *Router) handleDeleteSlotMethod(req *protocol.Request) protocol.Response {
+	var sDTO dto.SlotDTO
+	if err := json.Unmarshal(req.Data, &sDTO); err != nil {
+		return badRequest("invalid json for class.slot.delete")
+	}
+	if err := r.timetable.DeleteSlot(sDTO.SlotID); err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{"status": "deleted"})
+}
+
+func (r *Router) handleClassSlotsMethod(req *protocol.Request) protocol.Response {
+	var csDTO dto.ClassSlotsDTO
+	if err := json.Unmarshal(req.Data, &csDTO); err != nil {
+		return badRequest("invalid json for class.slots")
+	}
+	slots, err := r.timetable.ClassSlots(csDTO.ClassID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(slots)
+}
+
+func (r *Router) handleTimetableMethod(req *protocol.Request, caller *models.Person) protocol.Response {
+	var tDTO dto.TimetableDTO
+	if len(req.Data) > 0 {
+		if err := json.Unmarshal(req.Data, &tDTO); err != nil {
+			return badRequest("invalid json for person.timetable")
+		}
+	}
+	if tDTO.ID == 0 {
+		tDTO.ID = caller.ID
+	}
+	entries, err := r.timetable.PersonTimetable(tDTO.ID, tDTO.TermID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(entries)
+}
@@ -0,0 +1,37 @@
+package router
+
+import (
+	"OldSchool/internal/repository/models"
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+)
+
+func (r *Router) handleTransferMethod(req *protocol.Request) protocol.Response {
+	var tDTO dto.TransferDTO
+	if err := json.Unmarshal(req.Data, &tDTO); err != nil {
+		return badRequest("invalid json for person.transfer")
+	}
+	transfer, err := r.transfer.Transfer(tDTO.StudentID, tDTO.SchoolID, tDTO.Reason, tDTO.ClassIDs)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(transfer)
+}
+
+func (r *Router) handleTransferHistoryMethod(req *protocol.Request, caller *models.Person) protocol.Response {
+	var thDTO dto.TransferHistoryDTO
+	if len(req.Data) > 0 {
+		if err := json.Unmarshal(req.Data, &thDTO); err != nil {
+			return badRequest("invalid json for person.transfers")
+		}
+	}
+	if thDTO.ID == 0 {
+		thDTO.ID = caller.ID
+	}
+	transfers, err := r.transfer.History(thDTO.ID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(transfers)
+}
@@ -0,0 +1,55 @@
+package router
+
+import (
+	"OldSchool/internal/repository/models"
+	"OldSchool/internal/transport/dto"
+	"OldSchool/internal/transport/protocol"
+	"encoding/json"
+)
+
+func (r *Router) handleClassWaitlistMethod(req *protocol.Request) protocol.Response {
+	var wDTO dto.WaitlistDTO
+	if err := json.Unmarshal(req.Data, &wDTO); err != nil {
+		return badRequest("invalid json for class.waitlist")
+	}
+	entries, err := r.class.Waitlist(wDTO.ClassID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(entries)
+}
+
+// handleWaitlistPositionMethod answers for the caller unless a student_id is
+// given.
+func (r *Router) handleWaitlistPositionMethod(req *protocol.Request, caller *models.Person) protocol.Response {
+	var wDTO dto.WaitlistDTO
+	if err := json.Unmarshal(req.Data, &wDTO); err != nil {
+		return badRequest("invalid json for class.waitlist.position")
+	}
+	if wDTO.StudentID == 0 {
+		wDTO.StudentID = caller.ID
+	}
+	position, err := r.class.WaitlistPosition(wDTO.StudentID, wDTO.ClassID)
+	if err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{
+		"class_id":   wDTO.ClassID,
+		"student_id": wDTO.StudentID,
+		"position":   position,
+	})
+}
+
+func (r *Router) handleLeaveWaitlistMethod(req *protocol.Request, caller *models.Person) protocol.Response {
+	var wDTO dto.WaitlistDTO
+	if err := json.Unmarshal(req.Data, &wDTO); err != nil {
+		return badRequest("invalid json for class.waitlist.leave")
+	}
+	if wDTO.StudentID == 0 {
+		wDTO.StudentID = caller.ID
+	}
+	if err := r.class.LeaveWaitlist(wDTO.StudentID, wDTO.ClassID); err != nil {
+		return fromServiceError(err)
+	}
+	return ok(map[string]any{"status": "left waitlist"})
+}
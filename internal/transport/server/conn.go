@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"OldSchool/internal/transport/protocol"
+	"OldSchool/internal/transport/router"
+)
+
+// connIO is how the request loop talks to one connection. read returns the
+// next framed message; write sends one encoded reply and must be safe to call
+// from several goroutines; close unblocks read.
+type connIO struct {
+	peer  *protocol.Peer
+	read  func() ([]byte, error)
+	write func([]byte) error
+	close func()
+}
+
+// serveConn runs the request loop shared by every transport. Up to
+// maxInFlight messages are handled at once and each reply is written as soon
+// as it is ready.
+func serveConn(r *router.Router, maxInFlight int, c connIO) error {
+	var inFlight sync.WaitGroup
+	sem := make(chan struct{}, maxInFlight)
+	defer inFlight.Wait()
+
+	for {
+		msg, err := c.read()
+		if err != nil {
+			if protocol.IsEOF(err) {
+				return nil
+			}
+			if !errors.Is(err, protocol.ErrMessageTooBig) && !errors.Is(err, protocol.ErrUndecodable) {
+				return err
+			}
+			if err := c.write(encodeResponse(protocol.Response{
+				Status:  false,
+				Message: "bad request",
+				Data:    nil,
+			})); err != nil {
+				return err
+			}
+			continue
+		}
+
+		sem <- struct{}{}
+		inFlight.Add(1)
+		go func(msg []byte) {
+			defer func() {
+				<-sem
+				inFlight.Done()
+			}()
+
+			reply := handleMessage(r, c.peer, msg)
+			if reply == nil {
+				return
+			}
+			if err := c.write(reply); err != nil {
+				// unblocks the reader so the connection winds down
+				c.close()
+			}
+		}(msg)
+	}
+}
+
+// handleMessage answers one message in whichever envelope it arrived in.
+func handleMessage(r *router.Router, peer *protocol.Peer, msg []byte) []byte {
+	if protocol.IsJSONRPC(msg) {
+		return handleRPC(r, peer, msg)
+	}
+
+	req, err := protocol.ParseRequest(msg)
+	if err != nil {
+		errMsg := "bad request"
+		if errors.Is(err, protocol.ErrEmptyLine) {
+			errMsg = "empty request"
+		}
+		return encodeResponse(protocol.Response{
+			Status:  false,
+			Message: errMsg,
+			Data:    nil,
+		})
+	}
+
+	req.Peer = peer
+	return encodeResponse(r.Handle(req))
+}
+
+func encodeResponse(resp protocol.Response) []byte {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		b, _ = json.Marshal(protocol.Response{ID: resp.ID, Status: false, Message: "internal error"})
+	}
+	return b
+}
+
+// connSet tracks open connections so Stop can close them and wait for their
+// handlers instead of blocking on idle clients.
+type connSet struct {
+	mu     sync.Mutex
+	conns  map[io.Closer]struct{}
+	closed bool
+}
+
+// add registers c and reports false if the set was already closed.
+func (cs *connSet) add(c io.Closer) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.closed {
+		return false
+	}
+	if cs.conns == nil {
+		cs.conns = make(map[io.Closer]struct{})
+	}
+	cs.conns[c] = struct{}{}
+	return true
+}
+
+func (cs *connSet) remove(c io.Closer) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	delete(cs.conns, c)
+}
+
+func (cs *connSet) closeAll() {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.closed = true
+	for c := range cs.conns {
+		_ = c.Close()
+	}
+	cs.conns = nil
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"OldSchool/internal/transport/protocol"
+	"OldSchool/internal/transport/router"
+)
+
+// handleRPC answers a JSON-RPC 2.0 message, which is either a single call or
+// a batch array. It returns nil when nothing must be sent back, i.e. for
+// notifications and batches made only of notifications.
+func handleRPC(r *router.Router, peer *protocol.Peer, msg []byte) []byte {
+	msg = bytes.TrimSpace(msg)
+
+	if msg[0] != '[' {
+		resp := callRPC(r, peer, msg)
+		if resp == nil {
+			return nil
+		}
+		return mustMarshal(resp)
+	}
+
+	var calls []json.RawMessage
+	if err := json.Unmarshal(msg, &calls); err != nil {
+		return mustMarshal(rpcError(nil, protocol.RPCParseError, "parse error"))
+	}
+	if len(calls) == 0 {
+		return mustMarshal(rpcError(nil, protocol.RPCInvalidRequest, "invalid request"))
+	}
+
+	var replies []*protocol.RPCResponse
+	for _, call := range calls {
+		if resp := callRPC(r, peer, call); resp != nil {
+			replies = append(replies, resp)
+		}
+	}
+	if len(replies) == 0 {
+		return nil
+	}
+	return mustMarshal(replies)
+}
+
+func callRPC(r *router.Router, peer *protocol.Peer, msg []byte) *protocol.RPCResponse {
+	var call protocol.RPCRequest
+	if err := json.Unmarshal(msg, &call); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return rpcError(nil, protocol.RPCParseError, "parse error")
+		}
+		return rpcError(nil, protocol.RPCInvalidRequest, "invalid request")
+	}
+	if call.JSONRPC != protocol.JSONRPCVersion || call.Method == "" {
+		return rpcError(call.ID, protocol.RPCInvalidRequest, "invalid request")
+	}
+
+	resp := r.Handle(&protocol.Request{
+		ID:     call.ID,
+		Method: call.Method,
+		Data:   call.Params,
+		Peer:   peer,
+	})
+	if call.IsNotification() {
+		return nil
+	}
+
+	if !resp.Status {
+		out := rpcError(call.ID, router.ErrorCode(resp.Err), resp.Message)
+		out.Error.Data = resp.Data
+		return out
+	}
+	return &protocol.RPCResponse{ID: call.ID, Result: resp.Data}
+}
+
+func rpcError(id json.RawMessage, code int, message string) *protocol.RPCResponse {
+	return &protocol.RPCResponse{
+		ID:    id,
+		Error: &protocol.RPCError{Code: code, Message: message},
+	}
+}
+
+func mustMarshal(v any) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(rpcError(nil, protocol.RPCInternalError, "internal error"))
+	}
+	return b
+}
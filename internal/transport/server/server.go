@@ -2,9 +2,10 @@ package server
 
 import (
 	"bufio"
-	"errors"
+	"crypto/tls"
 	"net"
 	"sync"
+	"time"
 
 	"OldSchool/internal/transport/protocol"
 	"OldSchool/internal/transport/router"
@@ -15,18 +16,42 @@ type Server interface {
 	Stop() error
 }
 
+// DefaultMaxInFlight is used when Config.MaxInFlight is not set.
+const DefaultMaxInFlight = 16
+
+type Config struct {
+	// MaxInFlight caps how many requests from a single connection are
+	// handled at the same time. Responses are written as soon as each one
+	// is ready, so they may come back in a different order than requested.
+	MaxInFlight int
+
+	// TLS, when set, makes the listeners accept only TLS connections. See
+	// LoadTLSConfig.
+	TLS *tls.Config
+
+	// AllowedOrigins lists browser origins, besides the server's own, that
+	// may open a WebSocket. "*" allows any origin.
+	AllowedOrigins []string
+}
+
 type tcpServer struct {
 	r        *router.Router
+	cfg      Config
 	listener net.Listener
 
 	mu    sync.Mutex
 	wg    sync.WaitGroup
+	conns connSet
 	stopC chan struct{}
 }
 
-func New(r *router.Router) Server {
+func New(r *router.Router, cfg Config) Server {
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = DefaultMaxInFlight
+	}
 	return &tcpServer{
 		r:     r,
+		cfg:   cfg,
 		stopC: make(chan struct{}),
 	}
 }
@@ -36,6 +61,9 @@ func (s *tcpServer) Start(port string) error {
 	if err != nil {
 		return err
 	}
+	if s.cfg.TLS != nil {
+		ln = tls.NewListener(ln, s.cfg.TLS)
+	}
 
 	s.mu.Lock()
 	s.listener = ln
@@ -44,7 +72,7 @@ func (s *tcpServer) Start(port string) error {
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		s.acceptLoop()
+		s.acceptLoop(ln)
 	}()
 
 	return nil
@@ -60,13 +88,14 @@ func (s *tcpServer) Stop() error {
 	}
 
 	close(s.stopC)
+	s.conns.closeAll()
 	s.wg.Wait()
 	return nil
 }
 
-func (s *tcpServer) acceptLoop() {
+func (s *tcpServer) acceptLoop(ln net.Listener) {
 	for {
-		conn, err := s.listener.Accept()
+		conn, err := ln.Accept()
 		if err != nil {
 			select {
 			case <-s.stopC:
@@ -87,34 +116,141 @@ func (s *tcpServer) acceptLoop() {
 func (s *tcpServer) handleConn(conn net.Conn) error {
 	defer conn.Close()
 
+	if !s.conns.add(conn) {
+		return nil
+	}
+	defer s.conns.remove(conn)
+
+	peer, err := handshake(conn)
+	if err != nil {
+		return err
+	}
+
 	reader := bufio.NewReader(conn)
 	writer := bufio.NewWriter(conn)
 
-	for {
-		req, err := protocol.ReadRequest(reader)
-		if err != nil {
-			if protocol.IsEOF(err) {
-				return nil
+	var writeMu sync.Mutex
+	writeLine := func(msg []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return protocol.WriteLine(writer, msg)
+	}
+	readLine := func() ([]byte, error) { return protocol.ReadLine(reader) }
+
+	// The first message may switch the connection to another format. If it
+	// is anything else it is handed to the request loop as usual.
+	first, firstErr := readLine()
+	if firstErr == nil {
+		if n, reply, ok := s.negotiate(first); ok {
+			if err := writeLine(reply); err != nil {
+				return err
 			}
-			if errors.Is(err, protocol.ErrEmptyLine) {
-				_ = protocol.WriteResponse(writer, protocol.Response{
-					Status:  false,
-					Message: "empty request",
-					Data:    nil,
+			first = nil
+			if n != nil {
+				return serveConn(s.r, s.cfg.MaxInFlight, connIO{
+					peer:  peer,
+					read:  framedReader(reader, n),
+					write: framedWriter(writer, &writeMu, n),
+					close: func() { _ = conn.Close() },
 				})
-				continue
 			}
-			_ = protocol.WriteResponse(writer, protocol.Response{
-				Status:  false,
-				Message: "bad request",
-				Data:    nil,
-			})
-			continue
 		}
+	}
+
+	read := func() ([]byte, error) {
+		if first != nil || firstErr != nil {
+			msg, err := first, firstErr
+			first, firstErr = nil, nil
+			return msg, err
+		}
+		return readLine()
+	}
+
+	return serveConn(s.r, s.cfg.MaxInFlight, connIO{
+		peer:  peer,
+		read:  read,
+		write: writeLine,
+		close: func() { _ = conn.Close() },
+	})
+}
+
+// handshakeTimeout bounds how long a TLS client may take to say hello.
+const handshakeTimeout = 10 * time.Second
+
+// handshake completes the TLS handshake, if any, and describes the peer.
+func handshake(conn net.Conn) (*protocol.Peer, error) {
+	tc, ok := conn.(*tls.Conn)
+	if !ok {
+		return &protocol.Peer{Addr: conn.RemoteAddr().String()}, nil
+	}
+
+	_ = tc.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := tc.Handshake(); err != nil {
+		return nil, err
+	}
+	_ = tc.SetDeadline(time.Time{})
+
+	state := tc.ConnectionState()
+	return peerFromTLS(conn.RemoteAddr().String(), &state), nil
+}
+
+// negotiate answers msg if it is a NegotiateMethod request. It returns the
+// accepted format, or nil when the client asked for the defaults or the
+// request was refused.
+func (s *tcpServer) negotiate(msg []byte) (*protocol.Negotiation, []byte, bool) {
+	req, err := protocol.ParseRequest(msg)
+	if err != nil || req.Method != protocol.NegotiateMethod {
+		return nil, nil, false
+	}
+
+	n, err := protocol.ParseNegotiation(req)
+	if err != nil {
+		return nil, encodeResponse(protocol.Response{
+			ID:      req.ID,
+			Status:  false,
+			Message: err.Error(),
+		}), true
+	}
+
+	reply := encodeResponse(protocol.Response{ID: req.ID, Status: true, Message: "ok", Data: n})
+	if n.Framing == protocol.FramingLines && n.Codec == protocol.CodecJSON {
+		return nil, reply, true
+	}
+	return n, reply, true
+}
+
+func framedReader(r *bufio.Reader, n *protocol.Negotiation) func() ([]byte, error) {
+	codec, _ := protocol.LookupCodec(n.Codec)
+	return func() ([]byte, error) {
+		var (
+			msg []byte
+			err error
+		)
+		if n.Framing == protocol.FramingLength {
+			msg, err = protocol.ReadFrame(r)
+		} else {
+			msg, err = protocol.ReadLine(r)
+		}
+		if err != nil || len(msg) == 0 {
+			return msg, err
+		}
+		return codec.ToJSON(msg)
+	}
+}
 
-		resp := s.r.Handle(req)
-		if err := protocol.WriteResponse(writer, resp); err != nil {
+func framedWriter(w *bufio.Writer, mu *sync.Mutex, n *protocol.Negotiation) func([]byte) error {
+	codec, _ := protocol.LookupCodec(n.Codec)
+	return func(msg []byte) error {
+		out, err := codec.FromJSON(msg)
+		if err != nil {
 			return err
 		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if n.Framing == protocol.FramingLength {
+			return protocol.WriteFrame(w, out)
+		}
+		return protocol.WriteLine(w, out)
 	}
 }
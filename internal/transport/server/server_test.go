@@ -0,0 +1,393 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"OldSchool/internal/repository"
+	"OldSchool/internal/service"
+	"OldSchool/internal/transport/protocol"
+	"OldSchool/internal/transport/router"
+)
+
+const testAdminPassword = "admin-password"
+
+// loginLine logs in as the admin account created by newTestRouter.
+const loginLine = `{"method":"/auth/login","data":{"id":1,"password":"` + testAdminPassword + `"}}`
+
+func newTestRouter(t *testing.T) *router.Router {
+	t.Helper()
+
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db.DB failed: %v", err)
+	}
+
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	services := service.NewServices(repository.NewRepos(db))
+	if _, err := services.Auth.Bootstrap("admin", testAdminPassword); err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+	return router.NewRouter(services)
+}
+
+// adminPeer returns a peer with the admin's session bound to it.
+func adminPeer(t *testing.T, r *router.Router) *protocol.Peer {
+	t.Helper()
+
+	peer := &protocol.Peer{}
+	req, _ := protocol.ParseRequest([]byte(loginLine))
+	req.Peer = peer
+	if resp := r.Handle(req); !resp.Status {
+		t.Fatalf("admin login failed: %q", resp.Message)
+	}
+	return peer
+}
+
+// login sends loginLine and waits for the reply, so requests pipelined after
+// it are already authenticated.
+func login(t *testing.T, r *bufio.Reader, w io.Writer) {
+	t.Helper()
+
+	if _, err := io.WriteString(w, loginLine+"\n"); err != nil {
+		t.Fatalf("write login failed: %v", err)
+	}
+	line, err := r.ReadBytes('\n')
+	if err != nil || !strings.Contains(string(line), `"status":true`) {
+		t.Fatalf("login failed: %q, %v", line, err)
+	}
+}
+
+func startServer(t *testing.T, cfg Config) net.Addr {
+	t.Helper()
+
+	s := New(newTestRouter(t), cfg)
+	if err := s.Start("0"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Stop() })
+
+	return s.(*tcpServer).listener.Addr()
+}
+
+func TestHandleConn_PipelinedRequestsEchoIDs(t *testing.T) {
+	addr := startServer(t, Config{MaxInFlight: 4})
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	login(t, r, conn)
+
+	const n = 20
+	w := bufio.NewWriter(conn)
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(w, `{"id":%d,"method":"/school/create","data":{"name":"S%d"}}`+"\n", i, i)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	seen := make(map[int]bool)
+	for i := 0; i < n; i++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("read failed after %d responses: %v", i, err)
+		}
+		var resp struct {
+			ID     int  `json:"id"`
+			Status bool `json:"status"`
+		}
+		if err := json.Unmarshal(line, &resp); err != nil {
+			t.Fatalf("bad response %q: %v", line, err)
+		}
+		if !resp.Status {
+			t.Fatalf("request %d failed: %s", resp.ID, line)
+		}
+		seen[resp.ID] = true
+	}
+
+	for i := 1; i <= n; i++ {
+		if !seen[i] {
+			t.Fatalf("no response for id %d", i)
+		}
+	}
+}
+
+func TestHandleConn_BadRequestHasNoID(t *testing.T) {
+	addr := startServer(t, Config{})
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("not json\n")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	var resp protocol.Response
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if err := json.Unmarshal(line, &resp); err != nil {
+		t.Fatalf("bad response %q: %v", line, err)
+	}
+	if resp.Status || resp.Message != "bad request" || resp.ID != nil {
+		t.Fatalf("unexpected response: %s", line)
+	}
+}
+
+func TestStop_ClosesIdleConnections(t *testing.T) {
+	s := New(newTestRouter(t), Config{})
+	if err := s.Start("0"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", s.(*tcpServer).listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	// make sure the connection is being served before stopping
+	if _, err := conn.Write([]byte(`{"method":"/health"}` + "\n")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, err := bufio.NewReader(conn).ReadBytes('\n'); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = s.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Stop did not return with an idle client connected")
+	}
+}
+
+func TestWebSocket_TextFrameRoundTrip(t *testing.T) {
+	s := NewWebSocket(newTestRouter(t), Config{})
+	if err := s.Start("0"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	addr := s.(*wsServer).listener.Addr().String()
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+addr+WebSocketPath, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	req := `{"id":42,"method":"/health"}`
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(req)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	var resp struct {
+		ID     int  `json:"id"`
+		Status bool `json:"status"`
+	}
+	if err := conn.ReadJSON(&resp); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if resp.ID != 42 || !resp.Status {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = s.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Stop did not return with a WebSocket client connected")
+	}
+}
+
+func TestHandleMessage_JSONRPC(t *testing.T) {
+	r := newTestRouter(t)
+	peer := adminPeer(t, r)
+
+	reply := handleMessage(r, peer, []byte(`{"jsonrpc":"2.0","id":1,"method":"/school/create","params":{"name":"S1"}}`))
+	var ok struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      int             `json:"id"`
+		Result  json.RawMessage `json:"result"`
+		Error   *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(reply, &ok); err != nil {
+		t.Fatalf("bad reply %q: %v", reply, err)
+	}
+	if ok.JSONRPC != "2.0" || ok.ID != 1 || ok.Result == nil || ok.Error != nil {
+		t.Fatalf("unexpected reply: %s", reply)
+	}
+
+	reply = handleMessage(r, peer, []byte(`{"jsonrpc":"2.0","id":"dup","method":"/school/create","params":{"name":"S1"}}`))
+	var failed struct {
+		ID    string `json:"id"`
+		Error struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(reply, &failed); err != nil {
+		t.Fatalf("bad reply %q: %v", reply, err)
+	}
+	if failed.ID != "dup" || failed.Error.Code != router.ErrorCode(service.ErrSchoolAlreadyExists) {
+		t.Fatalf("expected school-exists error code, got %s", reply)
+	}
+
+	if reply := handleMessage(r, peer, []byte(`{"jsonrpc":"2.0","method":"/school/create","params":{"name":"S2"}}`)); reply != nil {
+		t.Fatalf("expected no reply to a notification, got %s", reply)
+	}
+}
+
+func TestHandleMessage_JSONRPCBatch(t *testing.T) {
+	r := newTestRouter(t)
+	peer := adminPeer(t, r)
+
+	reply := handleMessage(r, peer, []byte(`[
+		{"jsonrpc":"2.0","id":1,"method":"/school/list"},
+		{"jsonrpc":"2.0","method":"/school/create","params":{"name":"S1"}},
+		{"jsonrpc":"2.0","id":2,"method":"/no/such/method"},
+		42
+	]`))
+
+	var replies []struct {
+		ID    *int `json:"id"`
+		Error *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(reply, &replies); err != nil {
+		t.Fatalf("bad reply %q: %v", reply, err)
+	}
+	if len(replies) != 3 {
+		t.Fatalf("expected 3 replies (notification skipped), got %s", reply)
+	}
+	if replies[1].Error == nil || replies[1].Error.Code != protocol.RPCMethodNotFound {
+		t.Fatalf("expected method not found for id 2, got %s", reply)
+	}
+	if replies[2].ID != nil || replies[2].Error == nil || replies[2].Error.Code != protocol.RPCInvalidRequest {
+		t.Fatalf("expected invalid request with null id, got %s", reply)
+	}
+
+	if reply := handleMessage(r, peer, []byte(`[]`)); !strings.Contains(string(reply), `"code":-32600`) {
+		t.Fatalf("expected invalid request for empty batch, got %s", reply)
+	}
+
+	// old-style envelope on the same connection keeps its own shape
+	reply = handleMessage(r, peer, []byte(`{"id":7,"method":"/school/list"}`))
+	if !strings.HasPrefix(string(reply), `{"id":7,"status":true`) {
+		t.Fatalf("unexpected legacy reply: %s", reply)
+	}
+}
+
+func TestHandleConn_NegotiatesLengthPrefixedMsgPack(t *testing.T) {
+	addr := startServer(t, Config{})
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	hello := `{"id":0,"method":"/protocol/negotiate","data":{"framing":"length-prefixed","codec":"msgpack"}}`
+	if err := protocol.WriteLine(w, []byte(hello)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	line, err := r.ReadBytes('\n')
+	if err != nil || !strings.Contains(string(line), `"status":true`) {
+		t.Fatalf("negotiation failed: %q, %v", line, err)
+	}
+
+	codec, _ := protocol.LookupCodec(protocol.CodecMsgPack)
+	loginFrame, _ := codec.FromJSON([]byte(loginLine))
+	if err := protocol.WriteFrame(w, loginFrame); err != nil {
+		t.Fatalf("write frame failed: %v", err)
+	}
+	if _, err := protocol.ReadFrame(r); err != nil {
+		t.Fatalf("read login reply failed: %v", err)
+	}
+
+	req, _ := codec.FromJSON([]byte(`{"id":1,"method":"/school/create","data":{"name":"North\nCampus"}}`))
+	if err := protocol.WriteFrame(w, req); err != nil {
+		t.Fatalf("write frame failed: %v", err)
+	}
+
+	frame, err := protocol.ReadFrame(r)
+	if err != nil {
+		t.Fatalf("read frame failed: %v", err)
+	}
+	out, err := codec.ToJSON(frame)
+	if err != nil {
+		t.Fatalf("reply is not msgpack: %v", err)
+	}
+
+	var resp struct {
+		ID     int  `json:"id"`
+		Status bool `json:"status"`
+		Data   struct {
+			Name string
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("bad reply %s: %v", out, err)
+	}
+	if resp.ID != 1 || !resp.Status || resp.Data.Name != "North\nCampus" {
+		t.Fatalf("unexpected reply: %s", out)
+	}
+}
+
+func TestHandleConn_RejectsUnknownCodec(t *testing.T) {
+	addr := startServer(t, Config{})
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	fmt.Fprintln(conn, `{"method":"/protocol/negotiate","data":{"framing":"length-prefixed","codec":"xml"}}`)
+	if line, _ := r.ReadBytes('\n'); !strings.Contains(string(line), "unsupported codec") {
+		t.Fatalf("expected refusal, got %q", line)
+	}
+
+	// still on JSON lines
+	fmt.Fprintln(conn, `{"id":2,"method":"/health"}`)
+	if line, _ := r.ReadBytes('\n'); !strings.HasPrefix(string(line), `{"id":2,"status":true`) {
+		t.Fatalf("expected JSON line reply, got %q", line)
+	}
+}
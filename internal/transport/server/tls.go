@@ -0,0 +1,50 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+
+	"OldSchool/internal/transport/protocol"
+)
+
+// LoadTLSConfig builds a server TLS config from PEM files. When clientCAFile
+// is set, clients must present a certificate signed by one of its CAs.
+func LoadTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in client CA file")
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+// peerFromTLS fills in the client identity from a completed handshake.
+func peerFromTLS(addr string, state *tls.ConnectionState) *protocol.Peer {
+	peer := &protocol.Peer{Addr: addr}
+	if state != nil && len(state.VerifiedChains) > 0 {
+		leaf := state.VerifiedChains[0][0]
+		peer.CommonName = leaf.Subject.CommonName
+		peer.Subject = leaf.Subject.String()
+	}
+	return peer
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newCert(t *testing.T, cn string, parent *testCert, isCA bool) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"OldSchool"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:         isCA,
+
+		BasicConstraintsValid: true,
+	}
+
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) writePEM(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600); err != nil {
+		t.Fatalf("write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func (c *testCert) tlsCert() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func TestTLS_MutualAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "Test CA", nil, true)
+	srv := newCert(t, "server", ca, false)
+	cli := newCert(t, "front-desk", ca, false)
+
+	caFile, _ := ca.writePEM(t, dir, "ca")
+	certFile, keyFile := srv.writePEM(t, dir, "server")
+
+	tlsCfg, err := LoadTLSConfig(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatalf("LoadTLSConfig failed: %v", err)
+	}
+	addr := startServer(t, Config{TLS: tlsCfg})
+	local := net.JoinHostPort("127.0.0.1", strconv.Itoa(addr.(*net.TCPAddr).Port))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	// with a client certificate the request goes through
+	conn, err := tls.Dial("tcp", local, &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{cli.tlsCert()},
+	})
+	if err != nil {
+		t.Fatalf("mTLS dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(`{"id":1,"method":"/health"}` + "\n")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil || !strings.HasPrefix(string(line), `{"id":1,"status":true`) {
+		t.Fatalf("unexpected reply %q, %v", line, err)
+	}
+
+	// without one the server hangs up
+	anon, err := tls.Dial("tcp", local, &tls.Config{RootCAs: roots})
+	if err == nil {
+		defer anon.Close()
+		_, _ = anon.Write([]byte(`{"method":"/health"}` + "\n"))
+		if _, err := bufio.NewReader(anon).ReadBytes('\n'); err == nil {
+			t.Fatalf("expected connection without client certificate to be rejected")
+		}
+	}
+}
+
+func TestHandshake_ExposesClientIdentity(t *testing.T) {
+	ca := newCert(t, "Test CA", nil, true)
+	srv := newCert(t, "server", ca, false)
+	cli := newCert(t, "front-desk", ca, false)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConn := tls.Server(c1, &tls.Config{
+		Certificates: []tls.Certificate{srv.tlsCert()},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	clientConn := tls.Client(c2, &tls.Config{
+		RootCAs:      pool,
+		ServerName:   "127.0.0.1",
+		Certificates: []tls.Certificate{cli.tlsCert()},
+	})
+	go func() { _ = clientConn.Handshake() }()
+
+	peer, err := handshake(serverConn)
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if peer.CommonName != "front-desk" || !strings.Contains(peer.Subject, "O=OldSchool") {
+		t.Fatalf("unexpected peer identity: %+v", peer)
+	}
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"OldSchool/internal/transport/protocol"
+	"OldSchool/internal/transport/router"
+)
+
+// WebSocketPath is where the WebSocket endpoint is mounted.
+const WebSocketPath = "/ws"
+
+// wsServer speaks the same protocol as tcpServer, with one text frame per
+// request and one text frame per response.
+type wsServer struct {
+	r        *router.Router
+	cfg      Config
+	upgrader websocket.Upgrader
+	httpSrv  *http.Server
+	listener net.Listener
+
+	mu    sync.Mutex
+	wg    sync.WaitGroup
+	conns connSet
+	stopC chan struct{}
+}
+
+func NewWebSocket(r *router.Router, cfg Config) Server {
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = DefaultMaxInFlight
+	}
+
+	s := &wsServer{
+		r:     r,
+		cfg:   cfg,
+		stopC: make(chan struct{}),
+	}
+	s.upgrader = websocket.Upgrader{CheckOrigin: s.checkOrigin}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(WebSocketPath, s.handleUpgrade)
+	s.httpSrv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+func (s *wsServer) Start(port string) error {
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return err
+	}
+	if s.cfg.TLS != nil {
+		ln = tls.NewListener(ln, s.cfg.TLS)
+	}
+
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		_ = s.httpSrv.Serve(ln)
+	}()
+
+	return nil
+}
+
+func (s *wsServer) Stop() error {
+	s.mu.Lock()
+	ln := s.listener
+	s.listener = nil
+	s.mu.Unlock()
+	if ln == nil {
+		return nil
+	}
+
+	close(s.stopC)
+
+	// Shutdown does not wait for hijacked connections, so close them here.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := s.httpSrv.Shutdown(ctx)
+
+	s.conns.closeAll()
+	s.wg.Wait()
+	return err
+}
+
+// checkOrigin allows same-origin requests, plus any origin listed in
+// Config.AllowedOrigins ("*" allows all).
+func (s *wsServer) checkOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" || origin == "http://"+req.Host || origin == "https://"+req.Host {
+		return true
+	}
+	for _, o := range s.cfg.AllowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *wsServer) handleUpgrade(w http.ResponseWriter, req *http.Request) {
+	// Counted before the upgrade: until the connection is hijacked,
+	// httpSrv.Shutdown waits for this handler, so Stop cannot miss it.
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	conn, err := s.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return
+	}
+	_ = s.handleConn(conn, peerFromTLS(req.RemoteAddr, req.TLS))
+}
+
+func (s *wsServer) handleConn(conn *websocket.Conn, peer *protocol.Peer) error {
+	defer conn.Close()
+
+	if !s.conns.add(conn) {
+		return nil
+	}
+	defer s.conns.remove(conn)
+
+	conn.SetReadLimit(protocol.MaxLineBytes)
+
+	read := func() ([]byte, error) {
+		for {
+			mt, msg, err := conn.ReadMessage()
+			if err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					return nil, io.EOF
+				}
+				return nil, err
+			}
+			if mt == websocket.TextMessage {
+				return msg, nil
+			}
+		}
+	}
+
+	var writeMu sync.Mutex
+	write := func(msg []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteMessage(websocket.TextMessage, msg)
+	}
+
+	return serveConn(s.r, s.cfg.MaxInFlight, connIO{
+		peer:  peer,
+		read:  read,
+		write: write,
+		close: func() { _ = conn.Close() },
+	})
+}